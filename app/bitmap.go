@@ -0,0 +1,434 @@
+package main
+
+import (
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "setbit", Arity: 4, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "bitmap", Since: "2.2.0", Complexity: "O(1)",
+			Summary: "Sets or clears the bit at offset of the string value. Creates the key if it doesn't exist.",
+			Handler: setbitCommand,
+		},
+		&Command{
+			Name: "getbit", Arity: 3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "bitmap", Since: "2.2.0", Complexity: "O(1)",
+			Summary: "Returns a bit value by offset.",
+			Handler: getbitCommand,
+		},
+		&Command{
+			Name: "bitcount", Arity: -2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "bitmap", Since: "2.6.0", Complexity: "O(N)",
+			Summary: "Counts the number of set bits (population counting) in a string.",
+			Handler: bitcountCommand,
+		},
+		&Command{
+			Name: "bitpos", Arity: -3, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "bitmap", Since: "2.8.7", Complexity: "O(N)",
+			Summary: "Finds the first set (1) or clear (0) bit in a string.",
+			Handler: bitposCommand,
+		},
+		&Command{
+			Name: "bitop", Arity: -4, Flags: []string{"write", "denyoom"}, FirstKey: 2, LastKey: -1, Step: 1,
+			Group: "bitmap", Since: "2.6.0", Complexity: "O(N)",
+			Summary: "Performs bitwise operations on multiple strings, and stores the result.",
+			Handler: bitopCommand,
+		},
+		&Command{
+			Name: "bitfield", Arity: -2, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "bitmap", Since: "3.2.0", Complexity: "O(1) for each subcommand specified",
+			Summary: "Performs arbitrary bitfield integer operations on strings.",
+			Handler: bitfieldCommand,
+		},
+		&Command{
+			Name: "bitfield_ro", Arity: -2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "bitmap", Since: "6.0.0", Complexity: "O(1) for each subcommand specified",
+			Summary: "Performs arbitrary read-only bitfield integer operations on strings.",
+			Handler: bitfieldRoCommand,
+		},
+	)
+}
+
+// maxBitOffset bounds bit offsets so bitmaps fit the longest string.
+const maxBitOffset = maxStringLength*8 - 1
+
+var errBitOffset = Resp.NewError("ERR bit offset is not an integer or out of range")
+
+// parseBitOffset parses a bit offset. In BITFIELD a # prefix multiplies
+// the offset by the width of the field.
+func parseBitOffset(arg Resp.RESP, hashAllowed bool, width uint) (uint64, Resp.RESP) {
+	s, multiplier := arg.String(), int64(1)
+	if hashAllowed && strings.HasPrefix(s, "#") {
+		s, multiplier = s[1:], int64(width)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > maxBitOffset/multiplier {
+		return 0, errBitOffset
+	}
+	n *= multiplier
+	if hashAllowed && n+int64(width)-1 > maxBitOffset {
+		return 0, errBitOffset
+	}
+	return uint64(n), Resp.RESP{}
+}
+
+// stringBytes returns the string stored in value for a command to modify
+// in place, or nil if there is none. Store the result with
+// setChangedString.
+func stringBytes(value *store.Value) []byte {
+	if value == nil {
+		return nil
+	}
+	return value.Unshare()
+}
+
+// setbitCommand implements SETBIT key offset value and returns the
+// previous bit.
+func setbitCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	offset, errReply := parseBitOffset(args[2], false, 0)
+	if errReply.IsError() {
+		return errReply
+	}
+	bit := args[3].String()
+	if bit != "0" && bit != "1" {
+		return Resp.NewError("ERR bit is not an integer or out of range")
+	}
+	key := args[1].String()
+	value, errReply := lookupString(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	b, old := store.SetBit(stringBytes(value), offset, int(bit[0]-'0'))
+	setChangedString(client, key, value, b)
+	return Resp.NewInteger(int64(old))
+}
+
+func getbitCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	offset, errReply := parseBitOffset(args[2], false, 0)
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupString(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(int64(store.GetBit(value.Peek(), offset)))
+}
+
+// bitRange is the range argument of BITCOUNT and BITPOS: start [end
+// [BYTE | BIT]], inclusive offsets counted in bytes unless BIT is given,
+// negative ones from the end of the string.
+type bitRange struct {
+	start, end int64
+	hasEnd     bool
+	bit        bool
+}
+
+func parseBitRange(args []Resp.RESP) (bitRange, Resp.RESP) {
+	r := bitRange{start: 0, end: -1}
+	if len(args) == 0 {
+		return r, Resp.RESP{}
+	}
+	if len(args) > 3 {
+		return r, syntaxError
+	}
+	var errReply Resp.RESP
+	if r.start, errReply = parseInteger(args[0]); errReply.IsError() {
+		return r, errReply
+	}
+	if len(args) == 1 {
+		return r, Resp.RESP{}
+	}
+	if r.end, errReply = parseInteger(args[1]); errReply.IsError() {
+		return r, errReply
+	}
+	r.hasEnd = true
+	if len(args) == 3 {
+		switch strings.ToUpper(args[2].String()) {
+		case "BYTE":
+		case "BIT":
+			r.bit = true
+		default:
+			return r, syntaxError
+		}
+	}
+	return r, Resp.RESP{}
+}
+
+// bits returns the first and last bits the range covers in a string of
+// length n, reporting false if it is empty.
+func (r bitRange) bits(n int) (uint64, uint64, bool) {
+	length := int64(n)
+	if r.bit {
+		length *= 8
+	}
+	start, end := r.start, r.end
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= length {
+		end = length - 1
+	}
+	if length == 0 || start > end {
+		return 0, 0, false
+	}
+	if r.bit {
+		return uint64(start), uint64(end), true
+	}
+	return uint64(start) * 8, uint64(end)*8 + 7, true
+}
+
+// bitcountCommand implements BITCOUNT key [start end [BYTE | BIT]].
+func bitcountCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args) == 3 {
+		return syntaxError
+	}
+	r, errReply := parseBitRange(args[2:])
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupString(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewInteger(0)
+	}
+	b := value.Peek()
+	start, end, ok := r.bits(len(b))
+	if !ok {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(store.BitCount(b, start, end))
+}
+
+// bitposCommand implements BITPOS key bit [start [end [BYTE | BIT]]].
+// Looking for a clear bit without an end treats the string as padded with
+// zeros on the right, so past an all ones string the bit after it is
+// returned.
+func bitposCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	bit := args[2].String()
+	if bit != "0" && bit != "1" {
+		return Resp.NewError("ERR The bit argument must be 1 or 0.")
+	}
+	r, errReply := parseBitRange(args[3:])
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupString(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		if bit == "1" {
+			return Resp.NewInteger(-1)
+		}
+		return Resp.NewInteger(0)
+	}
+	b := value.Peek()
+	start, end, ok := r.bits(len(b))
+	if !ok {
+		return Resp.NewInteger(-1)
+	}
+	pos := store.BitPos(b, int(bit[0]-'0'), start, end)
+	if pos < 0 && bit == "0" && !r.hasEnd {
+		pos = int64(end) + 1
+	}
+	return Resp.NewInteger(pos)
+}
+
+// bitopCommand implements BITOP <AND | OR | XOR | NOT> destkey key [key
+// ...]. Missing keys count as empty strings and an empty result deletes
+// destkey. It returns the length of the result.
+func bitopCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	var op store.BitOp
+	switch strings.ToUpper(args[1].String()) {
+	case "AND":
+		op = store.BitAnd
+	case "OR":
+		op = store.BitOr
+	case "XOR":
+		op = store.BitXor
+	case "NOT":
+		op = store.BitNot
+		if len(args) != 4 {
+			return Resp.NewError("ERR BITOP NOT must be called with a single source key.")
+		}
+	default:
+		return syntaxError
+	}
+	srcs := make([][]byte, len(args)-3)
+	for i, key := range args[3:] {
+		value, errReply := lookupString(client, key.String())
+		if errReply.IsError() {
+			return errReply
+		}
+		if value != nil {
+			srcs[i] = value.Peek()
+		}
+	}
+	dst := store.BitOperation(op, srcs)
+	destKey := args[2].String()
+	if len(dst) == 0 {
+		client.db.Delete(destKey)
+	} else {
+		client.db.SetValue(destKey, store.NewString(dst))
+	}
+	return Resp.NewInteger(int64(len(dst)))
+}
+
+// bitfieldOp is a GET, SET or INCRBY subcommand of BITFIELD, with the
+// overflow policy in effect for it.
+type bitfieldOp struct {
+	name     string
+	typ      store.BitfieldType
+	offset   uint64
+	value    int64
+	overflow store.BitfieldOverflow
+}
+
+func parseBitfieldType(arg Resp.RESP) (store.BitfieldType, Resp.RESP) {
+	s := strings.ToLower(arg.String())
+	var t store.BitfieldType
+	if len(s) > 1 && (s[0] == 'i' || s[0] == 'u') {
+		n, err := strconv.Atoi(s[1:])
+		t = store.BitfieldType{Signed: s[0] == 'i', Bits: uint(n)}
+		if err == nil && n >= 1 && (n <= 63 || (t.Signed && n == 64)) {
+			return t, Resp.RESP{}
+		}
+	}
+	return t, Resp.NewError("ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is.")
+}
+
+// parseBitfieldOps parses the subcommands of BITFIELD, or of BITFIELD_RO
+// when readOnly is set.
+func parseBitfieldOps(args []Resp.RESP, readOnly bool) ([]bitfieldOp, Resp.RESP) {
+	var ops []bitfieldOp
+	overflow := store.OverflowWrap
+	for i := 2; i < len(args); i++ {
+		name := strings.ToUpper(args[i].String())
+		if readOnly && name != "GET" {
+			return nil, Resp.NewError("ERR BITFIELD_RO only supports the GET subcommand")
+		}
+		if name == "OVERFLOW" && i+1 < len(args) {
+			switch strings.ToUpper(args[i+1].String()) {
+			case "WRAP":
+				overflow = store.OverflowWrap
+			case "SAT":
+				overflow = store.OverflowSat
+			case "FAIL":
+				overflow = store.OverflowFail
+			default:
+				return nil, Resp.NewError("ERR Invalid OVERFLOW type specified")
+			}
+			i++
+			continue
+		}
+		argc := 3
+		if name == "GET" {
+			argc = 2
+		} else if name != "SET" && name != "INCRBY" {
+			return nil, syntaxError
+		}
+		if i+argc >= len(args) {
+			return nil, syntaxError
+		}
+		op := bitfieldOp{name: name, overflow: overflow}
+		var errReply Resp.RESP
+		if op.typ, errReply = parseBitfieldType(args[i+1]); errReply.IsError() {
+			return nil, errReply
+		}
+		if op.offset, errReply = parseBitOffset(args[i+2], true, op.typ.Bits); errReply.IsError() {
+			return nil, errReply
+		}
+		if argc == 3 {
+			if op.value, errReply = parseInteger(args[i+3]); errReply.IsError() {
+				return nil, errReply
+			}
+		}
+		ops = append(ops, op)
+		i += argc
+	}
+	return ops, Resp.RESP{}
+}
+
+func bitfieldCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return bitfieldGeneric(client, args, false)
+}
+
+func bitfieldRoCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return bitfieldGeneric(client, args, true)
+}
+
+// bitfieldGeneric implements BITFIELD key [GET encoding offset | [OVERFLOW
+// <WRAP | SAT | FAIL>] <SET encoding offset value | INCRBY encoding offset
+// increment> [GET encoding offset | ...]] and BITFIELD_RO key [GET
+// encoding offset ...]. SET replies with the previous value and INCRBY
+// with the new one, or nil when OVERFLOW FAIL refused the write.
+func bitfieldGeneric(client *Client, args []Resp.RESP, readOnly bool) Resp.RESP {
+	ops, errReply := parseBitfieldOps(args, readOnly)
+	if errReply.IsError() {
+		return errReply
+	}
+	key := args[1].String()
+	value, errReply := lookupString(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	// the string is only unshared once a field is written
+	var b []byte
+	if value != nil {
+		b = value.Peek()
+	}
+	replies := make([]Resp.RESP, len(ops))
+	changes := 0
+	for i, op := range ops {
+		current := store.GetBitfield(b, op.offset, op.typ)
+		if op.name == "GET" {
+			replies[i] = Resp.NewInteger(current)
+			continue
+		}
+		base, incr := int64(0), op.value
+		if op.name == "INCRBY" {
+			base = current
+		}
+		n, ok := store.BitfieldAdd(base, incr, op.typ, op.overflow)
+		if !ok {
+			replies[i] = Resp.NewNullBulkString()
+			continue
+		}
+		if changes == 0 {
+			b = stringBytes(value)
+		}
+		b = store.SetBitfield(b, op.offset, op.typ, n)
+		changes++
+		if op.name == "SET" {
+			replies[i] = Resp.NewInteger(current)
+		} else {
+			replies[i] = Resp.NewInteger(n)
+		}
+	}
+	if changes > 0 {
+		setChangedString(client, key, value, b)
+	} else {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewArray(replies)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"time"
+)
+
+// Blocking commands such as BLPOP park the client on keys when there is
+// nothing to serve. Write commands signal the keys they add elements to,
+// and once the command is done the clients blocked on those keys are
+// served in the order they blocked, by running their command again. This
+// way a blocked client never misses an element pushed while it waited,
+// and other clients can't steal it.
+
+// blockingKey identifies a key of a database.
+type blockingKey struct {
+	db  *store.Store
+	key string
+}
+
+// blockState describes what a blocked client waits for.
+type blockState struct {
+	keys    []blockingKey
+	timeout time.Duration
+	// timeoutReply is sent when the timeout expires first
+	timeoutReply Resp.RESP
+	// reply receives the reply of the command once served
+	reply chan Resp.RESP
+}
+
+var (
+	// blockedClients lists the clients blocked on each key in the order
+	// they blocked
+	blockedClients = map[blockingKey][]*Client{}
+	// readyKeys are keys with blocked clients that received elements
+	readyKeys []blockingKey
+)
+
+var errConnectionClosed = errors.New("connection closed while blocked")
+
+// parseTimeout parses the timeout of a blocking command, in seconds with
+// decimals. Zero blocks forever, and like in Redis other timeouts are
+// rounded up to whole milliseconds so they never turn into zero.
+func parseTimeout(arg Resp.RESP) (time.Duration, Resp.RESP) {
+	seconds, err := strconv.ParseFloat(arg.String(), 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0, Resp.NewError("ERR timeout is not a float or out of range")
+	}
+	if seconds < 0 {
+		return 0, Resp.NewError("ERR timeout is negative")
+	}
+	if seconds > float64(math.MaxInt64/int64(time.Second)) {
+		return 0, Resp.NewError("ERR timeout is out of range")
+	}
+	return time.Duration(math.Ceil(seconds*1000)) * time.Millisecond, Resp.RESP{}
+}
+
+// blockForKeys makes the client wait until one of keys is ready or the
+// timeout expires, and returns what the command replies for now. The link
+// to the master never blocks and gets timeoutReply right away.
+func blockForKeys(client *Client, keys []string, timeout time.Duration, timeoutReply Resp.RESP) Resp.RESP {
+	if client.master {
+		return timeoutReply
+	}
+	client.wouldBlock = true
+	client.rewritePropagation(nil)
+	if client.blocked != nil {
+		// the command runs again for a ready key but still has to wait
+		return Resp.RESP{}
+	}
+	state := &blockState{timeout: timeout, timeoutReply: timeoutReply, reply: make(chan Resp.RESP, 1)}
+	for _, key := range keys {
+		bk := blockingKey{client.db, key}
+		if containsBlockingKey(state.keys, bk) {
+			continue
+		}
+		state.keys = append(state.keys, bk)
+		blockedClients[bk] = append(blockedClients[bk], client)
+	}
+	client.blocked = state
+	return Resp.RESP{}
+}
+
+func containsBlockingKey(keys []blockingKey, key blockingKey) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// unblockClient removes the client from the keys it is blocked on.
+func unblockClient(client *Client) {
+	for _, bk := range client.blocked.keys {
+		clients := blockedClients[bk]
+		for i, c := range clients {
+			if c == client {
+				clients = append(clients[:i], clients[i+1:]...)
+				break
+			}
+		}
+		if len(clients) == 0 {
+			delete(blockedClients, bk)
+		} else {
+			blockedClients[bk] = clients
+		}
+	}
+}
+
+// signalKeyAsReady records that key received elements, so clients blocked
+// on it get served once the running command is done.
+func signalKeyAsReady(db *store.Store, key string) {
+	bk := blockingKey{db, key}
+	if _, ok := blockedClients[bk]; !ok || containsBlockingKey(readyKeys, bk) {
+		return
+	}
+	readyKeys = append(readyKeys, bk)
+}
+
+// handleClientsBlockedOnKeys serves the clients blocked on ready keys. A
+// served command may make other keys ready, e.g. BLMOVE, so it goes on
+// until there are no ready keys left.
+func handleClientsBlockedOnKeys() {
+	for len(readyKeys) > 0 {
+		keys := readyKeys
+		readyKeys = nil
+		for _, bk := range keys {
+			for len(blockedClients[bk]) > 0 {
+				client := blockedClients[bk][0]
+				res := call(client, client.cmd, client.args)
+				if client.wouldBlock {
+					// the key no longer holds anything for this client
+					break
+				}
+				unblockClient(client)
+				client.blocked.reply <- res
+			}
+		}
+	}
+}
+
+// waitUnblocked waits until the blocked client is served or its timeout
+// expires and returns the reply to send. It returns errConnectionClosed if
+// peeked reports the connection failed first. peeked is fed by a goroutine
+// peeking at the connection; when the client sends more data instead, the
+// result is put back for the caller to collect before reading again.
+func (c *Client) waitUnblocked(peeked chan error) (Resp.RESP, error) {
+	state := c.blocked
+	defer func() { c.blocked = nil }()
+	var expired <-chan time.Time
+	if state.timeout > 0 {
+		timer := time.NewTimer(state.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	watch := peeked
+	for {
+		select {
+		case res := <-state.reply:
+			return res, nil
+		case <-expired:
+			return c.abortBlocking(state, state.timeoutReply, nil)
+		case err := <-watch:
+			if err != nil {
+				return c.abortBlocking(state, Resp.RESP{}, errConnectionClosed)
+			}
+			// commands sent meanwhile are read once unblocked
+			peeked <- nil
+			watch = nil
+		}
+	}
+}
+
+// abortBlocking unblocks the client because of a timeout or an error,
+// unless it got served in the meantime.
+func (c *Client) abortBlocking(state *blockState, res Resp.RESP, err error) (Resp.RESP, error) {
+	c.db.Lock()
+	defer c.db.Unlock()
+	select {
+	case served := <-state.reply:
+		return served, err
+	default:
+		unblockClient(c)
+		return res, err
+	}
+}
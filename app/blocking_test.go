@@ -0,0 +1,263 @@
+package main
+
+import (
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestDB returns a fresh database selected by the server, and resets
+// the blocked clients and the ready keys once the test is done.
+func newTestDB(t *testing.T) *store.Store {
+	db := store.NewStore()
+	saved := databases
+	databases = []*store.Store{db}
+	t.Cleanup(func() {
+		databases = saved
+		blockedClients = map[blockingKey][]*Client{}
+		readyKeys = nil
+	})
+	return db
+}
+
+// newTestClient returns a client of db without a connection.
+func newTestClient(db *store.Store) *Client {
+	return NewClient(nil, db)
+}
+
+func argv(args ...string) []Resp.RESP {
+	argv := make([]Resp.RESP, len(args))
+	for i, arg := range args {
+		argv[i] = Resp.NewBulkString(arg)
+	}
+	return argv
+}
+
+func command(args ...string) Resp.RESP {
+	return Resp.NewArray(argv(args...))
+}
+
+// run executes a command on behalf of client and returns its reply.
+func run(client *Client, args ...string) Resp.RESP {
+	return handleCommand(command(args...), client)
+}
+
+func expectReply(t *testing.T, got, want Resp.RESP) {
+	t.Helper()
+	if got.Serialize() != want.Serialize() {
+		t.Errorf("Expected %q, got %q", want.Serialize(), got.Serialize())
+	}
+}
+
+// captureReplication makes the commands propagated until the test ends
+// go to the returned queue, as if there were a replica on database 0.
+func captureReplication(t *testing.T) chan []byte {
+	queue := make(chan []byte, replicaQueueLimit)
+	saved := replicas
+	replicas, replicationSelectedDB = []Replica{{queue: queue}}, 0
+	t.Cleanup(func() {
+		replicas, replicationSelectedDB = saved, -1
+	})
+	return queue
+}
+
+// expectPropagated checks the commands propagated since the last call.
+func expectPropagated(t *testing.T, queue chan []byte, commands ...[]string) {
+	t.Helper()
+	for _, args := range commands {
+		select {
+		case payload := <-queue:
+			want := command(args...)
+			if string(payload) != want.Serialize() {
+				t.Errorf("Expected %v to be propagated, got %q", args, payload)
+			}
+		default:
+			t.Errorf("Expected %v to be propagated, got nothing", args)
+		}
+	}
+	select {
+	case payload := <-queue:
+		t.Errorf("Expected nothing more to be propagated, got %q", payload)
+	default:
+	}
+}
+
+// served returns the reply of a blocked client that got served.
+func served(t *testing.T, client *Client) Resp.RESP {
+	t.Helper()
+	if client.blocked == nil {
+		t.Fatalf("Expected the client to be blocked")
+	}
+	select {
+	case res := <-client.blocked.reply:
+		client.blocked = nil
+		return res
+	default:
+		t.Fatalf("Expected the client to be served")
+	}
+	return Resp.RESP{}
+}
+
+func TestParseTimeout(t *testing.T) {
+	for arg, want := range map[string]time.Duration{
+		"0":            0,
+		"1.5":          1500 * time.Millisecond,
+		"0.01":         10 * time.Millisecond,
+		"0.0000000001": time.Millisecond,
+		"0.0015":       2 * time.Millisecond,
+	} {
+		if timeout, errReply := parseTimeout(Resp.NewBulkString(arg)); errReply.IsError() || timeout != want {
+			t.Errorf("Expected %s to be %v, got %v %q", arg, want, timeout, errReply.String())
+		}
+	}
+	for arg, want := range map[string]string{
+		"abc":  "ERR timeout is not a float or out of range",
+		"nan":  "ERR timeout is not a float or out of range",
+		"inf":  "ERR timeout is not a float or out of range",
+		"-1":   "ERR timeout is negative",
+		"1e30": "ERR timeout is out of range",
+	} {
+		if _, errReply := parseTimeout(Resp.NewBulkString(arg)); errReply.String() != want {
+			t.Errorf("Expected %s to fail with %q, got %q", arg, want, errReply.String())
+		}
+	}
+}
+
+func TestParseMpopArgs(t *testing.T) {
+	keys, head, count, errReply := parseMpopArgs(argv("LMPOP", "2", "a", "b", "LEFT"), 1)
+	if errReply.IsError() || strings.Join(respStrings(keys), ",") != "a,b" || !head || count != 1 {
+		t.Errorf("Expected keys a,b from the head, got %v %v %d %q", respStrings(keys), head, count, errReply.String())
+	}
+	keys, head, count, errReply = parseMpopArgs(argv("BLMPOP", "0", "1", "a", "right", "COUNT", "3"), 2)
+	if errReply.IsError() || len(keys) != 1 || head || count != 3 {
+		t.Errorf("Expected 3 elements of a from the tail, got %v %v %d %q", respStrings(keys), head, count, errReply.String())
+	}
+	for _, args := range []struct {
+		argv []string
+		err  string
+	}{
+		{[]string{"LMPOP", "0", "a", "LEFT"}, "ERR numkeys should be greater than 0"},
+		{[]string{"LMPOP", "x", "a", "LEFT"}, "ERR numkeys should be greater than 0"},
+		{[]string{"LMPOP", "3", "a", "b", "LEFT"}, "ERR syntax error"},
+		{[]string{"LMPOP", "1", "a", "MIDDLE"}, "ERR syntax error"},
+		{[]string{"LMPOP", "1", "a", "LEFT", "COUNT"}, "ERR syntax error"},
+		{[]string{"LMPOP", "1", "a", "LEFT", "LIMIT", "1"}, "ERR syntax error"},
+		{[]string{"LMPOP", "1", "a", "LEFT", "COUNT", "0"}, "ERR count should be greater than 0"},
+	} {
+		if _, _, _, errReply := parseMpopArgs(argv(args.argv...), 1); errReply.String() != args.err {
+			t.Errorf("Expected %v to fail with %q, got %q", args.argv, args.err, errReply.String())
+		}
+	}
+}
+
+func TestListPopPropagation(t *testing.T) {
+	key := Resp.NewBulkString("l")
+	expectReply(t, Resp.NewArray(listPopPropagation(key, true, 3)), command("LPOP", "l", "3"))
+	expectReply(t, Resp.NewArray(listPopPropagation(key, false, 0)), command("RPOP", "l"))
+}
+
+func TestBlockedClientsBookkeeping(t *testing.T) {
+	db := newTestDB(t)
+	c1, c2 := newTestClient(db), newTestClient(db)
+	blockForKeys(c1, []string{"a", "b", "a"}, 0, Resp.NewNullArray())
+	blockForKeys(c2, []string{"a"}, 0, Resp.NewNullArray())
+	if len(c1.blocked.keys) != 2 {
+		t.Errorf("Expected a key given twice to be waited on once, got %v", c1.blocked.keys)
+	}
+	if clients := blockedClients[blockingKey{db, "a"}]; len(clients) != 2 || clients[0] != c1 || clients[1] != c2 {
+		t.Errorf("Expected the clients blocked on a in the order they blocked")
+	}
+
+	signalKeyAsReady(db, "c")
+	if len(readyKeys) != 0 {
+		t.Errorf("Expected a key nobody waits for not to be ready")
+	}
+	signalKeyAsReady(db, "a")
+	signalKeyAsReady(db, "a")
+	if len(readyKeys) != 1 {
+		t.Errorf("Expected a key signaled twice to be ready once, got %v", readyKeys)
+	}
+
+	unblockClient(c1)
+	if clients := blockedClients[blockingKey{db, "a"}]; len(clients) != 1 || clients[0] != c2 {
+		t.Errorf("Expected only c2 to be left blocked on a")
+	}
+	if _, ok := blockedClients[blockingKey{db, "b"}]; ok {
+		t.Errorf("Expected b to have no blocked clients left")
+	}
+	unblockClient(c2)
+	if len(blockedClients) != 0 {
+		t.Errorf("Expected no blocked clients left, got %v", blockedClients)
+	}
+}
+
+func TestBlockedClientsServedInOrder(t *testing.T) {
+	db := newTestDB(t)
+	queue := captureReplication(t)
+	c1, c2, c3 := newTestClient(db), newTestClient(db), newTestClient(db)
+	expectReply(t, run(c1, "BLPOP", "l", "0"), Resp.RESP{})
+	expectReply(t, run(c2, "BRPOP", "other", "l", "0"), Resp.RESP{})
+	expectPropagated(t, queue)
+
+	expectReply(t, run(c3, "RPUSH", "l", "a", "b", "c"), Resp.NewInteger(3))
+	expectReply(t, served(t, c1), command("l", "a"))
+	expectReply(t, served(t, c2), command("l", "c"))
+	expectReply(t, run(c3, "LRANGE", "l", "0", "-1"), command("b"))
+	expectPropagated(t, queue, []string{"RPUSH", "l", "a", "b", "c"}, []string{"LPOP", "l"}, []string{"RPOP", "l"})
+	if len(blockedClients) != 0 || len(readyKeys) != 0 {
+		t.Errorf("Expected the served clients to be unblocked")
+	}
+}
+
+func TestBlockedClientsChained(t *testing.T) {
+	db := newTestDB(t)
+	queue := captureReplication(t)
+	c1, c2, c3, c4 := newTestClient(db), newTestClient(db), newTestClient(db), newTestClient(db)
+	run(c1, "BLMOVE", "src", "dst", "LEFT", "RIGHT", "0")
+	run(c2, "BLPOP", "dst", "0")
+	run(c3, "BLMPOP", "0", "2", "a", "b", "RIGHT", "COUNT", "2")
+
+	// the element moved by c1 makes dst ready for c2
+	run(c4, "RPUSH", "src", "x")
+	expectReply(t, served(t, c1), Resp.NewBulkString("x"))
+	expectReply(t, served(t, c2), command("dst", "x"))
+	run(c4, "RPUSH", "b", "1", "2", "3")
+	expectReply(t, served(t, c3), Resp.NewArray([]Resp.RESP{Resp.NewBulkString("b"), command("3", "2")}))
+	expectPropagated(t, queue,
+		[]string{"RPUSH", "src", "x"}, []string{"LMOVE", "src", "dst", "LEFT", "RIGHT"}, []string{"LPOP", "dst"},
+		[]string{"RPUSH", "b", "1", "2", "3"}, []string{"RPOP", "b", "2"})
+}
+
+func TestBlockedClientTimeout(t *testing.T) {
+	db := newTestDB(t)
+	queue := captureReplication(t)
+	c1, c2 := newTestClient(db), newTestClient(db)
+	run(c1, "BLPOP", "l", "0.01")
+	res, err := c1.waitUnblocked(make(chan error))
+	if err != nil {
+		t.Fatalf("Expected the timeout to expire, got %v", err)
+	}
+	expectReply(t, res, Resp.NewNullArray())
+	if c1.blocked != nil || len(blockedClients) != 0 {
+		t.Errorf("Expected the client to be unblocked on timeout")
+	}
+	run(c2, "RPUSH", "l", "a")
+	expectReply(t, run(c2, "LLEN", "l"), Resp.NewInteger(1))
+	expectPropagated(t, queue, []string{"RPUSH", "l", "a"})
+}
+
+func TestBlockedClientServedBeforeTimeout(t *testing.T) {
+	db := newTestDB(t)
+	c1, c2 := newTestClient(db), newTestClient(db)
+	run(c1, "BLPOP", "l", "0")
+	run(c2, "RPUSH", "l", "a")
+	// the timeout fires after the client got served: the element it
+	// popped is not lost
+	res, _ := c1.abortBlocking(c1.blocked, c1.blocked.timeoutReply, nil)
+	expectReply(t, res, command("l", "a"))
+	if len(blockedClients) != 0 {
+		t.Errorf("Expected the client to be unblocked")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"sync/atomic"
+)
+
+var nextClientId int64
+
+// Client holds the per-connection state of a connected client.
+type Client struct {
+	id       int64
+	conn     net.Conn
+	protocol int
+	name     string
+	enc      *Resp.Encoder
+	// db is the selected database and dbIndex its index in databases
+	db      *store.Store
+	dbIndex int
+	// master is set on the connection a replica keeps to its master
+	master bool
+	// propagation replaces the argv sent to replicas for the command being
+	// executed when propagationSet is true; a nil argv is not propagated
+	propagation    []Resp.RESP
+	propagationSet bool
+	// cmd and args are the command being executed
+	cmd  *Command
+	args []Resp.RESP
+	// blocked is set while the client waits in a blocking command, and
+	// wouldBlock when the command being executed has to wait
+	blocked    *blockState
+	wouldBlock bool
+}
+
+func NewClient(conn net.Conn, db *store.Store) *Client {
+	return &Client{
+		id:       atomic.AddInt64(&nextClientId, 1),
+		conn:     conn,
+		protocol: 2,
+		enc:      Resp.NewEncoder(conn),
+		db:       db,
+	}
+}
+
+// rewritePropagation makes the running command propagate args to replicas
+// instead of its own argv, or nothing when args is nil.
+func (c *Client) rewritePropagation(args []Resp.RESP) {
+	c.propagation = args
+	c.propagationSet = true
+}
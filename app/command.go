@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+// CommandHandler executes a command. args holds the full argv, so args[0]
+// is the command name as sent by the client.
+type CommandHandler func(client *Client, args []Resp.RESP) Resp.RESP
+
+// Command describes an entry of the command table. Arity follows the Redis
+// convention: a positive arity is the exact number of arguments including
+// the command name, a negative arity is the minimum number. FirstKey,
+// LastKey and Step locate the key arguments; a negative LastKey counts
+// from the end of argv. Commands whose keys cannot be described that way
+// list them in KeySpecs instead.
+type Command struct {
+	Name        string
+	Arity       int
+	Flags       []string
+	FirstKey    int
+	LastKey     int
+	Step        int
+	KeySpecs    []KeySpec
+	Group       string
+	Since       string
+	Summary     string
+	Complexity  string
+	Handler     CommandHandler
+	Subcommands []*Command
+	parent      *Command
+}
+
+// KeySpec locates key arguments the way Redis 7 key specifications do: a
+// begin search step finds the first key either at a fixed index or after
+// a keyword, and a find keys step selects the keys from there, either as a
+// range or by reading the number of keys from an argument.
+type KeySpec struct {
+	Flags []string
+	// begin search: BeginIndex > 0 selects an index, otherwise Keyword is
+	// searched starting at KeywordStart (negative counts from the end)
+	BeginIndex   int
+	Keyword      string
+	KeywordStart int
+	// find keys: with FirstKey > 0 the number of keys is read from the
+	// argument at KeyNumIndex relative to the begin search result and the
+	// keys start at FirstKey relative to it; otherwise LastKey (relative to
+	// the first key, negative counts from the end) bounds the range
+	LastKey     int
+	KeyStep     int
+	Limit       int
+	KeyNumIndex int
+	FirstKey    int
+}
+
+func (c *Command) HasFlag(flag string) bool {
+	for _, f := range c.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// FullName returns the name used in replies, e.g. "command|info" for
+// subcommands.
+func (c *Command) FullName() string {
+	if c.parent != nil {
+		return c.parent.Name + "|" + c.Name
+	}
+	return c.Name
+}
+
+var commandTable = map[string]*Command{}
+
+// registerCommands adds commands to the command table. Each command family
+// registers its commands from its own init function.
+func registerCommands(cmds ...*Command) {
+	for _, cmd := range cmds {
+		for _, sub := range cmd.Subcommands {
+			sub.parent = cmd
+			if sub.Group == "" {
+				sub.Group = cmd.Group
+			}
+		}
+		commandTable[cmd.Name] = cmd
+	}
+}
+
+func lookupCommand(name []byte) *Command {
+	return commandTable[strings.ToLower(string(name))]
+}
+
+func (c *Command) lookupSubcommand(name []byte) *Command {
+	lower := strings.ToLower(string(name))
+	for _, sub := range c.Subcommands {
+		if sub.Name == lower {
+			return sub
+		}
+	}
+	return nil
+}
+
+// handleCommand looks the command up in the command table, validates its
+// arity and runs it. Successful write commands are propagated to replicas.
+func handleCommand(req Resp.RESP, client *Client) Resp.RESP {
+	args := req.Array()
+	cmd := lookupCommand(args[0].Bytes())
+	if cmd == nil {
+		return unknownCommandError(args)
+	}
+	if len(cmd.Subcommands) > 0 && (len(args) > 1 || cmd.Handler == nil) {
+		if len(args) == 1 {
+			return wrongArityError(cmd.Name)
+		}
+		sub := cmd.lookupSubcommand(args[1].Bytes())
+		if sub == nil {
+			return Resp.NewError(fmt.Sprintf("ERR unknown subcommand '%s'. Try %s HELP.", args[1].String(), strings.ToUpper(cmd.Name)))
+		}
+		cmd = sub
+	}
+	if (cmd.Arity > 0 && len(args) != cmd.Arity) || len(args) < -cmd.Arity {
+		return wrongArityError(cmd.FullName())
+	}
+	if cmd.HasFlag("write") && config.role == "slave" && !client.master {
+		return Resp.NewError("READONLY You can't write against a read only replica.")
+	}
+	// commands run one at a time, like on the Redis event loop: the
+	// databases share their lock
+	client.db.Lock()
+	defer client.db.Unlock()
+	res := call(client, cmd, args)
+	handleClientsBlockedOnKeys()
+	return res
+}
+
+// call runs cmd and propagates it to replicas if it is a write command
+// that succeeded. Commands that block are propagated once served.
+func call(client *Client, cmd *Command, args []Resp.RESP) Resp.RESP {
+	client.cmd, client.args = cmd, args
+	client.propagation, client.propagationSet = nil, false
+	client.wouldBlock = false
+	if client.master {
+		// the master saw the keys as live when it ran the command
+		setExpirePolicy(store.ExpireKeep)
+		defer setExpirePolicy(store.ExpireHide)
+	}
+	res := cmd.Handler(client, args)
+	if cmd.HasFlag("write") && !res.IsError() && !client.wouldBlock {
+		if !client.propagationSet {
+			propagate(client.dbIndex, Resp.NewArray(args))
+		} else if client.propagation != nil {
+			propagate(client.dbIndex, Resp.NewArray(client.propagation))
+		}
+	}
+	return res
+}
+
+var (
+	okReply             = Resp.NewSimpleString("OK")
+	syntaxError         = Resp.NewError("ERR syntax error")
+	notIntegerError     = Resp.NewError("ERR value is not an integer or out of range")
+	unsupportedProtocol = Resp.NewError("NOPROTO unsupported protocol version")
+	wrongTypeError      = Resp.NewError(store.ErrWrongType.Error())
+)
+
+// parseInteger parses an integer argument. On failure the returned reply
+// is the error to send.
+func parseInteger(arg Resp.RESP) (int64, Resp.RESP) {
+	n, err := strconv.ParseInt(arg.String(), 10, 64)
+	if err != nil {
+		return 0, notIntegerError
+	}
+	return n, Resp.RESP{}
+}
+
+// parseFloat parses a float argument. NaN is rejected, like Redis does.
+func parseFloat(arg []byte) (float64, bool) {
+	f, err := strconv.ParseFloat(string(arg), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// respStrings returns the arguments as strings.
+func respStrings(args []Resp.RESP) []string {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		strs[i] = arg.String()
+	}
+	return strs
+}
+
+func invalidExpireError(command string) Resp.RESP {
+	return Resp.NewError(fmt.Sprintf("ERR invalid expire time in '%s' command", command))
+}
+
+func wrongArityError(name string) Resp.RESP {
+	return Resp.NewError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+}
+
+func unknownCommandError(args []Resp.RESP) Resp.RESP {
+	var quoted strings.Builder
+	for _, arg := range args[1:] {
+		fmt.Fprintf(&quoted, "'%s' ", arg.String())
+	}
+	return Resp.NewError(fmt.Sprintf("ERR unknown command '%s', with args beginning with: %s", args[0].String(), quoted.String()))
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	Resp "redis-go/pkg/resp"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerCommands(&Command{
+		Name: "command", Arity: -1, Flags: []string{"loading", "stale"},
+		Group: "server", Since: "2.8.13", Complexity: "O(N) where N is the total number of Redis commands",
+		Summary: "Returns detailed information about all commands.",
+		Handler: commandCommand,
+		Subcommands: []*Command{
+			{
+				Name: "count", Arity: 2, Flags: []string{"loading", "stale"},
+				Since: "2.8.13", Complexity: "O(1)",
+				Summary: "Returns a count of commands.",
+				Handler: commandCountCommand,
+			},
+			{
+				Name: "docs", Arity: -2, Flags: []string{"loading", "stale"},
+				Since: "7.0.0", Complexity: "O(N) where N is the number of commands to look up",
+				Summary: "Returns documentary information about one, multiple or all commands.",
+				Handler: commandDocsCommand,
+			},
+			{
+				Name: "info", Arity: -2, Flags: []string{"loading", "stale"},
+				Since: "2.8.13", Complexity: "O(N) where N is the number of commands to look up",
+				Summary: "Returns information about one, multiple or all commands.",
+				Handler: commandInfoCommand,
+			},
+			{
+				Name: "help", Arity: 2, Flags: []string{"loading", "stale"},
+				Since: "5.0.0", Complexity: "O(1)",
+				Summary: "Returns helpful text about the different subcommands.",
+				Handler: commandHelpCommand,
+			},
+		},
+	})
+}
+
+// sortedCommands returns the command table ordered by name so replies are
+// stable between calls.
+func sortedCommands() []*Command {
+	cmds := make([]*Command, 0, len(commandTable))
+	for _, cmd := range commandTable {
+		cmds = append(cmds, cmd)
+	}
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name < cmds[j].Name })
+	return cmds
+}
+
+func commandCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	cmds := sortedCommands()
+	infos := make([]Resp.RESP, len(cmds))
+	for i, cmd := range cmds {
+		infos[i] = commandInfo(cmd)
+	}
+	return Resp.NewArray(infos)
+}
+
+func commandCountCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return Resp.NewInteger(int64(len(commandTable)))
+}
+
+// commandInfoCommand implements COMMAND INFO [command-name ...]. Unknown
+// commands are reported as nulls.
+func commandInfoCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args) == 2 {
+		return commandCommand(client, args)
+	}
+	infos := make([]Resp.RESP, len(args)-2)
+	for i, name := range args[2:] {
+		if cmd := lookupCommandOrSubcommand(name.String()); cmd != nil {
+			infos[i] = commandInfo(cmd)
+		} else {
+			infos[i] = Resp.NewNullArray()
+		}
+	}
+	return Resp.NewArray(infos)
+}
+
+// commandDocsCommand implements COMMAND DOCS [command-name ...]. Unknown
+// commands are left out of the reply.
+func commandDocsCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	var cmds []*Command
+	if len(args) == 2 {
+		cmds = sortedCommands()
+	} else {
+		for _, name := range args[2:] {
+			if cmd := lookupCommandOrSubcommand(name.String()); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+	}
+	docs := make([]Resp.RESP, 0, len(cmds)*2)
+	for _, cmd := range cmds {
+		docs = append(docs, Resp.NewBulkString(cmd.FullName()), commandDocs(cmd))
+	}
+	return Resp.NewMap(docs)
+}
+
+func commandHelpCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return helpReply("COMMAND", []string{
+		"(no subcommand)",
+		"    Return details about all Redis commands.",
+		"COUNT",
+		"    Return the total number of commands in this Redis server.",
+		"DOCS [<command-name> ...]",
+		"    Return documentation details about multiple Redis commands.",
+		"    If no command names are given, documentation details for all",
+		"    commands are returned.",
+		"INFO [<command-name> ...]",
+		"    Return details about multiple Redis commands.",
+		"    If no command names are given, documentation details for all",
+		"    commands are returned.",
+	})
+}
+
+// helpReply formats the reply of a HELP subcommand.
+func helpReply(name string, lines []string) Resp.RESP {
+	reply := make([]Resp.RESP, 0, len(lines)+3)
+	reply = append(reply, Resp.NewSimpleString(name+" <subcommand> [<arg> [value] [opt] ...]. Subcommands are:"))
+	for _, line := range lines {
+		reply = append(reply, Resp.NewSimpleString(line))
+	}
+	reply = append(reply, Resp.NewSimpleString("HELP"), Resp.NewSimpleString("    Print this help."))
+	return Resp.NewArray(reply)
+}
+
+// lookupCommandOrSubcommand resolves names such as "get" or "command|info".
+func lookupCommandOrSubcommand(name string) *Command {
+	parts := strings.SplitN(name, "|", 2)
+	cmd := lookupCommand([]byte(parts[0]))
+	if cmd == nil || len(parts) == 1 {
+		return cmd
+	}
+	return cmd.lookupSubcommand([]byte(parts[1]))
+}
+
+func commandInfo(cmd *Command) Resp.RESP {
+	flags := make([]Resp.RESP, len(cmd.Flags))
+	for i, flag := range cmd.Flags {
+		flags[i] = Resp.NewSimpleString(flag)
+	}
+	categories := aclCategories(cmd)
+	categoryReplies := make([]Resp.RESP, len(categories))
+	for i, category := range categories {
+		categoryReplies[i] = Resp.NewSimpleString(category)
+	}
+	specs := cmd.keySpecs()
+	specReplies := make([]Resp.RESP, len(specs))
+	for i, spec := range specs {
+		specReplies[i] = keySpecInfo(spec)
+	}
+	subcommands := make([]Resp.RESP, len(cmd.Subcommands))
+	for i, sub := range cmd.Subcommands {
+		subcommands[i] = commandInfo(sub)
+	}
+	return Resp.NewArray([]Resp.RESP{
+		Resp.NewBulkString(cmd.FullName()),
+		Resp.NewInteger(int64(cmd.Arity)),
+		Resp.NewSet(flags),
+		Resp.NewInteger(int64(cmd.FirstKey)),
+		Resp.NewInteger(int64(cmd.LastKey)),
+		Resp.NewInteger(int64(cmd.Step)),
+		Resp.NewSet(categoryReplies),
+		Resp.NewArray([]Resp.RESP{}),
+		Resp.NewArray(specReplies),
+		Resp.NewArray(subcommands),
+	})
+}
+
+func commandDocs(cmd *Command) Resp.RESP {
+	docs := []Resp.RESP{
+		Resp.NewBulkString("summary"), Resp.NewBulkString(cmd.Summary),
+		Resp.NewBulkString("since"), Resp.NewBulkString(cmd.Since),
+		Resp.NewBulkString("group"), Resp.NewBulkString(cmd.Group),
+	}
+	if cmd.Complexity != "" {
+		docs = append(docs, Resp.NewBulkString("complexity"), Resp.NewBulkString(cmd.Complexity))
+	}
+	if len(cmd.Subcommands) > 0 {
+		subcommands := make([]Resp.RESP, 0, len(cmd.Subcommands)*2)
+		for _, sub := range cmd.Subcommands {
+			subcommands = append(subcommands, Resp.NewBulkString(sub.FullName()), commandDocs(sub))
+		}
+		docs = append(docs, Resp.NewBulkString("subcommands"), Resp.NewMap(subcommands))
+	}
+	return Resp.NewMap(docs)
+}
+
+// aclCategories derives the ACL categories of a command from its flags and
+// group, the way Redis assigns them in its command table.
+func aclCategories(cmd *Command) []string {
+	var categories []string
+	if cmd.HasFlag("write") {
+		categories = append(categories, "@write")
+	}
+	if cmd.HasFlag("readonly") {
+		categories = append(categories, "@read")
+	}
+	if cmd.HasFlag("admin") {
+		categories = append(categories, "@admin", "@dangerous")
+	}
+	if cmd.HasFlag("fast") {
+		categories = append(categories, "@fast")
+	} else {
+		categories = append(categories, "@slow")
+	}
+	if cmd.HasFlag("blocking") {
+		categories = append(categories, "@blocking")
+	}
+	switch cmd.Group {
+	case "string", "list", "hash", "set", "stream", "bitmap", "hyperloglog", "geo", "connection":
+		categories = append(categories, "@"+cmd.Group)
+	case "sorted-set":
+		categories = append(categories, "@sortedset")
+	case "generic":
+		categories = append(categories, "@keyspace")
+	}
+	return categories
+}
+
+// keySpecs returns the explicit key specs of the command or derives a
+// single range spec from its first key, last key and step.
+func (c *Command) keySpecs() []KeySpec {
+	if c.KeySpecs != nil {
+		return c.KeySpecs
+	}
+	if c.FirstKey == 0 {
+		return nil
+	}
+	lastKey := c.LastKey
+	if lastKey > 0 {
+		lastKey -= c.FirstKey
+	}
+	flags := []string{"RW", "ACCESS", "UPDATE"}
+	if c.HasFlag("readonly") {
+		flags = []string{"RO", "ACCESS"}
+	}
+	return []KeySpec{{Flags: flags, BeginIndex: c.FirstKey, LastKey: lastKey, KeyStep: c.Step}}
+}
+
+func keySpecInfo(spec KeySpec) Resp.RESP {
+	flags := make([]Resp.RESP, len(spec.Flags))
+	for i, flag := range spec.Flags {
+		flags[i] = Resp.NewSimpleString(flag)
+	}
+	var beginSearch Resp.RESP
+	if spec.BeginIndex > 0 {
+		beginSearch = Resp.NewMap([]Resp.RESP{
+			Resp.NewBulkString("type"), Resp.NewBulkString("index"),
+			Resp.NewBulkString("spec"), Resp.NewMap([]Resp.RESP{
+				Resp.NewBulkString("index"), Resp.NewInteger(int64(spec.BeginIndex)),
+			}),
+		})
+	} else {
+		beginSearch = Resp.NewMap([]Resp.RESP{
+			Resp.NewBulkString("type"), Resp.NewBulkString("keyword"),
+			Resp.NewBulkString("spec"), Resp.NewMap([]Resp.RESP{
+				Resp.NewBulkString("keyword"), Resp.NewBulkString(spec.Keyword),
+				Resp.NewBulkString("startfrom"), Resp.NewInteger(int64(spec.KeywordStart)),
+			}),
+		})
+	}
+	var findKeys Resp.RESP
+	// keynum specs always have a first key, while the index of the number
+	// of keys is often 0
+	if spec.FirstKey > 0 {
+		findKeys = Resp.NewMap([]Resp.RESP{
+			Resp.NewBulkString("type"), Resp.NewBulkString("keynum"),
+			Resp.NewBulkString("spec"), Resp.NewMap([]Resp.RESP{
+				Resp.NewBulkString("keynumidx"), Resp.NewInteger(int64(spec.KeyNumIndex)),
+				Resp.NewBulkString("firstkey"), Resp.NewInteger(int64(spec.FirstKey)),
+				Resp.NewBulkString("keystep"), Resp.NewInteger(int64(spec.KeyStep)),
+			}),
+		})
+	} else {
+		findKeys = Resp.NewMap([]Resp.RESP{
+			Resp.NewBulkString("type"), Resp.NewBulkString("range"),
+			Resp.NewBulkString("spec"), Resp.NewMap([]Resp.RESP{
+				Resp.NewBulkString("lastkey"), Resp.NewInteger(int64(spec.LastKey)),
+				Resp.NewBulkString("keystep"), Resp.NewInteger(int64(spec.KeyStep)),
+				Resp.NewBulkString("limit"), Resp.NewInteger(int64(spec.Limit)),
+			}),
+		})
+	}
+	return Resp.NewMap([]Resp.RESP{
+		Resp.NewBulkString("flags"), Resp.NewSet(flags),
+		Resp.NewBulkString("begin_search"), beginSearch,
+		Resp.NewBulkString("find_keys"), findKeys,
+	})
+}
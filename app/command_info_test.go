@@ -0,0 +1,129 @@
+package main
+
+import (
+	Resp "redis-go/pkg/resp"
+	"testing"
+)
+
+func simples(strs ...string) Resp.RESP {
+	elems := make([]Resp.RESP, len(strs))
+	for i, s := range strs {
+		elems[i] = Resp.NewSimpleString(s)
+	}
+	return Resp.NewSet(elems)
+}
+
+// keySpecReply builds a key spec the way COMMAND INFO replies with it.
+func keySpecReply(flags Resp.RESP, beginType string, begin []Resp.RESP, findType string, find []Resp.RESP) Resp.RESP {
+	return Resp.NewMap([]Resp.RESP{
+		Resp.NewBulkString("flags"), flags,
+		Resp.NewBulkString("begin_search"), Resp.NewMap([]Resp.RESP{
+			Resp.NewBulkString("type"), Resp.NewBulkString(beginType),
+			Resp.NewBulkString("spec"), Resp.NewMap(begin),
+		}),
+		Resp.NewBulkString("find_keys"), Resp.NewMap([]Resp.RESP{
+			Resp.NewBulkString("type"), Resp.NewBulkString(findType),
+			Resp.NewBulkString("spec"), Resp.NewMap(find),
+		}),
+	})
+}
+
+func indexSpec(index int64) []Resp.RESP {
+	return []Resp.RESP{Resp.NewBulkString("index"), Resp.NewInteger(index)}
+}
+
+func rangeSpec(lastKey, keyStep, limit int64) []Resp.RESP {
+	return []Resp.RESP{
+		Resp.NewBulkString("lastkey"), Resp.NewInteger(lastKey),
+		Resp.NewBulkString("keystep"), Resp.NewInteger(keyStep),
+		Resp.NewBulkString("limit"), Resp.NewInteger(limit),
+	}
+}
+
+func keynumSpec(keyNumIndex, firstKey, keyStep int64) []Resp.RESP {
+	return []Resp.RESP{
+		Resp.NewBulkString("keynumidx"), Resp.NewInteger(keyNumIndex),
+		Resp.NewBulkString("firstkey"), Resp.NewInteger(firstKey),
+		Resp.NewBulkString("keystep"), Resp.NewInteger(keyStep),
+	}
+}
+
+// commandInfoReply runs COMMAND INFO name and returns the info of name.
+func commandInfoReply(t *testing.T, name string) []Resp.RESP {
+	t.Helper()
+	reply := run(newTestClient(newTestDB(t)), "COMMAND", "INFO", name)
+	if len(reply.Array()) != 1 || len(reply.Array()[0].Array()) != 10 {
+		t.Fatalf("Expected the info of %s, got %q", name, reply.Serialize())
+	}
+	return reply.Array()[0].Array()
+}
+
+func TestCommandInfoFixedRange(t *testing.T) {
+	info := commandInfoReply(t, "get")
+	expectReply(t, Resp.NewArray(info), Resp.NewArray([]Resp.RESP{
+		Resp.NewBulkString("get"),
+		Resp.NewInteger(2),
+		simples("readonly", "fast"),
+		Resp.NewInteger(1), Resp.NewInteger(1), Resp.NewInteger(1),
+		simples("@read", "@fast", "@string"),
+		Resp.NewArray([]Resp.RESP{}),
+		Resp.NewArray([]Resp.RESP{
+			keySpecReply(simples("RO", "ACCESS"), "index", indexSpec(1), "range", rangeSpec(0, 1, 0)),
+		}),
+		Resp.NewArray([]Resp.RESP{}),
+	}))
+}
+
+func TestCommandInfoKeynum(t *testing.T) {
+	info := commandInfoReply(t, "blmpop")
+	expectReply(t, info[8], Resp.NewArray([]Resp.RESP{
+		keySpecReply(simples("RW", "ACCESS", "DELETE"), "index", indexSpec(2), "keynum", keynumSpec(0, 1, 1)),
+	}))
+
+	info = commandInfoReply(t, "zunionstore")
+	expectReply(t, info[8], Resp.NewArray([]Resp.RESP{
+		keySpecReply(simples("OW", "UPDATE"), "index", indexSpec(1), "range", rangeSpec(0, 1, 0)),
+		keySpecReply(simples("RO", "ACCESS"), "index", indexSpec(2), "keynum", keynumSpec(0, 1, 1)),
+	}))
+}
+
+func TestCommandInfoKeyword(t *testing.T) {
+	info := commandInfoReply(t, "xread")
+	expectReply(t, info[8], Resp.NewArray([]Resp.RESP{
+		keySpecReply(simples("RO", "ACCESS"), "keyword", []Resp.RESP{
+			Resp.NewBulkString("keyword"), Resp.NewBulkString("STREAMS"),
+			Resp.NewBulkString("startfrom"), Resp.NewInteger(1),
+		}, "range", rangeSpec(-1, 1, 2)),
+	}))
+}
+
+func TestCommandInfoUnknown(t *testing.T) {
+	client := newTestClient(newTestDB(t))
+	reply := run(client, "COMMAND", "INFO", "nosuchcommand", "command|count")
+	if len(reply.Array()) != 2 {
+		t.Fatalf("Expected two entries, got %q", reply.Serialize())
+	}
+	expectReply(t, reply.Array()[0], Resp.NewNullArray())
+	expectReply(t, reply.Array()[1].Array()[0], Resp.NewBulkString("command|count"))
+}
+
+func TestCommandCount(t *testing.T) {
+	client := newTestClient(newTestDB(t))
+	expectReply(t, run(client, "COMMAND", "COUNT"), Resp.NewInteger(int64(len(commandTable))))
+	all := run(client, "COMMAND")
+	if n := len(all.Array()); n != len(commandTable) {
+		t.Errorf("Expected COMMAND to list %d commands, got %d", len(commandTable), n)
+	}
+}
+
+func TestCommandDocs(t *testing.T) {
+	client := newTestClient(newTestDB(t))
+	expectReply(t, run(client, "COMMAND", "DOCS", "get", "nosuchcommand"), Resp.NewMap([]Resp.RESP{
+		Resp.NewBulkString("get"), Resp.NewMap(argv(
+			"summary", "Returns the string value of a key.",
+			"since", "1.0.0",
+			"group", "string",
+			"complexity", "O(1)",
+		)),
+	}))
+}
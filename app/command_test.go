@@ -0,0 +1,41 @@
+package main
+
+import (
+	Resp "redis-go/pkg/resp"
+	"testing"
+)
+
+func TestHandleCommandErrors(t *testing.T) {
+	client := newTestClient(newTestDB(t))
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		// fixed arity
+		{[]string{"GET"}, "ERR wrong number of arguments for 'get' command"},
+		{[]string{"GET", "a", "b"}, "ERR wrong number of arguments for 'get' command"},
+		{[]string{"get"}, "ERR wrong number of arguments for 'get' command"},
+		// variadic, with a minimum number of arguments
+		{[]string{"SET", "k"}, "ERR wrong number of arguments for 'set' command"},
+		{[]string{"DEL"}, "ERR wrong number of arguments for 'del' command"},
+		{[]string{"MSET", "a"}, "ERR wrong number of arguments for 'mset' command"},
+		// subcommands
+		{[]string{"COMMAND", "COUNT", "x"}, "ERR wrong number of arguments for 'command|count' command"},
+		{[]string{"COMMAND", "NOPE"}, "ERR unknown subcommand 'NOPE'. Try COMMAND HELP."},
+		// unknown commands
+		{[]string{"NOPE"}, "ERR unknown command 'NOPE', with args beginning with: "},
+		{[]string{"NOPE", "a", "b"}, "ERR unknown command 'NOPE', with args beginning with: 'a' 'b' "},
+		// an option missing its value used to crash the server
+		{[]string{"SET", "k", "v", "PX"}, "ERR syntax error"},
+	} {
+		expectReply(t, run(client, tt.args...), Resp.NewError(tt.want))
+	}
+	expectReply(t, run(client, "EXISTS", "k"), Resp.NewInteger(0))
+}
+
+func TestHandleCommandRunsValidCommands(t *testing.T) {
+	client := newTestClient(newTestDB(t))
+	expectReply(t, run(client, "set", "k", "v", "px", "100000"), okReply)
+	expectReply(t, run(client, "GET", "k"), Resp.NewBulkString("v"))
+	expectReply(t, run(client, "DEL", "k", "missing"), Resp.NewInteger(1))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "ping", Arity: -1, Flags: []string{"fast"},
+			Group: "connection", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Returns the server's liveliness response.",
+			Handler: pingCommand,
+		},
+		&Command{
+			Name: "echo", Arity: 2, Flags: []string{"fast"},
+			Group: "connection", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Returns the given string.",
+			Handler: echoCommand,
+		},
+		&Command{
+			Name: "hello", Arity: -1, Flags: []string{"noscript", "loading", "stale", "fast", "no_auth"},
+			Group: "connection", Since: "6.0.0", Complexity: "O(1)",
+			Summary: "Handshakes with the Redis server.",
+			Handler: helloCommand,
+		},
+	)
+}
+
+func pingCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args) > 2 {
+		return wrongArityError("ping")
+	}
+	if len(args) == 1 {
+		return Resp.NewSimpleString("PONG")
+	}
+	return Resp.NewBulk(args[1].Bytes())
+}
+
+func echoCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return Resp.NewBulk(args[1].Bytes())
+}
+
+// helloCommand implements HELLO [protover [AUTH username password] [SETNAME clientname]].
+func helloCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	protocol := client.protocol
+	if len(args) > 1 {
+		version, err := strconv.Atoi(args[1].String())
+		if err != nil {
+			return Resp.NewError("ERR Protocol version is not an integer or out of range")
+		}
+		if version < 2 || version > 3 {
+			return unsupportedProtocol
+		}
+		protocol = version
+	}
+	name := client.name
+	for i := 2; i < len(args); i++ {
+		option := strings.ToUpper(args[i].String())
+		switch {
+		case option == "AUTH" && i+2 < len(args):
+			// there is no ACL support, only the default user exists
+			if args[i+1].String() != "default" {
+				return Resp.NewError("WRONGPASS invalid username-password pair or user is disabled.")
+			}
+			i += 2
+		case option == "SETNAME" && i+1 < len(args):
+			name = args[i+1].String()
+			if strings.ContainsAny(name, " \n") {
+				return Resp.NewError("ERR Client names cannot contain spaces, newlines or special characters.")
+			}
+			i++
+		default:
+			return Resp.NewError("ERR Syntax error in HELLO option '" + args[i].String() + "'")
+		}
+	}
+	client.protocol = protocol
+	client.name = name
+
+	role := "master"
+	if config.role == "slave" {
+		role = "replica"
+	}
+	return Resp.NewMap([]Resp.RESP{
+		Resp.NewBulkString("server"), Resp.NewBulkString("redis"),
+		Resp.NewBulkString("version"), Resp.NewBulkString(serverVersion),
+		Resp.NewBulkString("proto"), Resp.NewInteger(int64(protocol)),
+		Resp.NewBulkString("id"), Resp.NewInteger(client.id),
+		Resp.NewBulkString("mode"), Resp.NewBulkString("standalone"),
+		Resp.NewBulkString("role"), Resp.NewBulkString(role),
+		Resp.NewBulkString("modules"), Resp.NewArray([]Resp.RESP{}),
+	})
+}
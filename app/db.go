@@ -0,0 +1,108 @@
+package main
+
+import (
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "select", Arity: 2, Flags: []string{"loading", "stale", "fast"},
+			Group: "connection", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Changes the selected database.",
+			Handler: selectCommand,
+		},
+		&Command{
+			Name: "move", Arity: 3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Moves a key to another database.",
+			Handler: moveCommand,
+		},
+		&Command{
+			Name: "swapdb", Arity: 3, Flags: []string{"write", "fast"},
+			Group: "server", Since: "4.0.0", Complexity: "O(N) where N is the count of clients watching or blocking on keys from both databases.",
+			Summary: "Swaps two Redis databases.",
+			Handler: swapdbCommand,
+		},
+	)
+}
+
+var errDBIndexOutOfRange = Resp.NewError("ERR DB index is out of range")
+
+// parseDBIndex parses the index of an existing database. invalid is the
+// error to reply when arg is not an integer.
+func parseDBIndex(arg Resp.RESP, invalid Resp.RESP) (int, Resp.RESP) {
+	index, errReply := parseInteger(arg)
+	if errReply.IsError() {
+		return 0, invalid
+	}
+	if index < 0 || index >= int64(len(databases)) {
+		return 0, errDBIndexOutOfRange
+	}
+	return int(index), Resp.RESP{}
+}
+
+// selectDB makes the database at index the one the client's commands
+// apply to.
+func (c *Client) selectDB(index int) {
+	c.db, c.dbIndex = databases[index], index
+}
+
+func selectCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	index, errReply := parseDBIndex(args[1], notIntegerError)
+	if errReply.IsError() {
+		return errReply
+	}
+	client.selectDB(index)
+	return okReply
+}
+
+// moveCommand implements MOVE key db.
+func moveCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	index, errReply := parseDBIndex(args[2], notIntegerError)
+	if errReply.IsError() {
+		return errReply
+	}
+	if index == client.dbIndex {
+		return Resp.NewError("ERR source and destination objects are the same")
+	}
+	key, dst := args[1].String(), databases[index]
+	if !client.db.Move(key, dst) {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	signalKeyAsReady(dst, key)
+	return Resp.NewInteger(1)
+}
+
+// swapdbCommand implements SWAPDB index1 index2. Clients keep the index
+// they selected, so they see the contents of the other database.
+func swapdbCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	index1, errReply := parseDBIndex(args[1], Resp.NewError("ERR invalid first DB index"))
+	if errReply.IsError() {
+		return errReply
+	}
+	index2, errReply := parseDBIndex(args[2], Resp.NewError("ERR invalid second DB index"))
+	if errReply.IsError() {
+		return errReply
+	}
+	if index1 == index2 {
+		return okReply
+	}
+	db1, db2 := databases[index1], databases[index2]
+	db1.Swap(db2)
+	scanDatabaseForReadyKeys(db1)
+	scanDatabaseForReadyKeys(db2)
+	return okReply
+}
+
+// scanDatabaseForReadyKeys signals the keys of db clients are blocked on
+// that hold a value, after SWAPDB changed its contents.
+func scanDatabaseForReadyKeys(db *store.Store) {
+	for bk := range blockedClients {
+		if bk.db == db && db.Lookup(bk.key) != nil {
+			signalKeyAsReady(db, bk.key)
+		}
+	}
+}
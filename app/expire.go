@@ -0,0 +1,200 @@
+package main
+
+import (
+	"math"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "expire", Arity: -3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Sets the expiration time of a key in seconds.",
+			Handler: expireCommand,
+		},
+		&Command{
+			Name: "pexpire", Arity: -3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "2.6.0", Complexity: "O(1)",
+			Summary: "Sets the expiration time of a key in milliseconds.",
+			Handler: pexpireCommand,
+		},
+		&Command{
+			Name: "expireat", Arity: -3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "1.2.0", Complexity: "O(1)",
+			Summary: "Sets the expiration time of a key to a Unix timestamp.",
+			Handler: expireatCommand,
+		},
+		&Command{
+			Name: "pexpireat", Arity: -3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "2.6.0", Complexity: "O(1)",
+			Summary: "Sets the expiration time of a key to a Unix milliseconds timestamp.",
+			Handler: pexpireatCommand,
+		},
+		&Command{
+			Name: "ttl", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Returns the expiration time in seconds of a key.",
+			Handler: ttlCommand,
+		},
+		&Command{
+			Name: "pttl", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "2.6.0", Complexity: "O(1)",
+			Summary: "Returns the expiration time in milliseconds of a key.",
+			Handler: pttlCommand,
+		},
+		&Command{
+			Name: "expiretime", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "7.0.0", Complexity: "O(1)",
+			Summary: "Returns the expiration time of a key as a Unix timestamp.",
+			Handler: expiretimeCommand,
+		},
+		&Command{
+			Name: "pexpiretime", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "7.0.0", Complexity: "O(1)",
+			Summary: "Returns the expiration time of a key as a Unix milliseconds timestamp.",
+			Handler: pexpiretimeCommand,
+		},
+		&Command{
+			Name: "persist", Arity: 2, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "2.2.0", Complexity: "O(1)",
+			Summary: "Removes the expiration time of a key.",
+			Handler: persistCommand,
+		},
+	)
+}
+
+func expireCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return expireGeneric(client, args, 1000, false)
+}
+
+func pexpireCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return expireGeneric(client, args, 1, false)
+}
+
+func expireatCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return expireGeneric(client, args, 1000, true)
+}
+
+func pexpireatCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return expireGeneric(client, args, 1, true)
+}
+
+// expireGeneric implements the EXPIRE family: key, a time in the given
+// unit (milliseconds per unit) either relative to now or absolute, and
+// one of the NX, XX, GT and LT conditions. The change is propagated as a
+// PEXPIREAT so replicas expire the key at the same moment.
+func expireGeneric(client *Client, args []Resp.RESP, unit int64, absolute bool) Resp.RESP {
+	name := strings.ToLower(args[0].String())
+	key := args[1].String()
+	when, err := strconv.ParseInt(args[2].String(), 10, 64)
+	if err != nil {
+		return notIntegerError
+	}
+	cond, errReply := parseExpireCondition(args[3:])
+	if errReply.IsError() {
+		return errReply
+	}
+	if when > math.MaxInt64/unit || when < math.MinInt64/unit {
+		return invalidExpireError(name)
+	}
+	when *= unit
+	if !absolute {
+		now := store.Now()
+		if (when > 0 && when > math.MaxInt64-now) || (when < 0 && when < math.MinInt64+now) {
+			return invalidExpireError(name)
+		}
+		when += now
+	}
+	if !client.db.Expire(key, when, cond) {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	client.rewritePropagation([]Resp.RESP{
+		Resp.NewBulkString("PEXPIREAT"),
+		args[1],
+		Resp.NewBulkString(strconv.FormatInt(when, 10)),
+	})
+	return Resp.NewInteger(1)
+}
+
+func parseExpireCondition(options []Resp.RESP) (store.ExpireCondition, Resp.RESP) {
+	nx, xx, gt, lt := false, false, false, false
+	for _, option := range options {
+		switch strings.ToUpper(option.String()) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GT":
+			gt = true
+		case "LT":
+			lt = true
+		default:
+			return store.ExpireAlways, Resp.NewError("ERR Unsupported option " + option.String())
+		}
+	}
+	if nx && (xx || gt || lt) {
+		return store.ExpireAlways, Resp.NewError("ERR NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if gt && lt {
+		return store.ExpireAlways, Resp.NewError("ERR GT and LT options at the same time are not compatible")
+	}
+	switch {
+	case nx:
+		return store.ExpireIfNoTTL, Resp.RESP{}
+	case gt:
+		return store.ExpireIfGreater, Resp.RESP{}
+	case lt:
+		return store.ExpireIfLess, Resp.RESP{}
+	case xx:
+		return store.ExpireIfHasTTL, Resp.RESP{}
+	}
+	return store.ExpireAlways, Resp.RESP{}
+}
+
+func ttlCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return ttlGeneric(client, args, 1000, false)
+}
+
+func pttlCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return ttlGeneric(client, args, 1, false)
+}
+
+func expiretimeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return ttlGeneric(client, args, 1000, true)
+}
+
+func pexpiretimeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return ttlGeneric(client, args, 1, true)
+}
+
+// ttlGeneric replies with the remaining time to live, or the absolute
+// expiration time, of a key in the given unit. Missing keys reply -2 and
+// keys without expiration -1.
+func ttlGeneric(client *Client, args []Resp.RESP, unit int64, absolute bool) Resp.RESP {
+	expireAt := client.db.ExpireTime(args[1].String())
+	if expireAt < 0 {
+		return Resp.NewInteger(expireAt)
+	}
+	if absolute {
+		return Resp.NewInteger(expireAt / unit)
+	}
+	ttl := expireAt - store.Now()
+	if ttl < 0 {
+		ttl = 0
+	}
+	// round to the closest unit like Redis does
+	return Resp.NewInteger((ttl + unit/2) / unit)
+}
+
+func persistCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if client.db.Persist(args[1].String()) {
+		return Resp.NewInteger(1)
+	}
+	client.rewritePropagation(nil)
+	return Resp.NewInteger(0)
+}
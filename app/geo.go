@@ -0,0 +1,414 @@
+package main
+
+import (
+	"fmt"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "geoadd", Arity: -5, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "geo", Since: "3.2.0", Complexity: "O(log(N)) for each item added, where N is the number of elements in the sorted set.",
+			Summary: "Adds one or more members to a geospatial index. The key is created if it doesn't exist.",
+			Handler: geoaddCommand,
+		},
+		&Command{
+			Name: "geopos", Arity: -2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "geo", Since: "3.2.0", Complexity: "O(1) for each member requested.",
+			Summary: "Returns the longitude and latitude of members from a geospatial index.",
+			Handler: geoposCommand,
+		},
+		&Command{
+			Name: "geodist", Arity: -4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "geo", Since: "3.2.0", Complexity: "O(1)",
+			Summary: "Returns the distance between two members of a geospatial index.",
+			Handler: geodistCommand,
+		},
+		&Command{
+			Name: "geohash", Arity: -2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "geo", Since: "3.2.0", Complexity: "O(1) for each member requested.",
+			Summary: "Returns members from a geospatial index as geohash strings.",
+			Handler: geohashCommand,
+		},
+		&Command{
+			Name: "geosearch", Arity: -7, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "geo", Since: "6.2.0", Complexity: "O(N+log(M)) where N is the number of elements in the grid-aligned bounding box area around the shape provided as the filter and M is the number of items inside the shape",
+			Summary: "Queries a geospatial index for members inside an area of a box or a circle.",
+			Handler: geosearchCommand,
+		},
+		&Command{
+			Name: "geosearchstore", Arity: -8, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: "geo", Since: "6.2.0", Complexity: "O(N+log(M)) where N is the number of elements in the grid-aligned bounding box area around the shape provided as the filter and M is the number of items inside the shape",
+			Summary: "Queries a geospatial index for members inside an area of a box or a circle, optionally stores the result.",
+			Handler: geosearchstoreCommand,
+		},
+	)
+}
+
+var errUnsupportedUnit = Resp.NewError("ERR unsupported unit provided. please use M, KM, FT, MI")
+
+// geoUnits are the meters in each distance unit.
+var geoUnits = map[string]float64{"m": 1, "km": 1000, "ft": 0.3048, "mi": 1609.34}
+
+// parseGeoUnit returns the meters in the unit arg.
+func parseGeoUnit(arg Resp.RESP) (float64, Resp.RESP) {
+	meters, ok := geoUnits[strings.ToLower(arg.String())]
+	if !ok {
+		return 0, errUnsupportedUnit
+	}
+	return meters, Resp.RESP{}
+}
+
+// parseLonLat parses a longitude and a latitude that can be indexed.
+func parseLonLat(lonArg, latArg Resp.RESP) (float64, float64, Resp.RESP) {
+	lon, ok := parseFloat(lonArg.Bytes())
+	if !ok {
+		return 0, 0, errNotFloat
+	}
+	lat, ok := parseFloat(latArg.Bytes())
+	if !ok {
+		return 0, 0, errNotFloat
+	}
+	if !store.GeoValid(lon, lat) {
+		return 0, 0, Resp.NewError(fmt.Sprintf("ERR invalid longitude,latitude pair %f,%f", lon, lat))
+	}
+	return lon, lat, Resp.RESP{}
+}
+
+// geoDistanceReply replies with a distance with 4 decimals, enough for
+// any unit.
+func geoDistanceReply(d float64) Resp.RESP {
+	return Resp.NewBulkString(strconv.FormatFloat(d, 'f', 4, 64))
+}
+
+func geoCoordReply(lon, lat float64) Resp.RESP {
+	return Resp.NewArray([]Resp.RESP{Resp.NewDouble(lon), Resp.NewDouble(lat)})
+}
+
+// geoaddCommand implements
+// GEOADD key [NX | XX] [CH] longitude latitude member [longitude latitude member ...].
+func geoaddCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	var flags zaddFlags
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i].String()) {
+		case "NX":
+			flags.nx = true
+		case "XX":
+			flags.xx = true
+		case "CH":
+			flags.ch = true
+		default:
+			break options
+		}
+	}
+	triples := args[i:]
+	if len(triples) == 0 || len(triples)%3 != 0 {
+		return syntaxError
+	}
+	if flags.nx && flags.xx {
+		return Resp.NewError("ERR XX and NX options at the same time are not compatible")
+	}
+	entries := make([]zsetEntry, len(triples)/3)
+	for j := range entries {
+		lon, lat, errReply := parseLonLat(triples[j*3], triples[j*3+1])
+		if errReply.IsError() {
+			return errReply
+		}
+		entries[j] = zsetEntry{triples[j*3+2].Bytes(), store.GeoEncode(lon, lat)}
+	}
+	return zaddGeneric(client, args[1].String(), flags, entries)
+}
+
+// geoposCommand implements GEOPOS key [member [member ...]].
+func geoposCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	reply := make([]Resp.RESP, len(args)-2)
+	for i, arg := range args[2:] {
+		reply[i] = Resp.NewNullArray()
+		if value == nil {
+			continue
+		}
+		if score, ok := value.ZSetScore(arg.Bytes()); ok {
+			reply[i] = geoCoordReply(store.GeoDecode(score))
+		}
+	}
+	return Resp.NewArray(reply)
+}
+
+// geodistCommand implements GEODIST key member1 member2 [M | KM | FT | MI].
+func geodistCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args) > 5 {
+		return syntaxError
+	}
+	unit := 1.0
+	if len(args) == 5 {
+		var errReply Resp.RESP
+		if unit, errReply = parseGeoUnit(args[4]); errReply.IsError() {
+			return errReply
+		}
+	}
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewNullBulkString()
+	}
+	score1, ok1 := value.ZSetScore(args[2].Bytes())
+	score2, ok2 := value.ZSetScore(args[3].Bytes())
+	if !ok1 || !ok2 {
+		return Resp.NewNullBulkString()
+	}
+	lon1, lat1 := store.GeoDecode(score1)
+	lon2, lat2 := store.GeoDecode(score2)
+	return geoDistanceReply(store.GeoDistance(lon1, lat1, lon2, lat2) / unit)
+}
+
+// geohashCommand implements GEOHASH key [member [member ...]].
+func geohashCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	reply := make([]Resp.RESP, len(args)-2)
+	for i, arg := range args[2:] {
+		reply[i] = Resp.NewNullBulkString()
+		if value == nil {
+			continue
+		}
+		if score, ok := value.ZSetScore(arg.Bytes()); ok {
+			reply[i] = Resp.NewBulkString(store.GeoHashString(score))
+		}
+	}
+	return Resp.NewArray(reply)
+}
+
+// geoSearchRequest is a parsed GEOSEARCH or GEOSEARCHSTORE.
+type geoSearchRequest struct {
+	fromMember, fromLonLat        bool
+	member                        []byte
+	byRadius, byBox               bool
+	shape                         store.GeoShape
+	unit                          float64
+	sort                          int // 0 unsorted, 1 ascending, -1 descending
+	count                         int64
+	any                           bool
+	withCoord, withDist, withHash bool
+	storeDist                     bool
+}
+
+// parseGeoSearch parses the options of GEOSEARCH, those of GEOSEARCHSTORE
+// with storing.
+func parseGeoSearch(cmd string, args []Resp.RESP, storing bool) (geoSearchRequest, Resp.RESP) {
+	var req geoSearchRequest
+	var errReply Resp.RESP
+	for i := 0; i < len(args); i++ {
+		left := len(args) - i - 1
+		switch opt := strings.ToUpper(args[i].String()); {
+		case opt == "FROMMEMBER" && left >= 1:
+			if req.fromMember || req.fromLonLat {
+				return req, syntaxError
+			}
+			req.fromMember, req.member = true, args[i+1].Bytes()
+			i++
+		case opt == "FROMLONLAT" && left >= 2:
+			if req.fromMember || req.fromLonLat {
+				return req, syntaxError
+			}
+			req.fromLonLat = true
+			if req.shape.Lon, req.shape.Lat, errReply = parseLonLat(args[i+1], args[i+2]); errReply.IsError() {
+				return req, errReply
+			}
+			i += 2
+		case opt == "BYRADIUS" && left >= 2:
+			if req.byRadius || req.byBox {
+				return req, syntaxError
+			}
+			req.byRadius = true
+			radius, ok := parseFloat(args[i+1].Bytes())
+			if !ok {
+				return req, Resp.NewError("ERR need numeric radius")
+			}
+			if radius < 0 {
+				return req, Resp.NewError("ERR radius cannot be negative")
+			}
+			if req.unit, errReply = parseGeoUnit(args[i+2]); errReply.IsError() {
+				return req, errReply
+			}
+			req.shape.Radius = radius * req.unit
+			i += 2
+		case opt == "BYBOX" && left >= 3:
+			if req.byRadius || req.byBox {
+				return req, syntaxError
+			}
+			req.byBox = true
+			width, ok := parseFloat(args[i+1].Bytes())
+			if !ok {
+				return req, Resp.NewError("ERR need numeric width")
+			}
+			height, ok := parseFloat(args[i+2].Bytes())
+			if !ok {
+				return req, Resp.NewError("ERR need numeric height")
+			}
+			if width < 0 || height < 0 {
+				return req, Resp.NewError("ERR height or width cannot be negative")
+			}
+			if req.unit, errReply = parseGeoUnit(args[i+3]); errReply.IsError() {
+				return req, errReply
+			}
+			req.shape.Box, req.shape.Width, req.shape.Height = true, width*req.unit, height*req.unit
+			i += 3
+		case opt == "ASC":
+			req.sort = 1
+		case opt == "DESC":
+			req.sort = -1
+		case opt == "COUNT" && left >= 1:
+			if req.count, errReply = parseInteger(args[i+1]); errReply.IsError() {
+				return req, errReply
+			}
+			if req.count <= 0 {
+				return req, Resp.NewError("ERR COUNT must be > 0")
+			}
+			i++
+		case opt == "ANY":
+			req.any = true
+		case opt == "WITHCOORD":
+			req.withCoord = true
+		case opt == "WITHDIST":
+			req.withDist = true
+		case opt == "WITHHASH":
+			req.withHash = true
+		case opt == "STOREDIST" && storing:
+			req.storeDist = true
+		default:
+			return req, syntaxError
+		}
+	}
+	if storing && (req.withCoord || req.withDist || req.withHash) {
+		return req, Resp.NewError(fmt.Sprintf("ERR %s is not compatible with WITHDIST, WITHHASH and WITHCOORD options", cmd))
+	}
+	if !req.fromMember && !req.fromLonLat {
+		return req, Resp.NewError(fmt.Sprintf("ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for %s", cmd))
+	}
+	if !req.byRadius && !req.byBox {
+		return req, Resp.NewError(fmt.Sprintf("ERR exactly one of BYRADIUS and BYBOX can be specified for %s", cmd))
+	}
+	if req.any && req.count == 0 {
+		return req, Resp.NewError("ERR the ANY argument requires COUNT argument")
+	}
+	// the nearest members are returned unless any will do
+	if req.count > 0 && req.sort == 0 && !req.any {
+		req.sort = 1
+	}
+	return req, Resp.RESP{}
+}
+
+// geoSearch returns the members of the geo index value matching req,
+// sorted and limited as requested.
+func geoSearch(value *store.Value, req geoSearchRequest) ([]store.GeoPoint, Resp.RESP) {
+	if req.fromMember {
+		score, ok := value.ZSetScore(req.member)
+		if !ok {
+			return nil, Resp.NewError("ERR could not decode requested zset member")
+		}
+		req.shape.Lon, req.shape.Lat = store.GeoDecode(score)
+	}
+	limit := 0
+	if req.any {
+		limit = int(req.count)
+	}
+	points := value.GeoSearch(req.shape, limit)
+	if req.sort != 0 {
+		sort.SliceStable(points, func(i, j int) bool {
+			if req.sort < 0 {
+				return points[i].Dist > points[j].Dist
+			}
+			return points[i].Dist < points[j].Dist
+		})
+	}
+	if req.count > 0 && int64(len(points)) > req.count {
+		points = points[:req.count]
+	}
+	return points, Resp.RESP{}
+}
+
+// geosearchCommand implements GEOSEARCH key <FROMMEMBER member |
+// FROMLONLAT longitude latitude> <BYRADIUS radius <M | KM | FT | MI> |
+// BYBOX width height <M | KM | FT | MI>> [ASC | DESC] [COUNT count [ANY]]
+// [WITHCOORD] [WITHDIST] [WITHHASH].
+func geosearchCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	req, errReply := parseGeoSearch(args[0].String(), args[2:], false)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewArray([]Resp.RESP{})
+	}
+	points, errReply := geoSearch(value, req)
+	if errReply.IsError() {
+		return errReply
+	}
+	reply := make([]Resp.RESP, len(points))
+	for i, p := range points {
+		if !req.withDist && !req.withHash && !req.withCoord {
+			reply[i] = Resp.NewBulk(p.Member)
+			continue
+		}
+		item := []Resp.RESP{Resp.NewBulk(p.Member)}
+		if req.withDist {
+			item = append(item, geoDistanceReply(p.Dist/req.unit))
+		}
+		if req.withHash {
+			item = append(item, Resp.NewInteger(int64(p.Score)))
+		}
+		if req.withCoord {
+			item = append(item, geoCoordReply(p.Lon, p.Lat))
+		}
+		reply[i] = Resp.NewArray(item)
+	}
+	return Resp.NewArray(reply)
+}
+
+// geosearchstoreCommand implements GEOSEARCHSTORE destination source
+// with the options of GEOSEARCH but the WITH ones, and STOREDIST to store
+// the distances instead of the geohashes.
+func geosearchstoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupZSet(client, args[2].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	req, errReply := parseGeoSearch(args[0].String(), args[3:], true)
+	if errReply.IsError() {
+		return errReply
+	}
+	result := store.NewZSet()
+	if value != nil {
+		points, errReply := geoSearch(value, req)
+		if errReply.IsError() {
+			return errReply
+		}
+		for _, p := range points {
+			score := p.Score
+			if req.storeDist {
+				score = p.Dist / req.unit
+			}
+			result.ZSetAdd(p.Member, score)
+		}
+	}
+	zsetStore(client, args[1].String(), result)
+	return Resp.NewInteger(int64(result.ZSetLen()))
+}
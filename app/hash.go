@@ -0,0 +1,441 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "hset", Arity: -4, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(1) for each field/value pair added, so O(N) to add N field/value pairs when the command is called with multiple field/value pairs.",
+			Summary: "Creates or modifies the value of a field in a hash.",
+			Handler: hsetCommand,
+		},
+		&Command{
+			Name: "hsetnx", Arity: 4, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(1)",
+			Summary: "Sets the value of a field in a hash only when the field doesn't exist.",
+			Handler: hsetnxCommand,
+		},
+		&Command{
+			Name: "hget", Arity: 3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(1)",
+			Summary: "Returns the value of a field in a hash.",
+			Handler: hgetCommand,
+		},
+		&Command{
+			Name: "hmget", Arity: -3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(N) where N is the number of fields being requested.",
+			Summary: "Returns the values of all fields in a hash.",
+			Handler: hmgetCommand,
+		},
+		&Command{
+			Name: "hgetall", Arity: 2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(N) where N is the size of the hash.",
+			Summary: "Returns all fields and values in a hash.",
+			Handler: hgetallCommand,
+		},
+		&Command{
+			Name: "hdel", Arity: -3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(N) where N is the number of fields to be removed.",
+			Summary: "Deletes one or more fields and their values from a hash. Deletes the hash if no fields remain.",
+			Handler: hdelCommand,
+		},
+		&Command{
+			Name: "hexists", Arity: 3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(1)",
+			Summary: "Determines whether a field exists in a hash.",
+			Handler: hexistsCommand,
+		},
+		&Command{
+			Name: "hlen", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(1)",
+			Summary: "Returns the number of fields in a hash.",
+			Handler: hlenCommand,
+		},
+		&Command{
+			Name: "hkeys", Arity: 2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(N) where N is the size of the hash.",
+			Summary: "Returns all fields in a hash.",
+			Handler: hkeysCommand,
+		},
+		&Command{
+			Name: "hvals", Arity: 2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(N) where N is the size of the hash.",
+			Summary: "Returns all values in a hash.",
+			Handler: hvalsCommand,
+		},
+		&Command{
+			Name: "hincrby", Arity: 4, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.0.0", Complexity: "O(1)",
+			Summary: "Increments the integer value of a field in a hash by a number. Uses 0 as initial value if the field doesn't exist.",
+			Handler: hincrbyCommand,
+		},
+		&Command{
+			Name: "hincrbyfloat", Arity: 4, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.6.0", Complexity: "O(1)",
+			Summary: "Increments the floating point value of a field by a number. Uses 0 as initial value if the field doesn't exist.",
+			Handler: hincrbyfloatCommand,
+		},
+		&Command{
+			Name: "hrandfield", Arity: -2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "6.2.0", Complexity: "O(N) where N is the number of fields returned",
+			Summary: "Returns one or more random fields from a hash.",
+			Handler: hrandfieldCommand,
+		},
+		&Command{
+			Name: "hscan", Arity: -3, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hash", Since: "2.8.0", Complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection.",
+			Summary: "Iterates over fields and values of a hash.",
+			Handler: hscanCommand,
+		},
+	)
+}
+
+// lookupHash returns the hash stored at key, or nil if there is none. On
+// failure the returned reply is the error to send.
+func lookupHash(client *Client, key string) (*store.Value, Resp.RESP) {
+	value, err := client.db.LookupType(key, store.ObjHash)
+	if err != nil {
+		return nil, wrongTypeError
+	}
+	return value, Resp.RESP{}
+}
+
+// lookupOrCreateHash returns the hash stored at key, creating an empty one
+// if there is none.
+func lookupOrCreateHash(client *Client, key string) (*store.Value, Resp.RESP) {
+	value, errReply := lookupHash(client, key)
+	if errReply.IsError() || value != nil {
+		return value, errReply
+	}
+	value = store.NewHash()
+	client.db.SetValue(key, value)
+	return value, Resp.RESP{}
+}
+
+func hsetCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args)%2 != 0 {
+		return wrongArityError("hset")
+	}
+	value, errReply := lookupOrCreateHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	added := 0
+	for i := 2; i < len(args); i += 2 {
+		if value.HashSet(args[i].Bytes(), args[i+1].Bytes()) {
+			added++
+		}
+	}
+	return Resp.NewInteger(int64(added))
+}
+
+func hsetnxCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupOrCreateHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if _, exists := value.HashGet(args[2].Bytes()); exists {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	value.HashSet(args[2].Bytes(), args[3].Bytes())
+	return Resp.NewInteger(1)
+}
+
+func hgetCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value != nil {
+		if field, ok := value.HashGet(args[2].Bytes()); ok {
+			return Resp.NewBulk(field)
+		}
+	}
+	return Resp.NewNullBulkString()
+}
+
+func hmgetCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	values := make([]Resp.RESP, len(args)-2)
+	for i, field := range args[2:] {
+		values[i] = Resp.NewNullBulkString()
+		if value == nil {
+			continue
+		}
+		if v, ok := value.HashGet(field.Bytes()); ok {
+			values[i] = Resp.NewBulk(v)
+		}
+	}
+	return Resp.NewArray(values)
+}
+
+func hgetallCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewMap([]Resp.RESP{})
+	}
+	entries := make([]Resp.RESP, 0, value.HashLen()*2)
+	value.HashRange(func(field, v []byte) bool {
+		entries = append(entries, Resp.NewBulk(field), Resp.NewBulk(v))
+		return true
+	})
+	return Resp.NewMap(entries)
+}
+
+func hdelCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key := args[1].String()
+	value, errReply := lookupHash(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	deleted := 0
+	if value != nil {
+		for _, field := range args[2:] {
+			if value.HashDelete(field.Bytes()) {
+				deleted++
+			}
+		}
+		client.db.Updated(key, value)
+	}
+	if deleted == 0 {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewInteger(int64(deleted))
+}
+
+func hexistsCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value != nil {
+		if _, ok := value.HashGet(args[2].Bytes()); ok {
+			return Resp.NewInteger(1)
+		}
+	}
+	return Resp.NewInteger(0)
+}
+
+func hlenCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(int64(value.HashLen()))
+}
+
+func hkeysCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return hashListGeneric(client, args, true)
+}
+
+func hvalsCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return hashListGeneric(client, args, false)
+}
+
+// hashListGeneric replies with all the fields or all the values of a hash.
+func hashListGeneric(client *Client, args []Resp.RESP, fields bool) Resp.RESP {
+	value, errReply := lookupHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewArray([]Resp.RESP{})
+	}
+	elements := make([]Resp.RESP, 0, value.HashLen())
+	value.HashRange(func(field, v []byte) bool {
+		if fields {
+			elements = append(elements, Resp.NewBulk(field))
+		} else {
+			elements = append(elements, Resp.NewBulk(v))
+		}
+		return true
+	})
+	return Resp.NewArray(elements)
+}
+
+func hincrbyCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	increment, errReply := parseInteger(args[3])
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupOrCreateHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	current := int64(0)
+	if field, ok := value.HashGet(args[2].Bytes()); ok {
+		n, err := strconv.ParseInt(string(field), 10, 64)
+		if err != nil {
+			return Resp.NewError("ERR hash value is not an integer")
+		}
+		current = n
+	}
+	if (increment < 0 && current < math.MinInt64-increment) || (increment > 0 && current > math.MaxInt64-increment) {
+		return Resp.NewError("ERR increment or decrement would overflow")
+	}
+	current += increment
+	value.HashSet(args[2].Bytes(), []byte(strconv.FormatInt(current, 10)))
+	return Resp.NewInteger(current)
+}
+
+// hincrbyfloatCommand implements HINCRBYFLOAT key field increment. It is
+// propagated as an HSET of the result so replicas don't depend on their
+// own float arithmetic.
+func hincrbyfloatCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	increment, ok := parseFloat(args[3].Bytes())
+	if !ok {
+		return Resp.NewError("ERR value is not a valid float")
+	}
+	if math.IsNaN(increment) || math.IsInf(increment, 0) {
+		return Resp.NewError("ERR value is NaN or Infinity")
+	}
+	value, errReply := lookupHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	current := 0.0
+	if value != nil {
+		if field, ok := value.HashGet(args[2].Bytes()); ok {
+			if current, ok = parseFloat(field); !ok {
+				return Resp.NewError("ERR hash value is not a float")
+			}
+		}
+	}
+	current += increment
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return Resp.NewError("ERR increment would produce NaN or Infinity")
+	}
+	// the hash is only created once the field is sure to be written
+	if value == nil {
+		value, _ = lookupOrCreateHash(client, args[1].String())
+	}
+	result := Resp.NewBulkString(Resp.FormatDouble(current))
+	value.HashSet(args[2].Bytes(), result.Bytes())
+	client.rewritePropagation([]Resp.RESP{Resp.NewBulkString("HSET"), args[1], args[2], result})
+	return result
+}
+
+var errValueOutOfRange = Resp.NewError("ERR value is out of range")
+
+// randomReplyCapacity returns the capacity to allocate for a reply of n
+// random elements, which may repeat. Huge counts grow the reply as it is
+// built instead of allocating it all up front.
+func randomReplyCapacity(n int64) int {
+	const maxCapacity = 1024
+	if n > maxCapacity {
+		return maxCapacity
+	}
+	return int(n)
+}
+
+// hrandfieldCommand implements HRANDFIELD key [count [WITHVALUES]]. A
+// negative count allows the same field to be returned several times.
+func hrandfieldCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args) > 4 || (len(args) == 4 && strings.ToUpper(args[3].String()) != "WITHVALUES") {
+		return syntaxError
+	}
+	value, errReply := lookupHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if len(args) == 2 {
+		if value == nil {
+			return Resp.NewNullBulkString()
+		}
+		field, _ := value.HashRandom()
+		return Resp.NewBulk(field)
+	}
+	count, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	withValues := len(args) == 4
+	// a negative count is negated, and doubled with values
+	if count == math.MinInt64 || (count < -math.MaxInt64/2 && withValues) {
+		return errValueOutOfRange
+	}
+	if value == nil || count == 0 {
+		return Resp.NewArray([]Resp.RESP{})
+	}
+	var pairs [][2][]byte
+	switch size := int64(value.HashLen()); {
+	case count < 0:
+		pairs = make([][2][]byte, 0, randomReplyCapacity(-count))
+		for i := int64(0); i < -count; i++ {
+			field, v := value.HashRandom()
+			pairs = append(pairs, [2][]byte{field, v})
+		}
+	case count >= size:
+		value.HashRange(func(field, v []byte) bool {
+			pairs = append(pairs, [2][]byte{field, v})
+			return true
+		})
+	default:
+		// a partial Fisher-Yates shuffle picks count distinct fields
+		value.HashRange(func(field, v []byte) bool {
+			pairs = append(pairs, [2][]byte{field, v})
+			return true
+		})
+		for i := 0; i < int(count); i++ {
+			j := i + rand.Intn(len(pairs)-i)
+			pairs[i], pairs[j] = pairs[j], pairs[i]
+		}
+		pairs = pairs[:count]
+	}
+	return fieldValueReply(client, pairs, withValues)
+}
+
+// fieldValueReply replies with fields, and their values when withValues is
+// set: flat in RESP2, and as an array of pairs in RESP3.
+func fieldValueReply(client *Client, pairs [][2][]byte, withValues bool) Resp.RESP {
+	reply := make([]Resp.RESP, 0, len(pairs)*2)
+	for _, pair := range pairs {
+		switch {
+		case !withValues:
+			reply = append(reply, Resp.NewBulk(pair[0]))
+		case client.protocol >= 3:
+			reply = append(reply, Resp.NewArray([]Resp.RESP{Resp.NewBulk(pair[0]), Resp.NewBulk(pair[1])}))
+		default:
+			reply = append(reply, Resp.NewBulk(pair[0]), Resp.NewBulk(pair[1]))
+		}
+	}
+	return Resp.NewArray(reply)
+}
+
+// hscanCommand implements HSCAN key cursor [MATCH pattern] [COUNT count].
+func hscanCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	cursor, errReply := parseScanCursor(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	opts, errReply := parseScanOptions(args[3:], false)
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupHash(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewArray([]Resp.RESP{Resp.NewBulkString("0"), Resp.NewArray([]Resp.RESP{})})
+	}
+	return scanGeneric(cursor, opts, true, value.HashScan)
+}
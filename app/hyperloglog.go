@@ -0,0 +1,121 @@
+package main
+
+import (
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "pfadd", Arity: -2, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "hyperloglog", Since: "2.8.9", Complexity: "O(1) to add every element.",
+			Summary: "Adds elements to a HyperLogLog key. Creates the key if it doesn't exist.",
+			Handler: pfaddCommand,
+		},
+		&Command{
+			Name: "pfcount", Arity: -2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "hyperloglog", Since: "2.8.9", Complexity: "O(1) with a very small average constant time when called with a single key. O(N) with N being the number of keys, and much bigger constant times, when called with multiple keys.",
+			Summary: "Returns the approximated cardinality of the set(s) observed by the HyperLogLog key(s).",
+			Handler: pfcountCommand,
+		},
+		&Command{
+			Name: "pfmerge", Arity: -2, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "hyperloglog", Since: "2.8.9", Complexity: "O(N) to merge N HyperLogLogs, but with high constant times.",
+			Summary: "Merges one or more HyperLogLog values into a single key.",
+			Handler: pfmergeCommand,
+		},
+	)
+}
+
+// pfaddCommand implements PFADD key [element [element ...]]. It returns 1
+// if the key was created or the estimated cardinality may have changed.
+func pfaddCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key := args[1].String()
+	value, errReply := lookupString(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	var b []byte
+	if value == nil {
+		b = store.NewHLL()
+	} else {
+		b = value.Bytes()
+	}
+	b, changed, err := store.HLLAdd(b, bytesArgs(args[2:]))
+	if err != nil {
+		return Resp.NewError(err.Error())
+	}
+	if !changed && value != nil {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	replaceString(client, key, value, store.NewString(b))
+	return Resp.NewInteger(1)
+}
+
+// pfcountCommand implements PFCOUNT key [key ...]. With several keys it
+// estimates the cardinality of their union. With a single one the
+// estimate is cached in the HyperLogLog until it changes.
+func pfcountCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args) == 2 {
+		key := args[1].String()
+		value, errReply := lookupString(client, key)
+		if errReply.IsError() {
+			return errReply
+		}
+		if value == nil {
+			return Resp.NewInteger(0)
+		}
+		count, cached, err := store.HLLCount(value.Bytes())
+		if err != nil {
+			return Resp.NewError(err.Error())
+		}
+		if cached != nil {
+			replaceString(client, key, value, store.NewString(cached))
+		}
+		return Resp.NewInteger(int64(count))
+	}
+	srcs, errReply := hllSources(client, args[1:])
+	if errReply.IsError() {
+		return errReply
+	}
+	count, err := store.HLLCountUnion(srcs)
+	if err != nil {
+		return Resp.NewError(err.Error())
+	}
+	return Resp.NewInteger(int64(count))
+}
+
+// hllSources returns the strings stored at keys, skipping missing keys.
+func hllSources(client *Client, keys []Resp.RESP) ([][]byte, Resp.RESP) {
+	var srcs [][]byte
+	for _, key := range keys {
+		value, errReply := lookupString(client, key.String())
+		if errReply.IsError() {
+			return nil, errReply
+		}
+		if value != nil {
+			srcs = append(srcs, value.Bytes())
+		}
+	}
+	return srcs, Resp.RESP{}
+}
+
+// pfmergeCommand implements PFMERGE destkey [sourcekey [sourcekey ...]],
+// storing the union of destkey and the sources in destkey. The result is
+// dense if any of them is.
+func pfmergeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	srcs, errReply := hllSources(client, args[1:])
+	if errReply.IsError() {
+		return errReply
+	}
+	b, err := store.HLLMerge(srcs)
+	if err != nil {
+		return Resp.NewError(err.Error())
+	}
+	key := args[1].String()
+	value, _ := lookupString(client, key)
+	replaceString(client, key, value, store.NewString(b))
+	return okReply
+}
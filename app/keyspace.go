@@ -0,0 +1,257 @@
+package main
+
+import (
+	Resp "redis-go/pkg/resp"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "type", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "generic", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Determines the type of value stored at a key.",
+			Handler: typeCommand,
+		},
+		&Command{
+			Name: "del", Arity: -2, Flags: []string{"write"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "generic", Since: "1.0.0", Complexity: "O(N) where N is the number of keys that will be removed. When a key to remove holds a value other than a string, the individual complexity for this key is O(M) where M is the number of elements in the list, set, sorted set or hash. Removing a single key that holds a string value is O(1).",
+			Summary: "Deletes one or more keys.",
+			Handler: delCommand,
+		},
+		&Command{
+			Name: "unlink", Arity: -2, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "generic", Since: "4.0.0", Complexity: "O(1) for each key removed regardless of its size. Then the command does O(N) work in a different thread in order to reclaim memory, where N is the number of allocations the deleted objects where composed of.",
+			Summary: "Asynchronously deletes one or more keys.",
+			Handler: unlinkCommand,
+		},
+		&Command{
+			Name: "exists", Arity: -2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "generic", Since: "1.0.0", Complexity: "O(N) where N is the number of keys to check.",
+			Summary: "Determines whether one or more keys exist.",
+			Handler: existsCommand,
+		},
+		&Command{
+			Name: "touch", Arity: -2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "generic", Since: "3.2.1", Complexity: "O(N) where N is the number of keys that will be touched.",
+			Summary: "Returns the number of existing keys out of those specified after updating the time they were last accessed.",
+			Handler: existsCommand,
+		},
+		&Command{
+			Name: "keys", Arity: 2, Flags: []string{"readonly"},
+			Group: "generic", Since: "1.0.0", Complexity: "O(N) with N being the number of keys in the database, under the assumption that the key names in the database and the given pattern have limited length.",
+			Summary: "Returns all key names that match a pattern.",
+			Handler: keysCommand,
+		},
+		&Command{
+			Name: "rename", Arity: 3, Flags: []string{"write"}, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: "generic", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Renames a key and overwrites the destination.",
+			Handler: renameCommand,
+		},
+		&Command{
+			Name: "renamenx", Arity: 3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: "generic", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Renames a key only when the target key name doesn't exist.",
+			Handler: renamenxCommand,
+		},
+		&Command{
+			Name: "copy", Arity: -3, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: "generic", Since: "6.2.0", Complexity: "O(N) worst case for collections, where N is the number of nested items. O(1) for string values.",
+			Summary: "Copies the value of a key to a new key.",
+			Handler: copyCommand,
+		},
+		&Command{
+			Name: "randomkey", Arity: 1, Flags: []string{"readonly"},
+			Group: "generic", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Returns a random key name from the database.",
+			Handler: randomkeyCommand,
+		},
+		&Command{
+			Name: "dbsize", Arity: 1, Flags: []string{"readonly", "fast"},
+			Group: "server", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Returns the number of keys in the database.",
+			Handler: dbsizeCommand,
+		},
+		&Command{
+			Name: "flushdb", Arity: -1, Flags: []string{"write"},
+			Group: "server", Since: "1.0.0", Complexity: "O(N) where N is the total number of keys in all databases",
+			Summary: "Remove all keys from the current database.",
+			Handler: flushdbCommand,
+		},
+		&Command{
+			Name: "flushall", Arity: -1, Flags: []string{"write"},
+			Group: "server", Since: "1.0.0", Complexity: "O(N) where N is the total number of keys in all databases",
+			Summary: "Removes all keys from all databases.",
+			Handler: flushallCommand,
+		},
+	)
+}
+
+func typeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value := client.db.Lookup(args[1].String())
+	if value == nil {
+		return Resp.NewSimpleString("none")
+	}
+	return Resp.NewSimpleString(value.Type.String())
+}
+
+func delCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	deleted := 0
+	for _, arg := range args[1:] {
+		key := arg.String()
+		// expired keys don't count as deleted
+		if client.db.Lookup(key) != nil && client.db.Delete(key) {
+			deleted++
+		}
+	}
+	return Resp.NewInteger(int64(deleted))
+}
+
+// unlinkCommand implements UNLINK key [key ...], which deletes like DEL
+// but frees large values in the background.
+func unlinkCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	deleted := 0
+	for _, arg := range args[1:] {
+		key := arg.String()
+		if client.db.Lookup(key) != nil && client.db.Unlink(key) {
+			deleted++
+		}
+	}
+	return Resp.NewInteger(int64(deleted))
+}
+
+// existsCommand implements EXISTS key [key ...], where keys given more
+// than once are counted more than once, and TOUCH. Access times are not
+// tracked so touching a key is looking it up.
+func existsCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	count := 0
+	for _, arg := range args[1:] {
+		if client.db.Lookup(arg.String()) != nil {
+			count++
+		}
+	}
+	return Resp.NewInteger(int64(count))
+}
+
+func keysCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	keys := client.db.Keys(args[1].Bytes())
+	reply := make([]Resp.RESP, len(keys))
+	for i, key := range keys {
+		reply[i] = Resp.NewBulkString(key)
+	}
+	return Resp.NewArray(reply)
+}
+
+func renameCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return renameGeneric(client, args, false)
+}
+
+func renamenxCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return renameGeneric(client, args, true)
+}
+
+// renameGeneric implements RENAME and, with nx, RENAMENX.
+func renameGeneric(client *Client, args []Resp.RESP, nx bool) Resp.RESP {
+	src, dst := args[1].String(), args[2].String()
+	if client.db.Lookup(src) == nil {
+		return Resp.NewError("ERR no such key")
+	}
+	if nx {
+		if src == dst || client.db.Lookup(dst) != nil {
+			client.rewritePropagation(nil)
+			return Resp.NewInteger(0)
+		}
+		client.db.Rename(src, dst)
+		signalKeyAsReady(client.db, dst)
+		return Resp.NewInteger(1)
+	}
+	client.db.Rename(src, dst)
+	signalKeyAsReady(client.db, dst)
+	return okReply
+}
+
+// copyCommand implements COPY source destination [DB destination-db]
+// [REPLACE].
+func copyCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	replace, index := false, client.dbIndex
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i].String()); {
+		case opt == "REPLACE":
+			replace = true
+		case opt == "DB" && i+1 < len(args):
+			var errReply Resp.RESP
+			if index, errReply = parseDBIndex(args[i+1], notIntegerError); errReply.IsError() {
+				return errReply
+			}
+			i++
+		default:
+			return syntaxError
+		}
+	}
+	src, dst := args[1].String(), args[2].String()
+	if src == dst && index == client.dbIndex {
+		return Resp.NewError("ERR source and destination objects are the same")
+	}
+	dstDB := databases[index]
+	value := client.db.Lookup(src)
+	if value == nil || (!replace && dstDB.Lookup(dst) != nil) {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	dstDB.SetValue(dst, value.Copy())
+	signalKeyAsReady(dstDB, dst)
+	return Resp.NewInteger(1)
+}
+
+func randomkeyCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key, ok := client.db.RandomKey()
+	if !ok {
+		return Resp.NewNullBulkString()
+	}
+	return Resp.NewBulkString(key)
+}
+
+func dbsizeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return Resp.NewInteger(int64(client.db.Len()))
+}
+
+// parseFlushMode parses the optional ASYNC or SYNC argument of FLUSHDB
+// and FLUSHALL, and reports whether to free the keys in the background.
+func parseFlushMode(args []Resp.RESP) (bool, Resp.RESP) {
+	if len(args) > 2 {
+		return false, syntaxError
+	}
+	if len(args) == 1 {
+		return false, Resp.RESP{}
+	}
+	switch strings.ToUpper(args[1].String()) {
+	case "ASYNC":
+		return true, Resp.RESP{}
+	case "SYNC":
+		return false, Resp.RESP{}
+	}
+	return false, syntaxError
+}
+
+// flushdbCommand implements FLUSHDB [ASYNC | SYNC].
+func flushdbCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	async, errReply := parseFlushMode(args)
+	if errReply.IsError() {
+		return errReply
+	}
+	client.db.Flush(async)
+	return okReply
+}
+
+// flushallCommand implements FLUSHALL [ASYNC | SYNC].
+func flushallCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	async, errReply := parseFlushMode(args)
+	if errReply.IsError() {
+		return errReply
+	}
+	for _, db := range databases {
+		db.Flush(async)
+	}
+	return okReply
+}
@@ -0,0 +1,785 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "lpush", Arity: -3, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+			Summary: "Prepends one or more elements to a list. Creates the key if it doesn't exist.",
+			Handler: lpushCommand,
+		},
+		&Command{
+			Name: "rpush", Arity: -3, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+			Summary: "Appends one or more elements to a list. Creates the key if it doesn't exist.",
+			Handler: rpushCommand,
+		},
+		&Command{
+			Name: "lpop", Arity: -2, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(N) where N is the number of elements returned",
+			Summary: "Returns the first elements in a list after removing it. Deletes the list if the last element was popped.",
+			Handler: lpopCommand,
+		},
+		&Command{
+			Name: "rpop", Arity: -2, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(N) where N is the number of elements returned",
+			Summary: "Returns and removes the last elements of the list. Deletes the list if the last element was popped.",
+			Handler: rpopCommand,
+		},
+		&Command{
+			Name: "lrange", Arity: 4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(S+N) where S is the distance of start offset from HEAD for small lists, from nearest end (HEAD or TAIL) for large lists; and N is the number of elements in the specified range.",
+			Summary: "Returns a range of elements from a list.",
+			Handler: lrangeCommand,
+		},
+		&Command{
+			Name: "llen", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Returns the length of a list.",
+			Handler: llenCommand,
+		},
+		&Command{
+			Name: "lindex", Arity: 3, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(N) where N is the number of elements to traverse to get to the element at index. This makes asking for the first or the last element of the list O(1).",
+			Summary: "Returns an element from a list by its index.",
+			Handler: lindexCommand,
+		},
+		&Command{
+			Name: "lset", Arity: 4, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(N) where N is the length of the list. Setting either the first or the last element of the list is O(1).",
+			Summary: "Sets the value of an element in a list by its index.",
+			Handler: lsetCommand,
+		},
+		&Command{
+			Name: "lrem", Arity: 4, Flags: []string{"write"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(N+M) where N is the length of the list and M is the number of elements removed.",
+			Summary: "Removes elements from a list. Deletes the list if the last element was removed.",
+			Handler: lremCommand,
+		},
+		&Command{
+			Name: "ltrim", Arity: 4, Flags: []string{"write"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "1.0.0", Complexity: "O(N) where N is the number of elements to be removed by the operation.",
+			Summary: "Removes elements from both ends a list. Deletes the list if all elements were trimmed.",
+			Handler: ltrimCommand,
+		},
+		&Command{
+			Name: "linsert", Arity: 5, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "2.2.0", Complexity: "O(N) where N is the number of elements to traverse before seeing the value pivot. This means that inserting somewhere on the left end on the list (head) can be considered O(1) and inserting somewhere on the right end (tail) is O(N).",
+			Summary: "Inserts an element before or after another element in a list.",
+			Handler: linsertCommand,
+		},
+		&Command{
+			Name: "lpos", Arity: -3, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "list", Since: "6.0.6", Complexity: "O(N) where N is the number of elements in the list, for the average case. When searching for elements near the head or the tail of the list, or when the MAXLEN option is provided, the command may run in constant time.",
+			Summary: "Returns the index of matching elements in a list.",
+			Handler: lposCommand,
+		},
+		&Command{
+			Name: "lmpop", Arity: -4, Flags: []string{"write", "movablekeys"},
+			KeySpecs: []KeySpec{{Flags: []string{"RW", "ACCESS", "DELETE"}, BeginIndex: 1, KeyNumIndex: 0, FirstKey: 1, KeyStep: 1}},
+			Group:    "list", Since: "7.0.0", Complexity: "O(N+M) where N is the number of provided keys and M is the number of elements returned.",
+			Summary: "Returns multiple elements from a list after removing them. Deletes the list if the last element was popped.",
+			Handler: lmpopCommand,
+		},
+		&Command{
+			Name: "blpop", Arity: -3, Flags: []string{"write", "blocking"}, FirstKey: 1, LastKey: -2, Step: 1,
+			Group: "list", Since: "2.0.0", Complexity: "O(N) where N is the number of provided keys.",
+			Summary: "Removes and returns the first element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+			Handler: blpopCommand,
+		},
+		&Command{
+			Name: "brpop", Arity: -3, Flags: []string{"write", "blocking"}, FirstKey: 1, LastKey: -2, Step: 1,
+			Group: "list", Since: "2.0.0", Complexity: "O(N) where N is the number of provided keys.",
+			Summary: "Removes and returns the last element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+			Handler: brpopCommand,
+		},
+		&Command{
+			Name: "blmpop", Arity: -5, Flags: []string{"write", "blocking", "movablekeys"},
+			KeySpecs: []KeySpec{{Flags: []string{"RW", "ACCESS", "DELETE"}, BeginIndex: 2, KeyNumIndex: 0, FirstKey: 1, KeyStep: 1}},
+			Group:    "list", Since: "7.0.0", Complexity: "O(N+M) where N is the number of provided keys and M is the number of elements returned.",
+			Summary: "Pops the first element from one of multiple lists. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+			Handler: blmpopCommand,
+		},
+		&Command{
+			Name: "blmove", Arity: 6, Flags: []string{"write", "denyoom", "blocking"}, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: "list", Since: "6.2.0", Complexity: "O(1)",
+			Summary: "Pops an element from a list, pushes it to another list and returns it. Blocks until an element is available otherwise. Deletes the list if the last element was moved.",
+			Handler: blmoveCommand,
+		},
+		&Command{
+			Name: "lmove", Arity: 5, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: "list", Since: "6.2.0", Complexity: "O(1)",
+			Summary: "Returns an element after popping it from one list and pushing it to another. Deletes the list if the last element was moved.",
+			Handler: lmoveCommand,
+		},
+	)
+}
+
+// lookupList returns the list stored at key, or nil if there is none. On
+// failure the returned reply is the error to send.
+func lookupList(client *Client, key string) (*store.Value, Resp.RESP) {
+	value, err := client.db.LookupType(key, store.ObjList)
+	if err != nil {
+		return nil, wrongTypeError
+	}
+	return value, Resp.RESP{}
+}
+
+// listIndex converts a possibly negative index to an offset from the head
+// and reports whether it is inside a list of the given length.
+func listIndex(index int64, length int) (int, bool) {
+	if index < 0 {
+		index += int64(length)
+	}
+	if index < 0 || index >= int64(length) {
+		return 0, false
+	}
+	return int(index), true
+}
+
+// listRange converts LRANGE style start and stop indexes to offsets from
+// the head. It reports false when the range is empty.
+func listRange(start, stop int64, length int) (int, int, bool) {
+	if start < 0 {
+		start += int64(length)
+	}
+	if stop < 0 {
+		stop += int64(length)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= int64(length) {
+		return 0, 0, false
+	}
+	if stop >= int64(length) {
+		stop = int64(length) - 1
+	}
+	return int(start), int(stop), true
+}
+
+// parseListSide parses the LEFT and RIGHT arguments of LMOVE and reports
+// whether the side is the head of the list.
+func parseListSide(arg Resp.RESP) (bool, Resp.RESP) {
+	switch strings.ToUpper(arg.String()) {
+	case "LEFT":
+		return true, Resp.RESP{}
+	case "RIGHT":
+		return false, Resp.RESP{}
+	}
+	return false, syntaxError
+}
+
+func lpushCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return pushGeneric(client, args, true)
+}
+
+func rpushCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return pushGeneric(client, args, false)
+}
+
+func pushGeneric(client *Client, args []Resp.RESP, head bool) Resp.RESP {
+	key := args[1].String()
+	value, errReply := lookupList(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		value = store.NewList()
+		client.db.SetValue(key, value)
+	}
+	list := value.List()
+	for _, arg := range args[2:] {
+		if head {
+			list.PushHead(arg.Bytes())
+		} else {
+			list.PushTail(arg.Bytes())
+		}
+	}
+	client.db.Updated(key, value)
+	signalKeyAsReady(client.db, key)
+	return Resp.NewInteger(int64(list.Len()))
+}
+
+func lpopCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return popGeneric(client, args, true)
+}
+
+func rpopCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return popGeneric(client, args, false)
+}
+
+// popGeneric implements LPOP and RPOP key [count]. Without a count a
+// single element is returned, with a count an array of elements.
+func popGeneric(client *Client, args []Resp.RESP, head bool) Resp.RESP {
+	if len(args) > 3 {
+		return wrongArityError(strings.ToLower(args[0].String()))
+	}
+	count := int64(1)
+	withCount := len(args) == 3
+	if withCount {
+		var errReply Resp.RESP
+		if count, errReply = parseInteger(args[2]); errReply.IsError() || count < 0 {
+			return Resp.NewError("ERR value is out of range, must be positive")
+		}
+	}
+	key := args[1].String()
+	value, errReply := lookupList(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		client.rewritePropagation(nil)
+		if withCount {
+			return Resp.NewNullArray()
+		}
+		return Resp.NewNullBulkString()
+	}
+	if count == 0 {
+		client.rewritePropagation(nil)
+		return Resp.NewArray([]Resp.RESP{})
+	}
+	list := value.List()
+	if count > int64(list.Len()) {
+		count = int64(list.Len())
+	}
+	popped := make([]Resp.RESP, count)
+	for i := range popped {
+		var element []byte
+		if head {
+			element, _ = list.PopHead()
+		} else {
+			element, _ = list.PopTail()
+		}
+		popped[i] = Resp.NewBulk(element)
+	}
+	client.db.Updated(key, value)
+	if !withCount {
+		return popped[0]
+	}
+	return Resp.NewArray(popped)
+}
+
+func lrangeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	start, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	stop, errReply := parseInteger(args[3])
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupList(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewArray([]Resp.RESP{})
+	}
+	list := value.List()
+	from, to, ok := listRange(start, stop, list.Len())
+	if !ok {
+		return Resp.NewArray([]Resp.RESP{})
+	}
+	elements := make([]Resp.RESP, 0, to-from+1)
+	list.Range(from, to, func(index int, element []byte) bool {
+		elements = append(elements, Resp.NewBulk(element))
+		return true
+	})
+	return Resp.NewArray(elements)
+}
+
+func llenCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupList(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(int64(value.List().Len()))
+}
+
+func lindexCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	index, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupList(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewNullBulkString()
+	}
+	list := value.List()
+	offset, ok := listIndex(index, list.Len())
+	if !ok {
+		return Resp.NewNullBulkString()
+	}
+	return Resp.NewBulk(list.Index(offset))
+}
+
+func lsetCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	index, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	key := args[1].String()
+	value, errReply := lookupList(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewError("ERR no such key")
+	}
+	list := value.List()
+	offset, ok := listIndex(index, list.Len())
+	if !ok {
+		return Resp.NewError("ERR index out of range")
+	}
+	list.Set(offset, args[3].Bytes())
+	client.db.Updated(key, value)
+	return okReply
+}
+
+// lremCommand implements LREM key count element. A positive count removes
+// the first count matches from the head, a negative one from the tail and
+// zero removes every match.
+func lremCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	count, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	key := args[1].String()
+	value, errReply := lookupList(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	list := value.List()
+	element := args[3].Bytes()
+	from, to := 0, list.Len()-1
+	if count < 0 {
+		from, to = to, from
+		count = -count
+	}
+	var matches []int
+	list.Range(from, to, func(index int, candidate []byte) bool {
+		if bytes.Equal(candidate, element) {
+			matches = append(matches, index)
+		}
+		return count == 0 || int64(len(matches)) < count
+	})
+	if len(matches) == 0 {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	// delete from the highest index so the others stay valid
+	if matches[0] < matches[len(matches)-1] {
+		for i := len(matches) - 1; i >= 0; i-- {
+			list.Delete(matches[i])
+		}
+	} else {
+		for _, index := range matches {
+			list.Delete(index)
+		}
+	}
+	client.db.Updated(key, value)
+	return Resp.NewInteger(int64(len(matches)))
+}
+
+func ltrimCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	start, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	stop, errReply := parseInteger(args[3])
+	if errReply.IsError() {
+		return errReply
+	}
+	key := args[1].String()
+	value, errReply := lookupList(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		client.rewritePropagation(nil)
+		return okReply
+	}
+	list := value.List()
+	from, to, ok := listRange(start, stop, list.Len())
+	if !ok {
+		client.db.Delete(key)
+		return okReply
+	}
+	if from == 0 && to == list.Len()-1 {
+		client.rewritePropagation(nil)
+		return okReply
+	}
+	list.Trim(from, to)
+	client.db.Updated(key, value)
+	return okReply
+}
+
+// linsertCommand implements LINSERT key <BEFORE | AFTER> pivot element. It
+// replies -1 when the pivot is not in the list.
+func linsertCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	var after bool
+	switch strings.ToUpper(args[2].String()) {
+	case "BEFORE":
+	case "AFTER":
+		after = true
+	default:
+		return syntaxError
+	}
+	key := args[1].String()
+	value, errReply := lookupList(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	list := value.List()
+	pivot := args[3].Bytes()
+	position := -1
+	list.Range(0, list.Len()-1, func(index int, element []byte) bool {
+		if bytes.Equal(element, pivot) {
+			position = index
+			return false
+		}
+		return true
+	})
+	if position < 0 {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(-1)
+	}
+	if after {
+		position++
+	}
+	list.Insert(position, args[4].Bytes())
+	client.db.Updated(key, value)
+	return Resp.NewInteger(int64(list.Len()))
+}
+
+// lposCommand implements
+// LPOS key element [RANK rank] [COUNT num-matches] [MAXLEN len].
+// RANK skips matches, from the tail when negative, COUNT asks for several
+// matches (0 for all of them) and MAXLEN bounds the number of comparisons.
+func lposCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	rank, count, maxLen := int64(1), int64(-1), int64(0)
+	for i := 3; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			return syntaxError
+		}
+		option := strings.ToUpper(args[i].String())
+		if option != "RANK" && option != "COUNT" && option != "MAXLEN" {
+			return syntaxError
+		}
+		n, errReply := parseInteger(args[i+1])
+		if errReply.IsError() {
+			return errReply
+		}
+		switch option {
+		case "RANK":
+			if n == 0 {
+				return Resp.NewError("ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list")
+			}
+			if n == math.MinInt64 {
+				return Resp.NewError("ERR value is out of range, value must between -9223372036854775807 and 9223372036854775807")
+			}
+			rank = n
+		case "COUNT":
+			if n < 0 {
+				return Resp.NewError("ERR COUNT can't be negative")
+			}
+			count = n
+		case "MAXLEN":
+			if n < 0 {
+				return Resp.NewError("ERR MAXLEN can't be negative")
+			}
+			maxLen = n
+		}
+	}
+	value, errReply := lookupList(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	var matches []Resp.RESP
+	if value != nil && value.List().Len() > 0 {
+		list := value.List()
+		from, to := 0, list.Len()-1
+		if rank < 0 {
+			from, to = to, from
+			rank = -rank
+		}
+		wanted := count
+		if wanted < 0 {
+			wanted = 1
+		}
+		element := args[2].Bytes()
+		compared := int64(0)
+		list.Range(from, to, func(index int, candidate []byte) bool {
+			compared++
+			if bytes.Equal(candidate, element) {
+				if rank > 1 {
+					rank--
+				} else {
+					matches = append(matches, Resp.NewInteger(int64(index)))
+				}
+			}
+			return (wanted == 0 || int64(len(matches)) < wanted) && (maxLen == 0 || compared < maxLen)
+		})
+	}
+	if count >= 0 {
+		if matches == nil {
+			matches = []Resp.RESP{}
+		}
+		return Resp.NewArray(matches)
+	}
+	if len(matches) == 0 {
+		return Resp.NewNullBulkString()
+	}
+	return matches[0]
+}
+
+// lmoveCommand implements LMOVE source destination <LEFT | RIGHT>
+// <LEFT | RIGHT>. Source and destination may be the same list, which
+// rotates it.
+func lmoveCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	fromHead, errReply := parseListSide(args[3])
+	if errReply.IsError() {
+		return errReply
+	}
+	toHead, errReply := parseListSide(args[4])
+	if errReply.IsError() {
+		return errReply
+	}
+	srcKey, dstKey := args[1].String(), args[2].String()
+	src, errReply := lookupList(client, srcKey)
+	if errReply.IsError() {
+		return errReply
+	}
+	if src == nil {
+		client.rewritePropagation(nil)
+		return Resp.NewNullBulkString()
+	}
+	dst, errReply := lookupList(client, dstKey)
+	if errReply.IsError() {
+		return errReply
+	}
+	element := listMove(client, srcKey, src, fromHead, dstKey, dst, toHead)
+	return Resp.NewBulk(element)
+}
+
+// blmoveCommand implements BLMOVE source destination <LEFT | RIGHT>
+// <LEFT | RIGHT> timeout. Once served it is propagated as an LMOVE.
+func blmoveCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	fromHead, errReply := parseListSide(args[3])
+	if errReply.IsError() {
+		return errReply
+	}
+	toHead, errReply := parseListSide(args[4])
+	if errReply.IsError() {
+		return errReply
+	}
+	timeout, errReply := parseTimeout(args[5])
+	if errReply.IsError() {
+		return errReply
+	}
+	srcKey, dstKey := args[1].String(), args[2].String()
+	src, errReply := lookupList(client, srcKey)
+	if errReply.IsError() {
+		return errReply
+	}
+	if src == nil {
+		return blockForKeys(client, []string{srcKey}, timeout, Resp.NewNullBulkString())
+	}
+	dst, errReply := lookupList(client, dstKey)
+	if errReply.IsError() {
+		return errReply
+	}
+	element := listMove(client, srcKey, src, fromHead, dstKey, dst, toHead)
+	client.rewritePropagation([]Resp.RESP{Resp.NewBulkString("LMOVE"), args[1], args[2], args[3], args[4]})
+	return Resp.NewBulk(element)
+}
+
+func blpopCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return blockingPopGeneric(client, args, true)
+}
+
+func brpopCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return blockingPopGeneric(client, args, false)
+}
+
+// blockingPopGeneric implements BLPOP and BRPOP key [key ...] timeout. It
+// pops from the first non empty list and replies with its key and the
+// element, and is propagated as an LPOP or RPOP.
+func blockingPopGeneric(client *Client, args []Resp.RESP, head bool) Resp.RESP {
+	timeout, errReply := parseTimeout(args[len(args)-1])
+	if errReply.IsError() {
+		return errReply
+	}
+	keys := args[1 : len(args)-1]
+	reply, popped, errReply := listPopFirst(client, keys, head, 1)
+	if errReply.IsError() {
+		return errReply
+	}
+	if popped {
+		// a single element is popped, propagate it without a count
+		client.rewritePropagation(listPopPropagation(reply[0], head, 0))
+		return Resp.NewArray([]Resp.RESP{reply[0], reply[1].Elems[0]})
+	}
+	return blockForKeys(client, respStrings(keys), timeout, Resp.NewNullArray())
+}
+
+func lmpopCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	keys, head, count, errReply := parseMpopArgs(args, 1)
+	if errReply.IsError() {
+		return errReply
+	}
+	reply, popped, errReply := listPopFirst(client, keys, head, count)
+	if errReply.IsError() {
+		return errReply
+	}
+	if !popped {
+		client.rewritePropagation(nil)
+		return Resp.NewNullArray()
+	}
+	return Resp.NewArray(reply)
+}
+
+// blmpopCommand implements
+// BLMPOP timeout numkeys key [key ...] <LEFT | RIGHT> [COUNT count].
+func blmpopCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	timeout, errReply := parseTimeout(args[1])
+	if errReply.IsError() {
+		return errReply
+	}
+	keys, head, count, errReply := parseMpopArgs(args, 2)
+	if errReply.IsError() {
+		return errReply
+	}
+	reply, popped, errReply := listPopFirst(client, keys, head, count)
+	if errReply.IsError() {
+		return errReply
+	}
+	if !popped {
+		return blockForKeys(client, respStrings(keys), timeout, Resp.NewNullArray())
+	}
+	return Resp.NewArray(reply)
+}
+
+// parseMpopArgs parses numkeys key [key ...] <LEFT | RIGHT> [COUNT count]
+// starting with numkeys at args[numKeysIndex].
+func parseMpopArgs(args []Resp.RESP, numKeysIndex int) ([]Resp.RESP, bool, int64, Resp.RESP) {
+	numKeys, errReply := parseInteger(args[numKeysIndex])
+	if errReply.IsError() || numKeys <= 0 {
+		return nil, false, 0, Resp.NewError("ERR numkeys should be greater than 0")
+	}
+	if numKeys >= int64(len(args)-numKeysIndex-1) {
+		return nil, false, 0, syntaxError
+	}
+	whereIndex := numKeysIndex + 1 + int(numKeys)
+	head, errReply := parseListSide(args[whereIndex])
+	if errReply.IsError() {
+		return nil, false, 0, errReply
+	}
+	count := int64(1)
+	rest := args[whereIndex+1:]
+	if len(rest) > 0 {
+		if len(rest) != 2 || strings.ToUpper(rest[0].String()) != "COUNT" {
+			return nil, false, 0, syntaxError
+		}
+		if count, errReply = parseInteger(rest[1]); errReply.IsError() || count <= 0 {
+			return nil, false, 0, Resp.NewError("ERR count should be greater than 0")
+		}
+	}
+	return args[numKeysIndex+1 : whereIndex], head, count, Resp.RESP{}
+}
+
+// listPopFirst pops up to count elements from the first of keys holding a
+// non empty list. It returns the key and the array of elements, and
+// propagates the pop as an LPOP or RPOP with a count.
+func listPopFirst(client *Client, keys []Resp.RESP, head bool, count int64) ([]Resp.RESP, bool, Resp.RESP) {
+	for _, keyArg := range keys {
+		key := keyArg.String()
+		value, errReply := lookupList(client, key)
+		if errReply.IsError() {
+			return nil, false, errReply
+		}
+		if value == nil {
+			continue
+		}
+		list := value.List()
+		if count > int64(list.Len()) {
+			count = int64(list.Len())
+		}
+		elements := make([]Resp.RESP, count)
+		for i := range elements {
+			var element []byte
+			if head {
+				element, _ = list.PopHead()
+			} else {
+				element, _ = list.PopTail()
+			}
+			elements[i] = Resp.NewBulk(element)
+		}
+		client.db.Updated(key, value)
+		client.rewritePropagation(listPopPropagation(keyArg, head, count))
+		return []Resp.RESP{keyArg, Resp.NewArray(elements)}, true, Resp.RESP{}
+	}
+	return nil, false, Resp.RESP{}
+}
+
+// listPopPropagation returns the LPOP or RPOP of count elements from key
+// that a pop is propagated as, without a count when count is 0.
+func listPopPropagation(key Resp.RESP, head bool, count int64) []Resp.RESP {
+	name := "RPOP"
+	if head {
+		name = "LPOP"
+	}
+	argv := []Resp.RESP{Resp.NewBulkString(name), key}
+	if count > 0 {
+		argv = append(argv, Resp.NewBulkString(strconv.FormatInt(count, 10)))
+	}
+	return argv
+}
+
+// listMove pops an element from src and pushes it to dst, which is created
+// when nil, and returns the element.
+func listMove(client *Client, srcKey string, src *store.Value, fromHead bool, dstKey string, dst *store.Value, toHead bool) []byte {
+	var element []byte
+	if fromHead {
+		element, _ = src.List().PopHead()
+	} else {
+		element, _ = src.List().PopTail()
+	}
+	if dst == nil {
+		dst = store.NewList()
+		client.db.SetValue(dstKey, dst)
+	}
+	if toHead {
+		dst.List().PushHead(element)
+	} else {
+		dst.List().PushTail(element)
+	}
+	client.db.Updated(srcKey, src)
+	client.db.Updated(dstKey, dst)
+	signalKeyAsReady(client.db, dstKey)
+	return element
+}
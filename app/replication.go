@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+type Replica struct {
+	conn net.Conn
+	// queue feeds the goroutine writing to the replica, so commands reach
+	// it in the order they were executed
+	queue chan []byte
+}
+
+// replicaQueueLimit is the number of commands waiting to be sent to a
+// replica past which it is disconnected, like the replica
+// client-output-buffer-limit of Redis.
+const replicaQueueLimit = 1024
+
+func newReplica(conn net.Conn) Replica {
+	replica := Replica{conn: conn, queue: make(chan []byte, replicaQueueLimit)}
+	go replica.writeLoop()
+	return replica
+}
+
+func (r Replica) writeLoop() {
+	for payload := range r.queue {
+		fmt.Printf("send command to replica: %s\n", r.conn.RemoteAddr().String())
+		if _, err := r.conn.Write(payload); err != nil {
+			fmt.Println("send command failed, err:", err.Error())
+			return
+		}
+	}
+}
+
+// close disconnects the replica, dropping the commands not sent yet.
+func (r Replica) close() {
+	close(r.queue)
+	r.conn.Close()
+}
+
+var replicas []Replica
+
+func connectToMaster(address string) net.Conn {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		panic(err.Error())
+	}
+	return conn
+}
+
+func sendCommand(conn net.Conn, reader *Resp.Reader, args ...string) Resp.RESP {
+	req := make([]Resp.RESP, len(args))
+	for i, arg := range args {
+		req[i] = Resp.NewBulkString(arg)
+	}
+	command := Resp.NewArray(req)
+	_, err := conn.Write([]byte(command.Serialize()))
+	if err != nil {
+		fmt.Println("send command failed, err:", err.Error())
+		return Resp.RESP{}
+	}
+	res, err := reader.ReadValue()
+	if err != nil {
+		fmt.Println("read connection failed, err:", err.Error())
+		return Resp.RESP{}
+	}
+	fmt.Println("response: ", res.String())
+	return res
+}
+
+// replicationSelectedDB is the database the commands sent to replicas
+// apply to, -1 when the next command has to select it.
+var replicationSelectedDB = -1
+
+// propagate sends the command req that ran on the database at index db to
+// the replicas, preceded by a SELECT when they are on another database.
+func propagate(db int, req Resp.RESP) {
+	payload := []byte(req.Serialize())
+	if db != replicationSelectedDB {
+		selectDB := Resp.NewArray([]Resp.RESP{Resp.NewBulkString("SELECT"), Resp.NewBulkString(strconv.Itoa(db))})
+		payload = append([]byte(selectDB.Serialize()), payload...)
+		replicationSelectedDB = db
+	}
+	// the send can't block as the caller holds the database lock, a
+	// replica too slow to keep up is dropped instead
+	kept := replicas[:0]
+	for _, replica := range replicas {
+		select {
+		case replica.queue <- payload:
+			kept = append(kept, replica)
+		default:
+			fmt.Println("replica queue overflow, disconnecting:", replica.conn.RemoteAddr().String())
+			replica.close()
+		}
+	}
+	replicas = kept
+}
+
+// expiredKeyPropagator returns the function propagating a DEL for the
+// expired keys deleted from the database at index db.
+func expiredKeyPropagator(db int) func(key string) {
+	return func(key string) {
+		propagate(db, Resp.NewArray([]Resp.RESP{Resp.NewBulkString("DEL"), Resp.NewBulkString(key)}))
+	}
+}
+
+// setExpirePolicy sets what lookups do with expired keys in every
+// database.
+func setExpirePolicy(policy store.ExpirePolicy) {
+	for _, db := range databases {
+		db.SetExpirePolicy(policy)
+	}
+}
+
+func getRdbFile() []byte {
+	contentsInBase64 := "UkVESVMwMDEx+glyZWRpcy12ZXIFNy4yLjD6CnJlZGlzLWJpdHPAQPoFY3RpbWXCbQi8ZfoIdXNlZC1tZW3CsMQQAPoIYW9mLWJhc2XAAP/wbjv+wP9aog=="
+	contents, err := base64.StdEncoding.DecodeString(contentsInBase64)
+	if err != nil {
+		panic(err.Error())
+	}
+	return contents
+}
+
+func handshakeToMaster() (net.Conn, *Resp.Reader) {
+	masterAddress := config.replica.masterHost + ":" + config.replica.masterPort
+	fmt.Println("handshake with master: " + masterAddress)
+	conn := connectToMaster(masterAddress)
+	reader := Resp.NewReader(conn)
+	sendCommand(conn, reader, "PING")
+	sendCommand(conn, reader, "REPLCONF", "listening-port", config.port)
+	sendCommand(conn, reader, "REPLCONF", "capa", "psync2")
+	sendCommand(conn, reader, "PSYNC", "?", "-1")
+	rdb, err := reader.ReadRDB()
+	if err != nil {
+		panic(err.Error())
+	}
+	fmt.Printf("received RDB file from master, size: %d\n", len(rdb))
+	return conn, reader
+}
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "replconf", Arity: -1, Flags: []string{"admin", "noscript", "loading", "stale"},
+			Group: "server", Since: "3.0.0", Complexity: "O(1)",
+			Summary: "An internal command for configuring the replication stream.",
+			Handler: replconfCommand,
+		},
+		&Command{
+			Name: "psync", Arity: -3, Flags: []string{"admin", "noscript"},
+			Group: "server", Since: "2.8.0",
+			Summary: "An internal command used in replication.",
+			Handler: psyncCommand,
+		},
+	)
+}
+
+func replconfCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args) < 2 {
+		return wrongArityError("replconf")
+	}
+	option := strings.ToUpper(args[1].String())
+	if option == "LISTENING-PORT" {
+		replicas = append(replicas, newReplica(client.conn))
+		// the new replica starts on database 0 whatever the others use
+		replicationSelectedDB = -1
+	}
+	return okReply
+}
+
+func psyncCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	client.enc.Encode(Resp.NewSimpleString(fmt.Sprintf("FULLRESYNC %s %d", config.replica.replicationId, config.replica.offset)))
+	rdbFile := getRdbFile()
+	return Resp.NewRDB(rdbFile)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "scan", Arity: -2, Flags: []string{"readonly"},
+			Group: "generic", Since: "2.8.0", Complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection.",
+			Summary: "Iterates over the key names in the database.",
+			Handler: scanCommand,
+		},
+	)
+}
+
+// scanOptions are the options shared by the SCAN family.
+type scanOptions struct {
+	// pattern filters the returned elements, nil matches everything
+	pattern []byte
+	count   int64
+	// typ filters the keys returned by SCAN by type name, empty matches
+	// every type
+	typ string
+}
+
+func parseScanCursor(arg Resp.RESP) (uint64, Resp.RESP) {
+	cursor, err := strconv.ParseUint(arg.String(), 10, 64)
+	if err != nil {
+		return 0, Resp.NewError("ERR invalid cursor")
+	}
+	return cursor, Resp.RESP{}
+}
+
+// parseScanOptions parses [MATCH pattern] [COUNT count], and [TYPE type]
+// when scanning the keyspace.
+func parseScanOptions(args []Resp.RESP, keyspace bool) (scanOptions, Resp.RESP) {
+	opts := scanOptions{count: 10}
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			return opts, syntaxError
+		}
+		switch strings.ToUpper(args[i].String()) {
+		case "COUNT":
+			count, errReply := parseInteger(args[i+1])
+			if errReply.IsError() {
+				return opts, errReply
+			}
+			if count < 1 {
+				return opts, syntaxError
+			}
+			opts.count = count
+		case "MATCH":
+			opts.pattern = args[i+1].Bytes()
+			if string(opts.pattern) == "*" {
+				opts.pattern = nil
+			}
+		case "TYPE":
+			if !keyspace {
+				return opts, syntaxError
+			}
+			opts.typ = strings.ToLower(args[i+1].String())
+		default:
+			return opts, syntaxError
+		}
+	}
+	return opts, Resp.RESP{}
+}
+
+// scanGeneric calls scan from cursor until it collected about opts.count
+// elements, or visited ten times as many buckets without finding them,
+// and replies with the next cursor and the elements matching the pattern.
+// With values each element is followed by its value.
+func scanGeneric(cursor uint64, opts scanOptions, withValues bool, scan func(cursor uint64, fn func(element, value []byte)) uint64) Resp.RESP {
+	var items []Resp.RESP
+	found := int64(0)
+	collect := func(element, value []byte) {
+		found++
+		if opts.pattern != nil && !store.MatchPattern(opts.pattern, element, false) {
+			return
+		}
+		items = append(items, Resp.NewBulk(element))
+		if withValues {
+			items = append(items, Resp.NewBulk(value))
+		}
+	}
+	for iterations := opts.count * 10; ; iterations-- {
+		cursor = scan(cursor, collect)
+		if cursor == 0 || iterations == 0 || found >= opts.count {
+			break
+		}
+	}
+	if items == nil {
+		items = []Resp.RESP{}
+	}
+	return Resp.NewArray([]Resp.RESP{
+		Resp.NewBulkString(strconv.FormatUint(cursor, 10)),
+		Resp.NewArray(items),
+	})
+}
+
+// scanCommand implements SCAN cursor [MATCH pattern] [COUNT count]
+// [TYPE type]. Unknown types match no key.
+func scanCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	cursor, errReply := parseScanCursor(args[1])
+	if errReply.IsError() {
+		return errReply
+	}
+	opts, errReply := parseScanOptions(args[2:], true)
+	if errReply.IsError() {
+		return errReply
+	}
+	return scanGeneric(cursor, opts, false, func(cursor uint64, fn func(element, value []byte)) uint64 {
+		return client.db.Scan(cursor, func(key string, value *store.Value) {
+			if opts.typ == "" || value.Type.String() == opts.typ {
+				fn([]byte(key), nil)
+			}
+		})
+	})
+}
@@ -1,15 +1,12 @@
 package main
 
 import (
-	"bufio"
-	"encoding/base64"
 	"flag"
 	"fmt"
 	"io"
 	"math/rand"
 	"net"
 	"redis-go/internal/store"
-	"redis-go/pkg/resp"
 	Resp "redis-go/pkg/resp"
 	"strconv"
 	"strings"
@@ -27,16 +24,17 @@ type Config struct {
 	role    string
 	replica *ReplicaConfig
 	port    string
+	hz      int
+	// databases is the number of numbered databases clients can select
+	databases int
 }
 
 var config = Config{role: "master"}
-var replicaIdLen = 40
-
-type Replica struct {
-	conn net.Conn
-}
 
-var replicas []Replica
+// databases are the keyspaces clients select by index, 0 by default.
+var databases []*store.Store
+var serverVersion = "7.2.0"
+var replicaIdLen = 40
 
 func generateRandomString(l int) string {
 	charSet := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
@@ -52,53 +50,12 @@ func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
-func connectToMaster(address string) net.Conn {
-	conn, err := net.Dial("tcp", address)
-	if err != nil {
-		panic(err.Error())
-	}
-	return conn
-}
-
-func sendCommand(conn net.Conn, req *Resp.RESP) string {
-	_, err := conn.Write([]byte(req.Serialize()))
-	if err != nil {
-		fmt.Println("send command failed, err:", err.Error())
-	}
-	data := make([]byte, 1024)
-	n, err := conn.Read(data)
-	if err != nil {
-		fmt.Println("read connection failed, err:", err.Error())
-	}
-	res := string(data[:n])
-	fmt.Println("response: ", res)
-	return res
-}
-
-func getRdbFile() []byte {
-	contentsInBase64 := "UkVESVMwMDEx+glyZWRpcy12ZXIFNy4yLjD6CnJlZGlzLWJpdHPAQPoFY3RpbWXCbQi8ZfoIdXNlZC1tZW3CsMQQAPoIYW9mLWJhc2XAAP/wbjv+wP9aog=="
-	contents, err := base64.StdEncoding.DecodeString(contentsInBase64)
-	if err != nil {
-		panic(err.Error())
-	}
-	return contents
-}
-
-func handshakeToMaster() net.Conn {
-	masterAddress := config.replica.masterHost + ":" + config.replica.masterPort
-	fmt.Println("handshake with master: " + masterAddress)
-	conn := connectToMaster(masterAddress)
-	sendCommand(conn, &Resp.RESP{Type: Resp.Array, Data: []*Resp.RESP{{Type: Resp.SimpleString, Data: "PING"}}})
-	sendCommand(conn, &Resp.RESP{Type: Resp.Array, Data: []*Resp.RESP{{Type: Resp.BulkString, Data: "REPLCONF"}, {Type: Resp.BulkString, Data: "listening-port"}, {Type: Resp.BulkString, Data: config.port}}})
-	sendCommand(conn, &Resp.RESP{Type: Resp.Array, Data: []*Resp.RESP{{Type: Resp.BulkString, Data: "REPLCONF"}, {Type: Resp.BulkString, Data: "capa"}, {Type: Resp.BulkString, Data: "psync2"}}})
-	sendCommand(conn, &Resp.RESP{Type: Resp.Array, Data: []*Resp.RESP{{Type: Resp.BulkString, Data: "PSYNC"}, {Type: Resp.BulkString, Data: "?"}, {Type: Resp.BulkString, Data: "-1"}}})
-	return conn
-}
-
 func main() {
 	fmt.Println("Logs from your program will appear here!")
 
 	portPtr := flag.Int("port", 6379, "Port number")
+	flag.IntVar(&config.hz, "hz", store.DefaultHz, "Frequency of background tasks such as expiring keys")
+	flag.IntVar(&config.databases, "databases", 16, "Number of databases")
 	var replicaConfig ReplicaConfig
 	flag.Func("replicaof", "Replica of <master_host> <master_port>", func(flagValue string) error {
 		fmt.Println("flagValue: " + flagValue)
@@ -118,6 +75,9 @@ func main() {
 		replicas = []Replica{}
 	}
 	flag.Parse()
+	if config.databases < 1 {
+		panic("databases must be at least 1")
+	}
 	port := *portPtr
 	config.port = strconv.Itoa(port)
 	address := fmt.Sprintf("0.0.0.0:%d", port)
@@ -125,15 +85,22 @@ func main() {
 
 	fmt.Println("Replica of " + config.replica.masterHost + ":" + config.replica.masterPort + " role: " + config.role + " port: " + config.port)
 
-	store := store.NewStore()
+	databases = store.NewDatabases(config.databases)
+	for i, db := range databases {
+		db.SetHz(config.hz)
+		// replicas keep their expired keys until the DEL of their master,
+		// so they can't go away before the master's
+		if config.role == "master" {
+			db.SetExpireHook(expiredKeyPropagator(i))
+			db.StartActiveExpire()
+		} else {
+			db.SetExpirePolicy(store.ExpireHide)
+		}
+	}
 	if config.role == "slave" {
-		masterConn := handshakeToMaster()
+		masterConn, masterReader := handshakeToMaster()
 		// create a standalone go routine to listen command from master
-		go func() {
-			for {
-				handle(masterConn, store, false)
-			}
-		}()
+		go handle(masterConn, masterReader, databases[0], false)
 	}
 	l, err := net.Listen("tcp", address)
 
@@ -150,205 +117,155 @@ func main() {
 			continue
 		}
 		fmt.Println("handle a connection:")
-		go handle(conn, store, true)
+		go handle(conn, Resp.NewReader(conn), databases[0], true)
 	}
 }
 
-func handle(conn net.Conn, store *store.Store, isMaster bool) {
+func handle(conn net.Conn, reader *Resp.Reader, store *store.Store, isMaster bool) {
 	fmt.Println("accept a request, addr:", conn.RemoteAddr().String())
+	client := NewClient(conn, store)
+	client.master = !isMaster
 
 	for {
-		reader := bufio.NewReader(conn)
-		p := make([]byte, 4096)
-		n, err := reader.Read(p)
+		req, err := reader.ReadCommand()
 		if err == io.EOF {
 			fmt.Println("Read finish")
 			break
 		}
 		if err != nil {
-			fmt.Println("Read failed")
+			fmt.Println("Read failed, err:", err.Error())
+			if _, isNetErr := err.(net.Error); isMaster && !isNetErr && err != io.ErrUnexpectedEOF {
+				client.enc.Encode(Resp.NewError("ERR Protocol error: " + err.Error()))
+				client.enc.Flush()
+			}
 			break
 		}
-		str := string(p[:n])
-		fmt.Println("Read: ", strings.ReplaceAll(string(p[:n]), "\r\n", ","))
-		parser := Resp.NewRESPParser(str)
-		responses := []string{}
-		for parser.HasNext() {
-			// print the state of parser
-			fmt.Printf("parser currentIndex: %v\n", parser.CurrentIndex)
-			req, err := parser.ParseNext()
-			if err != nil {
-				fmt.Printf("Parse RESP failed, input: %s\nerr: %s\n", str, err.Error())
+		if len(req.Array()) == 0 {
+			// empty inline lines are ignored like in Redis
+			continue
+		}
+		res := handleCommand(req, client)
+		var peeked chan error
+		if client.blocked != nil {
+			// watch the connection while blocked so a client going away
+			// stops waiting
+			peeked = make(chan error, 1)
+			go func() { peeked <- reader.Peek() }()
+			if res, err = client.waitUnblocked(peeked); err != nil {
+				fmt.Println("Blocked client failed, err:", err.Error())
 				break
 			}
-			fmt.Printf("req.Type: %v, req.Data: %v\n", req.Type, req.Data)
-			res := handleCommand(req, conn, store)
-			if !isMaster {
+		}
+		if !isMaster {
+			if peeked != nil {
+				<-peeked
+			}
+			continue
+		}
+		client.enc.Protocol = client.protocol
+		if err := client.enc.Encode(res); err != nil {
+			fmt.Println("Write failed, err:", err.Error())
+			break
+		}
+		if res.Type == Resp.RDB {
+			// the connection is a replica from now on and only receives
+			// propagated commands
+			isMaster = false
+		}
+		if peeked != nil {
+			if err := client.enc.Flush(); err != nil {
+				fmt.Println("Write failed, err:", err.Error())
 				break
 			}
-			if res.Type == Resp.Array {
-				for _, element := range res.Data.([]*Resp.RESP) {
-					responses = append(responses, element.Serialize())
-					if element.Type == Resp.RDB {
-						isMaster = false
-					}
-				}
-			} else {
-				responses = append(responses, res.Serialize())
+			// the reader is free again once the peek returns
+			<-peeked
+			continue
+		}
+		// replies to pipelined commands are sent together
+		if reader.Buffered() == 0 {
+			if err := client.enc.Flush(); err != nil {
+				fmt.Println("Write failed, err:", err.Error())
+				break
 			}
 		}
-		response := strings.Join(responses, "\r\n")
-		conn.Write([]byte(response))
 	}
 	if isMaster {
 		conn.Close()
 	}
 }
 
-func handleCommand(req *Resp.RESP, conn net.Conn, store *store.Store) *Resp.RESP {
-	data := req.Data.([]*resp.RESP)
-	if data[0].Type != resp.BulkString {
-		return &Resp.RESP{
-			Type: Resp.SimpleString,
-			Data: "ERR wrong command",
-		}
+func init() {
+	registerCommands(&Command{
+		Name: "info", Arity: -1, Flags: []string{"loading", "stale"},
+		Group: "server", Since: "1.0.0", Complexity: "O(1)",
+		Summary: "Returns information and statistics about the server.",
+		Handler: infoCommand,
+	})
+}
+
+// infoSection is a named section of the INFO reply.
+type infoSection struct {
+	name string
+	// title heads the section in the reply
+	title  string
+	fields func(client *Client) string
+}
+
+// serverStats sums the counters of every database.
+func serverStats() store.Stats {
+	var stats store.Stats
+	for _, db := range databases {
+		dbStats := db.Stats()
+		stats.ExpiredKeys += dbStats.ExpiredKeys
+		stats.LazyfreePendingObjects += dbStats.LazyfreePendingObjects
+		stats.LazyfreedObjects += dbStats.LazyfreedObjects
 	}
-	command := strings.ToUpper(data[0].Data.(string))
-	fmt.Printf("command: %s\n", command)
-	switch command {
-	case "PING":
-		if len(data) < 2 {
-			return &Resp.RESP{
-				Type: Resp.SimpleString,
-				Data: "PONG",
-			}
-		}
-		args := strings.ToUpper(data[1].Data.(string))
-		fmt.Printf("command: %s, args: %s\n", command, args)
-		return &Resp.RESP{
-			Type: Resp.BulkString,
-			Data: args,
-		}
-	case "ECHO":
-		if len(data) < 2 {
-			return &Resp.RESP{
-				Type: Resp.SimpleString,
-				Data: "ERR wrong number of arguments for command",
-			}
-		}
-		args := strings.ToUpper(data[1].Data.(string))
-		fmt.Printf("command: %s, args: %s\n", command, args)
-		return &Resp.RESP{
-			Type: Resp.BulkString,
-			Data: args,
-		}
-	case "SET":
-		if len(data) < 3 {
-			return &Resp.RESP{
-				Type: Resp.SimpleString,
-				Data: "ERR wrong number of arguments for command",
-			}
-		}
-		key := data[1].Data.(string)
-		value := data[2].Data.(string)
-		with_opts := len(data) > 3
-		if with_opts {
-			opt := strings.ToUpper(data[3].Data.(string))
-			param, err := strconv.ParseInt(data[4].Data.(string), 0, 64)
-			if err != nil {
-				fmt.Println("Error parsing expire time: ", err.Error())
-				return &Resp.RESP{
-					Type: Resp.SimpleString,
-					Data: "ERR wrong expire time",
-				}
-			}
-			var res *Resp.RESP
-			if opt == "PX" {
-				store.SetPx(key, value, param)
-				res = &Resp.RESP{
-					Type: Resp.SimpleString,
-					Data: "OK",
-				}
-			}
-			for _, replica := range replicas {
-				go func(replica Replica) {
-					fmt.Printf("send command to replica: %s\n", replica.conn.RemoteAddr().String())
-					sendCommand(replica.conn, req)
-				}(replica)
-			}
-			return res
-		}
-		store.Set(key, value)
-		for _, replica := range replicas {
-			go func(replica Replica) {
-				fmt.Printf("send command to replica: %s\n", replica.conn.RemoteAddr().String())
-				sendCommand(replica.conn, req)
-			}(replica)
-		}
-		return &Resp.RESP{
-			Type: Resp.SimpleString,
-			Data: "OK",
-		}
-	case "GET":
-		if len(data) < 2 {
-			return &Resp.RESP{
-				Type: Resp.SimpleString,
-				Data: "ERR wrong number of arguments for command",
-			}
-		}
-		key := data[1].Data.(string)
-		if value, exist := store.Get(key); exist {
-			return &Resp.RESP{
-				Type: Resp.SimpleString,
-				Data: value,
-			}
-		}
-		return &Resp.RESP{
-			Type: Resp.NullBulkString,
-			Data: nil,
-		}
-	case "INFO":
-		return &Resp.RESP{
-			Type: Resp.BulkString,
-			Data: fmt.Sprintf("role:%s\r\nmaster_replid:%s\r\nmaster_repl_offset:%d\r\n", config.role, config.replica.replicationId, config.replica.offset),
-		}
-	case "REPLCONF":
-		if len(data) < 2 {
-			return &Resp.RESP{
-				Type: Resp.SimpleString,
-				Data: "ERR wrong number of arguments for command",
+	return stats
+}
+
+var infoSections = []infoSection{
+	{"server", "Server", func(client *Client) string {
+		return fmt.Sprintf("redis_version:%s\r\ntcp_port:%s\r\nhz:%d\r\n", serverVersion, config.port, client.db.Hz())
+	}},
+	{"memory", "Memory", func(client *Client) string {
+		stats := serverStats()
+		return fmt.Sprintf("lazyfree_pending_objects:%d\r\nlazyfreed_objects:%d\r\n", stats.LazyfreePendingObjects, stats.LazyfreedObjects)
+	}},
+	{"stats", "Stats", func(client *Client) string {
+		return fmt.Sprintf("expired_keys:%d\r\n", serverStats().ExpiredKeys)
+	}},
+	{"replication", "Replication", func(client *Client) string {
+		return fmt.Sprintf("role:%s\r\nmaster_replid:%s\r\nmaster_repl_offset:%d\r\n", config.role, config.replica.replicationId, config.replica.offset)
+	}},
+	{"keyspace", "Keyspace", func(client *Client) string {
+		var lines strings.Builder
+		for i, db := range databases {
+			if db.Len() > 0 {
+				fmt.Fprintf(&lines, "db%d:keys=%d,expires=%d,avg_ttl=%d\r\n", i, db.Len(), db.Expires(), db.AvgTTL())
 			}
 		}
-		args := strings.ToUpper(data[1].Data.(string))
-		if args == "LISTENING-PORT" {
-			replicas = append(replicas, Replica{
-				conn: conn,
-			})
-		}
-		return &Resp.RESP{
-			Type: Resp.SimpleString,
-			Data: "OK",
-		}
-	case "PSYNC":
-		res := Resp.RESP{
-			Type: Resp.Array,
-			Data: make([]*Resp.RESP, 0),
-		}
-		res.Data = append(res.Data.([]*Resp.RESP), &Resp.RESP{
-			Type: Resp.SimpleString,
-			Data: fmt.Sprintf("FULLRESYNC %s %d", config.replica.replicationId, config.replica.offset),
-		})
-		rdbFile := getRdbFile()
+		return lines.String()
+	}},
+}
 
-		res.Data = append(res.Data.([]*Resp.RESP), &Resp.RESP{
-			Type: Resp.RDB,
-			Data: rdbFile,
-		})
-		return &res
-	default:
-		return &Resp.RESP{
-			Type: Resp.SimpleString,
-			Data: "ERR wrong command " + command,
+// infoCommand implements INFO [section ...]. Without sections, or with
+// default, all or everything, every section is returned.
+func infoCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	requested := map[string]bool{}
+	for _, arg := range args[1:] {
+		requested[strings.ToLower(arg.String())] = true
+	}
+	all := len(requested) == 0 || requested["default"] || requested["all"] || requested["everything"]
+	var info strings.Builder
+	for _, section := range infoSections {
+		if !all && !requested[section.name] {
+			continue
+		}
+		if info.Len() > 0 {
+			info.WriteString("\r\n")
 		}
+		fmt.Fprintf(&info, "# %s\r\n", section.title)
+		info.WriteString(section.fields(client))
 	}
+	return Resp.NewVerbatimString("txt", info.String())
 }
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "sadd", Arity: -3, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+			Summary: "Adds one or more members to a set. Creates the key if it doesn't exist.",
+			Handler: saddCommand,
+		},
+		&Command{
+			Name: "srem", Arity: -3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(N) where N is the number of members to be removed.",
+			Summary: "Removes one or more members from a set. Deletes the set if the last member was removed.",
+			Handler: sremCommand,
+		},
+		&Command{
+			Name: "smembers", Arity: 2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(N) where N is the set cardinality.",
+			Summary: "Returns all members of a set.",
+			Handler: smembersCommand,
+		},
+		&Command{
+			Name: "sismember", Arity: 3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Determines whether a member belongs to a set.",
+			Handler: sismemberCommand,
+		},
+		&Command{
+			Name: "smismember", Arity: -3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "set", Since: "6.2.0", Complexity: "O(N) where N is the number of elements being checked for membership",
+			Summary: "Determines whether multiple members belong to a set.",
+			Handler: smismemberCommand,
+		},
+		&Command{
+			Name: "scard", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Returns the number of members in a set.",
+			Handler: scardCommand,
+		},
+		&Command{
+			Name: "spop", Arity: -2, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "Without the count argument O(1), otherwise O(N) where N is the value of the passed count.",
+			Summary: "Returns one or more random members from a set after removing them. Deletes the set if the last member was popped.",
+			Handler: spopCommand,
+		},
+		&Command{
+			Name: "srandmember", Arity: -2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "Without the count argument O(1), otherwise O(N) where N is the absolute value of the passed count.",
+			Summary: "Get one or multiple random members from a set",
+			Handler: srandmemberCommand,
+		},
+		&Command{
+			Name: "smove", Arity: 4, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Moves a member from one set to another.",
+			Handler: smoveCommand,
+		},
+		&Command{
+			Name: "sinter", Arity: -2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets.",
+			Summary: "Returns the intersect of multiple sets.",
+			Handler: sinterCommand,
+		},
+		&Command{
+			Name: "sinterstore", Arity: -3, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets.",
+			Summary: "Stores the intersect of multiple sets in a key.",
+			Handler: sinterstoreCommand,
+		},
+		&Command{
+			Name: "sintercard", Arity: -3, Flags: []string{"readonly", "movablekeys"},
+			KeySpecs: []KeySpec{{Flags: []string{"RO", "ACCESS"}, BeginIndex: 1, KeyNumIndex: 0, FirstKey: 1, KeyStep: 1}},
+			Group:    "set", Since: "7.0.0", Complexity: "O(N*M) worst case where N is the cardinality of the smallest set and M is the number of sets.",
+			Summary: "Returns the number of members of the intersect of multiple sets.",
+			Handler: sintercardCommand,
+		},
+		&Command{
+			Name: "sunion", Arity: -2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(N) where N is the total number of elements in all given sets.",
+			Summary: "Returns the union of multiple sets.",
+			Handler: sunionCommand,
+		},
+		&Command{
+			Name: "sunionstore", Arity: -3, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(N) where N is the total number of elements in all given sets.",
+			Summary: "Stores the union of multiple sets in a key.",
+			Handler: sunionstoreCommand,
+		},
+		&Command{
+			Name: "sdiff", Arity: -2, Flags: []string{"readonly"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(N) where N is the total number of elements in all given sets.",
+			Summary: "Returns the difference of multiple sets.",
+			Handler: sdiffCommand,
+		},
+		&Command{
+			Name: "sdiffstore", Arity: -3, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "set", Since: "1.0.0", Complexity: "O(N) where N is the total number of elements in all given sets.",
+			Summary: "Stores the difference of multiple sets in a key.",
+			Handler: sdiffstoreCommand,
+		},
+		&Command{
+			Name: "sscan", Arity: -3, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "set", Since: "2.8.0", Complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection.",
+			Summary: "Iterates over members of a set.",
+			Handler: sscanCommand,
+		},
+	)
+}
+
+// lookupSet returns the set stored at key, or nil if there is none. On
+// failure the returned reply is the error to send.
+func lookupSet(client *Client, key string) (*store.Value, Resp.RESP) {
+	value, err := client.db.LookupType(key, store.ObjSet)
+	if err != nil {
+		return nil, wrongTypeError
+	}
+	return value, Resp.RESP{}
+}
+
+// setMembers returns the members of a set as bulk strings.
+func setMembers(value *store.Value) []Resp.RESP {
+	members := make([]Resp.RESP, 0, value.SetLen())
+	value.SetRange(func(member []byte) bool {
+		members = append(members, Resp.NewBulk(member))
+		return true
+	})
+	return members
+}
+
+func saddCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key := args[1].String()
+	value, errReply := lookupSet(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		value = store.NewSet()
+		client.db.SetValue(key, value)
+	}
+	added := 0
+	for _, member := range args[2:] {
+		if value.SetAdd(member.Bytes()) {
+			added++
+		}
+	}
+	return Resp.NewInteger(int64(added))
+}
+
+func sremCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key := args[1].String()
+	value, errReply := lookupSet(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	removed := 0
+	if value != nil {
+		for _, member := range args[2:] {
+			if value.SetRemove(member.Bytes()) {
+				removed++
+			}
+		}
+		client.db.Updated(key, value)
+	}
+	if removed == 0 {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewInteger(int64(removed))
+}
+
+func smembersCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewSet([]Resp.RESP{})
+	}
+	return Resp.NewSet(setMembers(value))
+}
+
+func sismemberCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value != nil && value.SetIsMember(args[2].Bytes()) {
+		return Resp.NewInteger(1)
+	}
+	return Resp.NewInteger(0)
+}
+
+func smismemberCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	replies := make([]Resp.RESP, len(args)-2)
+	for i, member := range args[2:] {
+		replies[i] = Resp.NewInteger(0)
+		if value != nil && value.SetIsMember(member.Bytes()) {
+			replies[i] = Resp.NewInteger(1)
+		}
+	}
+	return Resp.NewArray(replies)
+}
+
+func scardCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(int64(value.SetLen()))
+}
+
+// spopCommand implements SPOP key [count]. The popped members are
+// propagated as an SREM so replicas remove the same ones.
+func spopCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args) > 3 {
+		return syntaxError
+	}
+	count := int64(1)
+	withCount := len(args) == 3
+	if withCount {
+		var errReply Resp.RESP
+		if count, errReply = parseInteger(args[2]); errReply.IsError() || count < 0 {
+			return Resp.NewError("ERR value is out of range, must be positive")
+		}
+	}
+	key := args[1].String()
+	value, errReply := lookupSet(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil || count == 0 {
+		client.rewritePropagation(nil)
+		if withCount {
+			return Resp.NewSet([]Resp.RESP{})
+		}
+		return Resp.NewNullBulkString()
+	}
+	var members []Resp.RESP
+	if count >= int64(value.SetLen()) {
+		members = setMembers(value)
+		client.db.Delete(key)
+	} else {
+		members = make([]Resp.RESP, count)
+		for i := range members {
+			member := value.SetRandom()
+			value.SetRemove(member)
+			members[i] = Resp.NewBulk(member)
+		}
+		client.db.Updated(key, value)
+	}
+	client.rewritePropagation(append([]Resp.RESP{Resp.NewBulkString("SREM"), args[1]}, members...))
+	if !withCount {
+		return members[0]
+	}
+	return Resp.NewSet(members)
+}
+
+// srandmemberCommand implements SRANDMEMBER key [count]. A negative count
+// allows the same member to be returned several times.
+func srandmemberCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args) > 3 {
+		return syntaxError
+	}
+	value, errReply := lookupSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if len(args) == 2 {
+		if value == nil {
+			return Resp.NewNullBulkString()
+		}
+		return Resp.NewBulk(value.SetRandom())
+	}
+	count, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	if count == math.MinInt64 {
+		return errValueOutOfRange
+	}
+	if value == nil || count == 0 {
+		return Resp.NewArray([]Resp.RESP{})
+	}
+	size := int64(value.SetLen())
+	switch {
+	case count < 0:
+		members := make([]Resp.RESP, 0, randomReplyCapacity(-count))
+		for i := int64(0); i < -count; i++ {
+			members = append(members, Resp.NewBulk(value.SetRandom()))
+		}
+		return Resp.NewArray(members)
+	case count >= size:
+		return Resp.NewArray(setMembers(value))
+	}
+	// a partial Fisher-Yates shuffle picks count distinct members
+	members := setMembers(value)
+	for i := 0; i < int(count); i++ {
+		j := i + rand.Intn(len(members)-i)
+		members[i], members[j] = members[j], members[i]
+	}
+	return Resp.NewArray(members[:count])
+}
+
+func smoveCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	srcKey, dstKey := args[1].String(), args[2].String()
+	src, errReply := lookupSet(client, srcKey)
+	if errReply.IsError() {
+		return errReply
+	}
+	dst, errReply := lookupSet(client, dstKey)
+	if errReply.IsError() {
+		return errReply
+	}
+	member := args[3].Bytes()
+	if src == nil || (src == dst && !src.SetIsMember(member)) || (src != dst && !src.SetRemove(member)) {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	if src == dst {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(1)
+	}
+	client.db.Updated(srcKey, src)
+	if dst == nil {
+		dst = store.NewSet()
+		client.db.SetValue(dstKey, dst)
+	}
+	dst.SetAdd(member)
+	return Resp.NewInteger(1)
+}
+
+type setOperation int
+
+const (
+	setInter setOperation = iota
+	setUnion
+	setDiff
+)
+
+// setAlgebra computes the intersection, union or difference of the sets
+// at keys into a new set. Missing keys count as empty sets.
+func setAlgebra(client *Client, keys []Resp.RESP, op setOperation) (*store.Value, Resp.RESP) {
+	result := store.NewSet()
+	sets := make([]*store.Value, 0, len(keys))
+	missing := false
+	for _, key := range keys {
+		// every key is type checked, even after a missing one
+		value, errReply := lookupSet(client, key.String())
+		if errReply.IsError() {
+			return nil, errReply
+		}
+		missing = missing || value == nil
+		sets = append(sets, value)
+	}
+	switch op {
+	case setInter:
+		// the intersection with an empty set is empty
+		if missing {
+			break
+		}
+		setIntersect(sets, func(member []byte) bool {
+			result.SetAdd(member)
+			return true
+		})
+	case setUnion:
+		for _, set := range sets {
+			if set == nil {
+				continue
+			}
+			set.SetRange(func(member []byte) bool {
+				result.SetAdd(member)
+				return true
+			})
+		}
+	case setDiff:
+		if sets[0] == nil {
+			break
+		}
+		sets[0].SetRange(func(member []byte) bool {
+			for _, set := range sets[1:] {
+				if set != nil && set.SetIsMember(member) {
+					return true
+				}
+			}
+			result.SetAdd(member)
+			return true
+		})
+	}
+	return result, Resp.RESP{}
+}
+
+// setIntersect calls fn for the members of the intersection of sets until
+// it returns false. It walks the smallest set and checks the others from
+// the smallest up, so non members are ruled out quickly. Sets are sorted
+// in place.
+func setIntersect(sets []*store.Value, fn func(member []byte) bool) {
+	sort.Slice(sets, func(i, j int) bool { return sets[i].SetLen() < sets[j].SetLen() })
+	sets[0].SetRange(func(member []byte) bool {
+		for _, set := range sets[1:] {
+			if !set.SetIsMember(member) {
+				return true
+			}
+		}
+		return fn(member)
+	})
+}
+
+func setAlgebraReply(client *Client, args []Resp.RESP, op setOperation) Resp.RESP {
+	result, errReply := setAlgebra(client, args[1:], op)
+	if errReply.IsError() {
+		return errReply
+	}
+	return Resp.NewSet(setMembers(result))
+}
+
+// setAlgebraStore stores the result of a set operation at the destination
+// key, replacing what was there, and replies with its size.
+func setAlgebraStore(client *Client, args []Resp.RESP, op setOperation) Resp.RESP {
+	result, errReply := setAlgebra(client, args[2:], op)
+	if errReply.IsError() {
+		return errReply
+	}
+	dstKey := args[1].String()
+	if result.SetLen() == 0 {
+		client.db.Delete(dstKey)
+	} else {
+		client.db.SetValue(dstKey, result)
+	}
+	return Resp.NewInteger(int64(result.SetLen()))
+}
+
+func sinterCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return setAlgebraReply(client, args, setInter)
+}
+
+func sinterstoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return setAlgebraStore(client, args, setInter)
+}
+
+func sunionCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return setAlgebraReply(client, args, setUnion)
+}
+
+func sunionstoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return setAlgebraStore(client, args, setUnion)
+}
+
+func sdiffCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return setAlgebraReply(client, args, setDiff)
+}
+
+func sdiffstoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return setAlgebraStore(client, args, setDiff)
+}
+
+// sintercardCommand implements SINTERCARD numkeys key [key ...]
+// [LIMIT limit]. The count stops at limit, 0 meaning no limit.
+func sintercardCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	numKeys, errReply := parseInteger(args[1])
+	if errReply.IsError() || numKeys <= 0 {
+		return Resp.NewError("ERR numkeys should be greater than 0")
+	}
+	if numKeys > int64(len(args)-2) {
+		return Resp.NewError("ERR Number of keys can't be greater than number of args")
+	}
+	limit := int64(0)
+	rest := args[2+numKeys:]
+	if len(rest) > 0 {
+		if len(rest) != 2 || strings.ToUpper(rest[0].String()) != "LIMIT" {
+			return syntaxError
+		}
+		if limit, errReply = parseInteger(rest[1]); errReply.IsError() {
+			return errReply
+		}
+		if limit < 0 {
+			return Resp.NewError("ERR LIMIT can't be negative")
+		}
+	}
+	sets := make([]*store.Value, 0, numKeys)
+	missing := false
+	for _, key := range args[2 : 2+numKeys] {
+		value, errReply := lookupSet(client, key.String())
+		if errReply.IsError() {
+			return errReply
+		}
+		missing = missing || value == nil
+		sets = append(sets, value)
+	}
+	if missing {
+		return Resp.NewInteger(0)
+	}
+	cardinality := int64(0)
+	setIntersect(sets, func(member []byte) bool {
+		cardinality++
+		return limit == 0 || cardinality < limit
+	})
+	return Resp.NewInteger(cardinality)
+}
+
+// sscanCommand implements SSCAN key cursor [MATCH pattern] [COUNT count].
+func sscanCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	cursor, errReply := parseScanCursor(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	opts, errReply := parseScanOptions(args[3:], false)
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewArray([]Resp.RESP{Resp.NewBulkString("0"), Resp.NewArray([]Resp.RESP{})})
+	}
+	return scanGeneric(cursor, opts, false, func(cursor uint64, fn func(element, value []byte)) uint64 {
+		return value.SetScan(cursor, func(member []byte) {
+			fn(member, nil)
+		})
+	})
+}
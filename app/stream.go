@@ -0,0 +1,576 @@
+package main
+
+import (
+	"math"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "xadd", Arity: -5, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "5.0.0", Complexity: "O(1) when adding a new entry, O(N) when trimming where N being the number of entries evicted.",
+			Summary: "Appends a new message to a stream. Creates the key if it doesn't exist.",
+			Handler: xaddCommand,
+		},
+		&Command{
+			Name: "xrange", Arity: -4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "5.0.0", Complexity: "O(N) with N being the number of elements being returned. If N is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1).",
+			Summary: "Returns the messages from a stream within a range of IDs.",
+			Handler: xrangeCommand,
+		},
+		&Command{
+			Name: "xrevrange", Arity: -4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "5.0.0", Complexity: "O(N) with N being the number of elements returned. If N is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1).",
+			Summary: "Returns the messages from a stream within a range of IDs in reverse order.",
+			Handler: xrevrangeCommand,
+		},
+		&Command{
+			Name: "xlen", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "5.0.0", Complexity: "O(1)",
+			Summary: "Return the number of messages in a stream.",
+			Handler: xlenCommand,
+		},
+		&Command{
+			Name: "xtrim", Arity: -4, Flags: []string{"write"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "5.0.0", Complexity: "O(N), with N being the number of evicted entries. Constant times are very small however, since entries are organized in macro nodes containing multiple entries that can be released with a single deallocation.",
+			Summary: "Deletes messages from the beginning of a stream.",
+			Handler: xtrimCommand,
+		},
+		&Command{
+			Name: "xdel", Arity: -3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "5.0.0", Complexity: "O(1) for each single item to delete in the stream, regardless of the stream size.",
+			Summary: "Returns the number of messages after removing them from a stream.",
+			Handler: xdelCommand,
+		},
+		&Command{
+			Name: "xread", Arity: -4, Flags: []string{"readonly", "blocking", "movablekeys"},
+			KeySpecs: []KeySpec{{Flags: []string{"RO", "ACCESS"}, Keyword: "STREAMS", KeywordStart: 1, LastKey: -1, KeyStep: 1, Limit: 2}},
+			Group:    "stream", Since: "5.0.0", Complexity: "For each stream mentioned: O(N) with N being the number of elements being returned, it means that XREAD-ing with a fixed COUNT is O(1). Note that when the BLOCK option is used, XADD will pay O(M) time in order to serve the M clients blocked on the stream getting new data.",
+			Summary: "Returns messages from multiple streams with IDs greater than the ones requested. Blocks until a message is available otherwise.",
+			Handler: xreadCommand,
+		},
+	)
+}
+
+var errInvalidStreamID = Resp.NewError("ERR Invalid stream ID specified as stream command argument")
+
+// lookupStream returns the stream stored at key, or nil if there is none.
+// On failure the returned reply is the error to send.
+func lookupStream(client *Client, key string) (*store.Stream, Resp.RESP) {
+	value, err := client.db.LookupType(key, store.ObjStream)
+	if err != nil {
+		return nil, wrongTypeError
+	}
+	if value == nil {
+		return nil, Resp.RESP{}
+	}
+	return value.Stream(), Resp.RESP{}
+}
+
+func streamEntryReply(entry store.StreamEntry) Resp.RESP {
+	fields := make([]Resp.RESP, len(entry.Fields))
+	for i, field := range entry.Fields {
+		fields[i] = Resp.NewBulk(field)
+	}
+	return Resp.NewArray([]Resp.RESP{Resp.NewBulkString(entry.ID.String()), Resp.NewArray(fields)})
+}
+
+// streamTrim is the trimming requested with MAXLEN or MINID, the zero
+// value meaning none.
+type streamTrim struct {
+	strategy string
+	maxLen   int64
+	minID    store.StreamID
+	limit    int64
+}
+
+func (t streamTrim) apply(s *store.Stream) int {
+	switch t.strategy {
+	case "MAXLEN":
+		return s.TrimMaxLen(int(t.maxLen), int(t.limit))
+	case "MINID":
+		return s.TrimMinID(t.minID, int(t.limit))
+	}
+	return 0
+}
+
+// parseStreamOptions parses the options shared by XADD and XTRIM starting
+// at args[2]: [NOMKSTREAM] [<MAXLEN | MINID> [= | ~] threshold [LIMIT
+// count]], NOMKSTREAM only for XADD. It returns the index of the first
+// argument that is not an option, which for XTRIM must be the end.
+func parseStreamOptions(args []Resp.RESP, xadd bool) (streamTrim, bool, int, Resp.RESP) {
+	var trim streamTrim
+	noMkStream, approx, limit := false, false, false
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i].String()); {
+		case option == "NOMKSTREAM" && xadd:
+			noMkStream = true
+		case (option == "MAXLEN" || option == "MINID") && i+1 < len(args):
+			trim.strategy = option
+			if arg := args[i+1].String(); arg == "~" || arg == "=" {
+				approx = arg == "~"
+				i++
+			}
+			if i+1 == len(args) {
+				return trim, false, 0, syntaxError
+			}
+			i++
+			if option == "MINID" {
+				id, ok := store.ParseStreamID(args[i].Bytes(), 0)
+				if !ok {
+					return trim, false, 0, errInvalidStreamID
+				}
+				trim.minID = id
+				continue
+			}
+			maxLen, errReply := parseInteger(args[i])
+			if errReply.IsError() {
+				return trim, false, 0, errReply
+			}
+			if maxLen < 0 {
+				return trim, false, 0, Resp.NewError("ERR The MAXLEN argument must be >= 0.")
+			}
+			trim.maxLen = maxLen
+		case option == "LIMIT" && i+1 < len(args):
+			n, errReply := parseInteger(args[i+1])
+			if errReply.IsError() {
+				return trim, false, 0, errReply
+			}
+			if n < 0 {
+				return trim, false, 0, Resp.NewError("ERR The LIMIT argument must be >= 0.")
+			}
+			trim.limit = n
+			limit = true
+			i++
+		case xadd:
+			break options
+		default:
+			return trim, false, 0, syntaxError
+		}
+	}
+	if limit && !approx {
+		return trim, false, 0, Resp.NewError("ERR syntax error, LIMIT cannot be used without the special ~ option")
+	}
+	return trim, noMkStream, i, Resp.RESP{}
+}
+
+// xaddCommand implements XADD key [NOMKSTREAM] [<MAXLEN | MINID> [= | ~]
+// threshold [LIMIT count]] <* | id> field value [field value ...]. The ID
+// of the entry is propagated in place of * so replicas use the same one.
+func xaddCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	trim, noMkStream, i, errReply := parseStreamOptions(args, true)
+	if errReply.IsError() {
+		return errReply
+	}
+	if len(args)-i < 3 || (len(args)-i-1)%2 != 0 {
+		return wrongArityError("xadd")
+	}
+	idArg := args[i].Bytes()
+	autoMs, autoSeq := len(idArg) == 1 && idArg[0] == '*', false
+	var id store.StreamID
+	if !autoMs {
+		var ok bool
+		if autoSeq = len(idArg) > 2 && string(idArg[len(idArg)-2:]) == "-*"; autoSeq {
+			var err error
+			id.Ms, err = strconv.ParseUint(string(idArg[:len(idArg)-2]), 10, 64)
+			ok = err == nil
+		} else {
+			id, ok = store.ParseStreamID(idArg, 0)
+		}
+		if !ok {
+			return errInvalidStreamID
+		}
+		if id == (store.StreamID{}) && !autoSeq {
+			return Resp.NewError(store.ErrStreamIDZero.Error())
+		}
+	}
+	key := args[1].String()
+	stream, errReply := lookupStream(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	var created *store.Value
+	if stream == nil {
+		if noMkStream {
+			client.rewritePropagation(nil)
+			return Resp.NewNullBulkString()
+		}
+		created = store.NewStream()
+		stream = created.Stream()
+	}
+	var err error
+	switch {
+	case autoMs:
+		id, err = stream.AutoID(uint64(store.Now()))
+	case autoSeq:
+		id, err = stream.AutoSeqID(id.Ms)
+	}
+	if err == nil {
+		err = stream.Add(id, bytesArgs(args[i+1:]))
+	}
+	if err != nil {
+		return Resp.NewError(err.Error())
+	}
+	if created != nil {
+		client.db.SetValue(key, created)
+	}
+	trim.apply(stream)
+	signalKeyAsReady(client.db, key)
+	propagation := append([]Resp.RESP{}, args...)
+	propagation[i] = Resp.NewBulkString(id.String())
+	client.rewritePropagation(propagation)
+	return Resp.NewBulkString(id.String())
+}
+
+// bytesArgs returns the arguments as byte slices.
+func bytesArgs(args []Resp.RESP) [][]byte {
+	values := make([][]byte, len(args))
+	for i, arg := range args {
+		values[i] = arg.Bytes()
+	}
+	return values
+}
+
+// parseStreamRangeID parses an end of an XRANGE interval: - and + stand
+// for the smallest and largest IDs, a missing sequence defaults to
+// missingSeq and a ( prefix excludes the ID from the interval.
+func parseStreamRangeID(arg []byte, missingSeq uint64) (store.StreamID, bool, bool) {
+	switch string(arg) {
+	case "-":
+		return store.StreamID{}, false, true
+	case "+":
+		return store.MaxStreamID, false, true
+	}
+	exclusive := len(arg) > 0 && arg[0] == '('
+	if exclusive {
+		arg = arg[1:]
+	}
+	id, ok := store.ParseStreamID(arg, missingSeq)
+	return id, exclusive, ok
+}
+
+func xrangeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return xrangeGeneric(client, args[1].String(), args[2], args[3], args[4:], false)
+}
+
+func xrevrangeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return xrangeGeneric(client, args[1].String(), args[3], args[2], args[4:], true)
+}
+
+// xrangeGeneric implements XRANGE key start end [COUNT count] and XREVRANGE
+// key end start [COUNT count].
+func xrangeGeneric(client *Client, key string, startArg, endArg Resp.RESP, options []Resp.RESP, reverse bool) Resp.RESP {
+	start, startEx, ok := parseStreamRangeID(startArg.Bytes(), 0)
+	if !ok {
+		return errInvalidStreamID
+	}
+	end, endEx, ok := parseStreamRangeID(endArg.Bytes(), math.MaxUint64)
+	if !ok {
+		return errInvalidStreamID
+	}
+	if startEx {
+		if start, ok = start.Incr(); !ok {
+			return Resp.NewError("ERR invalid start ID for the interval")
+		}
+	}
+	if endEx {
+		if end, ok = end.Decr(); !ok {
+			return Resp.NewError("ERR invalid end ID for the interval")
+		}
+	}
+	count := int64(-1)
+	for i := 0; i < len(options); i++ {
+		if strings.ToUpper(options[i].String()) != "COUNT" || i+1 == len(options) {
+			return syntaxError
+		}
+		var errReply Resp.RESP
+		if count, errReply = parseInteger(options[i+1]); errReply.IsError() {
+			return errReply
+		}
+		if count < 0 {
+			count = 0
+		}
+		i++
+	}
+	stream, errReply := lookupStream(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if count == 0 {
+		return Resp.NewNullArray()
+	}
+	entries := []Resp.RESP{}
+	if stream != nil {
+		stream.Range(start, end, reverse, func(entry store.StreamEntry) bool {
+			entries = append(entries, streamEntryReply(entry))
+			return int64(len(entries)) != count
+		})
+	}
+	return Resp.NewArray(entries)
+}
+
+func xlenCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	stream, errReply := lookupStream(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if stream == nil {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(int64(stream.Len()))
+}
+
+// xtrimCommand implements
+// XTRIM key <MAXLEN | MINID> [= | ~] threshold [LIMIT count].
+func xtrimCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	trim, _, _, errReply := parseStreamOptions(args, false)
+	if errReply.IsError() {
+		return errReply
+	}
+	if trim.strategy == "" {
+		return syntaxError
+	}
+	stream, errReply := lookupStream(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	trimmed := 0
+	if stream != nil {
+		trimmed = trim.apply(stream)
+	}
+	if trimmed == 0 {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewInteger(int64(trimmed))
+}
+
+func xdelCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	ids := make([]store.StreamID, len(args)-2)
+	for i, arg := range args[2:] {
+		id, ok := store.ParseStreamID(arg.Bytes(), 0)
+		if !ok {
+			return errInvalidStreamID
+		}
+		ids[i] = id
+	}
+	stream, errReply := lookupStream(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	deleted := 0
+	if stream != nil {
+		for _, id := range ids {
+			if stream.Delete(id) {
+				deleted++
+			}
+		}
+	}
+	if deleted == 0 {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewInteger(int64(deleted))
+}
+
+func xreadCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return xreadGeneric(client, args, false)
+}
+
+func xreadgroupCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return xreadGeneric(client, args, true)
+}
+
+// xreadGeneric implements XREAD [COUNT count] [BLOCK milliseconds] STREAMS
+// key [key ...] id [id ...] and XREADGROUP GROUP group consumer [COUNT
+// count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...] id [id ...].
+//
+// XREAD returns the entries with IDs greater than the given ones, or with
+// BLOCK waits for some to be added. The $ ID stands for the last ID of the
+// stream when the command is called; it is replaced in the arguments when
+// blocking, so entries added while the client waits are the ones returned
+// when the command runs again.
+//
+// XREADGROUP delivers the entries never delivered to the group for the >
+// ID, blocking like XREAD, and otherwise the history of the entries
+// pending for the consumer with IDs greater than the given one.
+func xreadGeneric(client *Client, args []Resp.RESP, xreadgroup bool) Resp.RESP {
+	count, block := int64(0), int64(-1)
+	var groupName, consumerName string
+	noAck := false
+	streamsIndex := 0
+options:
+	for i := 1; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i].String()); {
+		case option == "COUNT" && i+1 < len(args):
+			var errReply Resp.RESP
+			if count, errReply = parseInteger(args[i+1]); errReply.IsError() {
+				return errReply
+			}
+			if count < 0 {
+				count = 0
+			}
+			i++
+		case option == "BLOCK" && i+1 < len(args):
+			var errReply Resp.RESP
+			if block, errReply = parseInteger(args[i+1]); errReply.IsError() {
+				return Resp.NewError("ERR timeout is not an integer or out of range")
+			}
+			if block < 0 {
+				return Resp.NewError("ERR timeout is negative")
+			}
+			i++
+		case option == "STREAMS":
+			streamsIndex = i + 1
+			break options
+		case option == "GROUP" && i+2 < len(args):
+			if !xreadgroup {
+				return Resp.NewError("ERR The GROUP option is only supported by XREADGROUP. You called XREAD instead.")
+			}
+			groupName, consumerName = args[i+1].String(), args[i+2].String()
+			i += 2
+		case option == "NOACK" && xreadgroup:
+			noAck = true
+		default:
+			return syntaxError
+		}
+	}
+	if streamsIndex == 0 {
+		return syntaxError
+	}
+	if xreadgroup && groupName == "" {
+		return Resp.NewError("ERR Missing GROUP option for XREADGROUP")
+	}
+	if (len(args)-streamsIndex)%2 != 0 {
+		name := "xread"
+		if xreadgroup {
+			name = "xreadgroup"
+		}
+		return Resp.NewError("ERR Unbalanced '" + name + "' list of streams: for each stream key an ID or '$' must be specified.")
+	}
+	numStreams := (len(args) - streamsIndex) / 2
+	keys := args[streamsIndex : streamsIndex+numStreams]
+	idArgs := args[streamsIndex+numStreams:]
+	streams := make([]*store.Stream, numStreams)
+	groups := make([]*store.StreamGroup, numStreams)
+	ids := make([]store.StreamID, numStreams)
+	// undelivered marks the > IDs of XREADGROUP
+	undelivered := make([]bool, numStreams)
+	for i, key := range keys {
+		stream, errReply := lookupStream(client, key.String())
+		if errReply.IsError() {
+			return errReply
+		}
+		streams[i] = stream
+		if xreadgroup {
+			if stream != nil {
+				groups[i] = stream.Group(groupName)
+			}
+			if groups[i] == nil {
+				return Resp.NewError("NOGROUP No such key '" + key.String() + "' or consumer group '" + groupName + "' in XREADGROUP with GROUP option")
+			}
+		}
+		switch idArgs[i].String() {
+		case "$":
+			if xreadgroup {
+				return Resp.NewError("ERR The $ ID is meaningless in the context of XREADGROUP: you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set.")
+			}
+			if stream != nil {
+				ids[i] = stream.LastID()
+			}
+			continue
+		case ">":
+			if !xreadgroup {
+				return Resp.NewError("ERR The > ID can be specified only when calling XREADGROUP using the GROUP <group> <consumer> option.")
+			}
+			undelivered[i] = true
+			continue
+		}
+		id, ok := store.ParseStreamID(idArgs[i].Bytes(), 0)
+		if !ok {
+			return errInvalidStreamID
+		}
+		ids[i] = id
+	}
+	now := store.Now()
+	var replies []Resp.RESP
+	history := false
+	for i, stream := range streams {
+		var entries []Resp.RESP
+		switch {
+		case xreadgroup:
+			consumer, _ := groups[i].CreateConsumer(consumerName, now)
+			consumer.SeenTime = now
+			if undelivered[i] {
+				for _, entry := range stream.ReadGroup(groups[i], consumer, int(count), noAck, now) {
+					entries = append(entries, streamEntryReply(entry))
+				}
+			} else {
+				history = true
+				entries = consumerHistory(stream, consumer, ids[i], count, now)
+			}
+			if len(entries) > 0 {
+				consumer.ActiveTime = now
+			}
+		case stream != nil:
+			start, ok := ids[i].Incr()
+			if !ok {
+				continue
+			}
+			stream.Range(start, store.MaxStreamID, false, func(entry store.StreamEntry) bool {
+				entries = append(entries, streamEntryReply(entry))
+				return int64(len(entries)) != count
+			})
+		}
+		// the history of a consumer is returned even when empty
+		if len(entries) > 0 || (xreadgroup && !undelivered[i]) {
+			replies = append(replies, keys[i], Resp.NewArray(entries))
+		}
+	}
+	switch {
+	case len(replies) > 0 && client.protocol >= 3:
+		return Resp.NewMap(replies)
+	case len(replies) > 0:
+		pairs := make([]Resp.RESP, 0, len(replies)/2)
+		for i := 0; i < len(replies); i += 2 {
+			pairs = append(pairs, Resp.NewArray(replies[i:i+2]))
+		}
+		return Resp.NewArray(pairs)
+	case block < 0 || history:
+		return Resp.NewNullArray()
+	}
+	if !xreadgroup {
+		for i, id := range ids {
+			idArgs[i] = Resp.NewBulkString(id.String())
+		}
+	}
+	return blockForKeys(client, respStrings(keys), time.Duration(block)*time.Millisecond, Resp.NewNullArray())
+}
+
+// consumerHistory returns up to count entries pending for the consumer
+// with IDs greater than id, as delivered again at time now. Entries
+// deleted from the stream since are returned with nil fields.
+func consumerHistory(stream *store.Stream, consumer *store.StreamConsumer, id store.StreamID, count int64, now int64) []Resp.RESP {
+	entries := []Resp.RESP{}
+	start, ok := id.Incr()
+	if !ok {
+		return entries
+	}
+	consumer.PendingRange(start, func(p *store.StreamPending) bool {
+		entry, ok := stream.Entry(p.ID)
+		if !ok {
+			entries = append(entries, Resp.NewArray([]Resp.RESP{Resp.NewBulkString(p.ID.String()), Resp.NewNullArray()}))
+		} else {
+			p.DeliveryTime = now
+			p.DeliveryCount++
+			entries = append(entries, streamEntryReply(entry))
+		}
+		return int64(len(entries)) != count
+	})
+	return entries
+}
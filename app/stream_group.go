@@ -0,0 +1,879 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "xgroup", Arity: -2,
+			Group: "stream", Since: "5.0.0", Complexity: "Depends on subcommand.",
+			Summary: "A container for consumer groups commands.",
+			Subcommands: []*Command{
+				{
+					Name: "create", Arity: -5, Flags: []string{"write", "denyoom"}, FirstKey: 2, LastKey: 2, Step: 1,
+					Since: "5.0.0", Complexity: "O(1)",
+					Summary: "Creates a consumer group.",
+					Handler: xgroupCreateCommand,
+				},
+				{
+					Name: "setid", Arity: -5, Flags: []string{"write"}, FirstKey: 2, LastKey: 2, Step: 1,
+					Since: "5.0.0", Complexity: "O(1)",
+					Summary: "Sets the last-delivered ID of a consumer group.",
+					Handler: xgroupSetidCommand,
+				},
+				{
+					Name: "destroy", Arity: 4, Flags: []string{"write"}, FirstKey: 2, LastKey: 2, Step: 1,
+					Since: "5.0.0", Complexity: "O(N) where N is the number of entries in the group's pending entries list (PEL).",
+					Summary: "Destroys a consumer group.",
+					Handler: xgroupDestroyCommand,
+				},
+				{
+					Name: "createconsumer", Arity: 5, Flags: []string{"write", "denyoom"}, FirstKey: 2, LastKey: 2, Step: 1,
+					Since: "6.2.0", Complexity: "O(1)",
+					Summary: "Creates a consumer in a consumer group.",
+					Handler: xgroupCreateconsumerCommand,
+				},
+				{
+					Name: "delconsumer", Arity: 5, Flags: []string{"write"}, FirstKey: 2, LastKey: 2, Step: 1,
+					Since: "5.0.0", Complexity: "O(1)",
+					Summary: "Deletes a consumer from a consumer group.",
+					Handler: xgroupDelconsumerCommand,
+				},
+				{
+					Name: "help", Arity: 2, Flags: []string{"loading", "stale"},
+					Since: "5.0.0", Complexity: "O(1)",
+					Summary: "Returns helpful text about the different subcommands.",
+					Handler: xgroupHelpCommand,
+				},
+			},
+		},
+		&Command{
+			Name: "xreadgroup", Arity: -7, Flags: []string{"write", "blocking", "movablekeys"},
+			KeySpecs: []KeySpec{{Flags: []string{"RW", "ACCESS", "UPDATE"}, Keyword: "STREAMS", KeywordStart: 4, LastKey: -1, KeyStep: 1, Limit: 2}},
+			Group:    "stream", Since: "5.0.0", Complexity: "For each stream mentioned: O(M) with M being the number of elements returned. If M is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1). On the other side when XREADGROUP blocks, XADD will pay the O(N) time in order to serve the N clients blocked on the stream getting new data.",
+			Summary: "Returns new or historical messages from a stream for a consumer in a group. Blocks until a message is available otherwise.",
+			Handler: xreadgroupCommand,
+		},
+		&Command{
+			Name: "xack", Arity: -4, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "5.0.0", Complexity: "O(1) for each message ID processed.",
+			Summary: "Returns the number of messages that were successfully acknowledged by the consumer group member of a stream.",
+			Handler: xackCommand,
+		},
+		&Command{
+			Name: "xpending", Arity: -3, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "5.0.0", Complexity: "O(N) with N being the number of elements returned, so asking for a small fixed number of entries per call is O(1). O(M), where M is the total number of entries scanned when used with the IDLE filter. When the command returns just the summary and the list of consumers is small, it runs in O(1) time; otherwise, an additional O(N) time for iterating every consumer.",
+			Summary: "Returns the information and entries from a stream consumer group's pending entries list.",
+			Handler: xpendingCommand,
+		},
+		&Command{
+			Name: "xclaim", Arity: -6, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "5.0.0", Complexity: "O(log N) with N being the number of messages in the PEL of the consumer group.",
+			Summary: "Changes, or acquires, ownership of a message in a consumer group, as if the message was delivered a consumer group member.",
+			Handler: xclaimCommand,
+		},
+		&Command{
+			Name: "xautoclaim", Arity: -6, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "stream", Since: "6.2.0", Complexity: "O(1) if COUNT is small.",
+			Summary: "Changes, or acquires, ownership of messages in a consumer group, as if the messages were delivered to as consumer group member.",
+			Handler: xautoclaimCommand,
+		},
+		&Command{
+			Name: "xinfo", Arity: -2,
+			Group: "stream", Since: "5.0.0", Complexity: "Depends on subcommand.",
+			Summary: "A container for stream introspection commands.",
+			Subcommands: []*Command{
+				{
+					Name: "stream", Arity: -3, Flags: []string{"readonly"}, FirstKey: 2, LastKey: 2, Step: 1,
+					Since: "5.0.0", Complexity: "O(1)",
+					Summary: "Returns information about a stream.",
+					Handler: xinfoStreamCommand,
+				},
+				{
+					Name: "groups", Arity: 3, Flags: []string{"readonly"}, FirstKey: 2, LastKey: 2, Step: 1,
+					Since: "5.0.0", Complexity: "O(1)",
+					Summary: "Returns a list of the consumer groups of a stream.",
+					Handler: xinfoGroupsCommand,
+				},
+				{
+					Name: "consumers", Arity: 4, Flags: []string{"readonly"}, FirstKey: 2, LastKey: 2, Step: 1,
+					Since: "5.0.0", Complexity: "O(1)",
+					Summary: "Returns a list of the consumers in a consumer group.",
+					Handler: xinfoConsumersCommand,
+				},
+				{
+					Name: "help", Arity: 2, Flags: []string{"loading", "stale"},
+					Since: "5.0.0", Complexity: "O(1)",
+					Summary: "Returns helpful text about the different subcommands.",
+					Handler: xinfoHelpCommand,
+				},
+			},
+		},
+	)
+}
+
+func noGroupError(key, group string) Resp.RESP {
+	return Resp.NewError(fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s'", key, group))
+}
+
+// lookupStreamGroup returns the stream stored at key and its group of the
+// given name. Either is nil when missing.
+func lookupStreamGroup(client *Client, key, name string) (*store.Stream, *store.StreamGroup, Resp.RESP) {
+	stream, errReply := lookupStream(client, key)
+	if errReply.IsError() || stream == nil {
+		return nil, nil, errReply
+	}
+	return stream, stream.Group(name), Resp.RESP{}
+}
+
+// parseGroupID parses the last delivered ID given to XGROUP CREATE and
+// SETID, where $ stands for the last ID of the stream.
+func parseGroupID(arg Resp.RESP, stream *store.Stream) (store.StreamID, Resp.RESP) {
+	if arg.String() == "$" {
+		if stream == nil {
+			return store.StreamID{}, Resp.RESP{}
+		}
+		return stream.LastID(), Resp.RESP{}
+	}
+	id, ok := store.ParseStreamID(arg.Bytes(), 0)
+	if !ok {
+		return id, errInvalidStreamID
+	}
+	return id, Resp.RESP{}
+}
+
+// parseEntriesRead parses the options of XGROUP CREATE and SETID starting
+// at args[5]: ENTRIESREAD, and MKSTREAM when mkStream is not nil.
+func parseEntriesRead(args []Resp.RESP, mkStream *bool) (int64, Resp.RESP) {
+	entriesRead := int64(-1)
+	for i := 5; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i].String()); {
+		case option == "MKSTREAM" && mkStream != nil:
+			*mkStream = true
+		case option == "ENTRIESREAD" && i+1 < len(args):
+			var errReply Resp.RESP
+			if entriesRead, errReply = parseInteger(args[i+1]); errReply.IsError() {
+				return 0, errReply
+			}
+			if entriesRead < -1 {
+				return 0, Resp.NewError("ERR value for ENTRIESREAD must be positive or -1")
+			}
+			i++
+		default:
+			return 0, syntaxError
+		}
+	}
+	return entriesRead, Resp.RESP{}
+}
+
+var errXGroupNoKey = Resp.NewError("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+
+// xgroupCreateCommand implements XGROUP CREATE key group <id | $>
+// [MKSTREAM] [ENTRIESREAD entries-read].
+func xgroupCreateCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	mkStream := false
+	entriesRead, errReply := parseEntriesRead(args, &mkStream)
+	if errReply.IsError() {
+		return errReply
+	}
+	key := args[2].String()
+	stream, errReply := lookupStream(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if stream == nil && !mkStream {
+		return errXGroupNoKey
+	}
+	lastID, errReply := parseGroupID(args[4], stream)
+	if errReply.IsError() {
+		return errReply
+	}
+	if stream == nil {
+		value := store.NewStream()
+		client.db.SetValue(key, value)
+		stream = value.Stream()
+	}
+	if stream.CreateGroup(args[3].String(), lastID, entriesRead) == nil {
+		return Resp.NewError("BUSYGROUP Consumer Group name already exists")
+	}
+	return okReply
+}
+
+// xgroupSetidCommand implements
+// XGROUP SETID key group <id | $> [ENTRIESREAD entries-read].
+func xgroupSetidCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	entriesRead, errReply := parseEntriesRead(args, nil)
+	if errReply.IsError() {
+		return errReply
+	}
+	key, name := args[2].String(), args[3].String()
+	stream, group, errReply := lookupStreamGroup(client, key, name)
+	if errReply.IsError() {
+		return errReply
+	}
+	if stream == nil {
+		return errXGroupNoKey
+	}
+	if group == nil {
+		return noSuchGroupError(key, name)
+	}
+	lastID, errReply := parseGroupID(args[4], stream)
+	if errReply.IsError() {
+		return errReply
+	}
+	group.LastID, group.EntriesRead = lastID, entriesRead
+	return okReply
+}
+
+func noSuchGroupError(key, group string) Resp.RESP {
+	return Resp.NewError(fmt.Sprintf("NOGROUP No such consumer group '%s' for key name '%s'", group, key))
+}
+
+// xgroupDestroyCommand implements XGROUP DESTROY key group. Clients
+// blocked reading from the group are woken up to get a NOGROUP error.
+func xgroupDestroyCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key := args[2].String()
+	stream, errReply := lookupStream(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if stream == nil {
+		return errXGroupNoKey
+	}
+	if !stream.DestroyGroup(args[3].String()) {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	signalKeyAsReady(client.db, key)
+	return Resp.NewInteger(1)
+}
+
+// xgroupConsumerGroup returns the group for the consumer subcommands of
+// XGROUP, or the error to reply.
+func xgroupConsumerGroup(client *Client, args []Resp.RESP) (*store.StreamGroup, Resp.RESP) {
+	key, name := args[2].String(), args[3].String()
+	stream, group, errReply := lookupStreamGroup(client, key, name)
+	switch {
+	case errReply.IsError():
+		return nil, errReply
+	case stream == nil:
+		return nil, errXGroupNoKey
+	case group == nil:
+		return nil, noSuchGroupError(key, name)
+	}
+	return group, Resp.RESP{}
+}
+
+func xgroupCreateconsumerCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	group, errReply := xgroupConsumerGroup(client, args)
+	if errReply.IsError() {
+		return errReply
+	}
+	if _, created := group.CreateConsumer(args[4].String(), store.Now()); !created {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(1)
+}
+
+// xgroupDelconsumerCommand implements XGROUP DELCONSUMER key group
+// consumer. It returns the number of entries that were pending for the
+// consumer.
+func xgroupDelconsumerCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	group, errReply := xgroupConsumerGroup(client, args)
+	if errReply.IsError() {
+		return errReply
+	}
+	pending, ok := group.DeleteConsumer(args[4].String())
+	if !ok {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewInteger(int64(pending))
+}
+
+func xgroupHelpCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return helpReply("XGROUP", []string{
+		"CREATE <key> <groupname> <id|$> [option]",
+		"    Create a new consumer group. Options are:",
+		"    * MKSTREAM",
+		"      Create the empty stream if it does not exist.",
+		"    * ENTRIESREAD entries_read",
+		"      Set the group's entries_read counter (internal use).",
+		"CREATECONSUMER <key> <groupname> <consumer>",
+		"    Create a new consumer in the specified group.",
+		"DELCONSUMER <key> <groupname> <consumer>",
+		"    Remove the specified consumer.",
+		"DESTROY <key> <groupname>",
+		"    Remove the specified group.",
+		"SETID <key> <groupname> <id|$> [ENTRIESREAD entries_read]",
+		"    Set the current group ID and entries_read counter.",
+	})
+}
+
+// xackCommand implements XACK key group id [id ...]. It returns the number
+// of entries removed from the pending entries list of the group.
+func xackCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	ids := make([]store.StreamID, len(args)-3)
+	for i, arg := range args[3:] {
+		id, ok := store.ParseStreamID(arg.Bytes(), 0)
+		if !ok {
+			return errInvalidStreamID
+		}
+		ids[i] = id
+	}
+	_, group, errReply := lookupStreamGroup(client, args[1].String(), args[2].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	acked := 0
+	if group != nil {
+		for _, id := range ids {
+			if group.Ack(id) {
+				acked++
+			}
+		}
+	}
+	if acked == 0 {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewInteger(int64(acked))
+}
+
+// xpendingCommand implements XPENDING key group [[IDLE min-idle-time]
+// start end count [consumer]]. Without a range it sums the pending
+// entries up: how many, the smallest and greatest IDs and how many per
+// consumer.
+func xpendingCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	extended := len(args) > 3
+	var minIdle, count int64
+	var start, end store.StreamID
+	var consumerName string
+	if extended {
+		i := 3
+		if strings.ToUpper(args[i].String()) == "IDLE" && i+1 < len(args) {
+			var errReply Resp.RESP
+			if minIdle, errReply = parseInteger(args[i+1]); errReply.IsError() {
+				return errReply
+			}
+			i += 2
+		}
+		if n := len(args) - i; n != 3 && n != 4 {
+			return syntaxError
+		}
+		var startEx, endEx, ok bool
+		if start, startEx, ok = parseStreamRangeID(args[i].Bytes(), 0); !ok {
+			return errInvalidStreamID
+		}
+		if end, endEx, ok = parseStreamRangeID(args[i+1].Bytes(), math.MaxUint64); !ok {
+			return errInvalidStreamID
+		}
+		if startEx {
+			if start, ok = start.Incr(); !ok {
+				return Resp.NewError("ERR invalid start ID for the interval")
+			}
+		}
+		if endEx {
+			if end, ok = end.Decr(); !ok {
+				return Resp.NewError("ERR invalid end ID for the interval")
+			}
+		}
+		var errReply Resp.RESP
+		if count, errReply = parseInteger(args[i+2]); errReply.IsError() {
+			return errReply
+		}
+		if i+3 < len(args) {
+			consumerName = args[i+3].String()
+		}
+	}
+	key, name := args[1].String(), args[2].String()
+	_, group, errReply := lookupStreamGroup(client, key, name)
+	if errReply.IsError() {
+		return errReply
+	}
+	if group == nil {
+		return noGroupError(key, name)
+	}
+	if !extended {
+		if group.PendingLen() == 0 {
+			return Resp.NewArray([]Resp.RESP{Resp.NewInteger(0), Resp.NewNullBulkString(), Resp.NewNullBulkString(), Resp.NewNullArray()})
+		}
+		first, last := group.PendingBounds()
+		var consumers []Resp.RESP
+		for _, consumer := range group.Consumers() {
+			if n := consumer.PendingLen(); n > 0 {
+				consumers = append(consumers, Resp.NewArray([]Resp.RESP{
+					Resp.NewBulkString(consumer.Name), Resp.NewBulkString(strconv.Itoa(n)),
+				}))
+			}
+		}
+		return Resp.NewArray([]Resp.RESP{
+			Resp.NewInteger(int64(group.PendingLen())),
+			Resp.NewBulkString(first.String()), Resp.NewBulkString(last.String()),
+			Resp.NewArray(consumers),
+		})
+	}
+	entries := []Resp.RESP{}
+	if count <= 0 || end.Less(start) {
+		return Resp.NewArray(entries)
+	}
+	now := store.Now()
+	fn := func(p *store.StreamPending) bool {
+		if end.Less(p.ID) {
+			return false
+		}
+		idle := now - p.DeliveryTime
+		if idle < minIdle {
+			return true
+		}
+		entries = append(entries, Resp.NewArray([]Resp.RESP{
+			Resp.NewBulkString(p.ID.String()), Resp.NewBulkString(p.Consumer.Name),
+			Resp.NewInteger(idle), Resp.NewInteger(p.DeliveryCount),
+		}))
+		return int64(len(entries)) != count
+	}
+	if consumerName == "" {
+		group.PendingRange(start, fn)
+	} else if consumer := group.Consumer(consumerName); consumer != nil {
+		consumer.PendingRange(start, fn)
+	}
+	return Resp.NewArray(entries)
+}
+
+// claimOptions are the options of XCLAIM applied to each claimed entry.
+type claimOptions struct {
+	deliveryTime int64
+	// retryCount is the delivery count to set, or -1 to count the claim
+	// as a delivery unless justID is set
+	retryCount    int64
+	force, justID bool
+}
+
+// claimPropagation returns the XCLAIM replicas run to claim ids like the
+// running command did: the entries are claimed regardless of their idle
+// time and get the same delivery time. Deleted entries among ids are
+// acknowledged on the replicas too. With no ids it only creates the
+// consumer and sets the last delivered ID of the group.
+func claimPropagation(args []Resp.RESP, ids []store.StreamID, options claimOptions, group *store.StreamGroup) []Resp.RESP {
+	if len(ids) == 0 {
+		ids = []store.StreamID{{}}
+	}
+	propagation := append([]Resp.RESP{Resp.NewBulkString("XCLAIM")}, args[1:4]...)
+	propagation = append(propagation, Resp.NewBulkString("0"))
+	for _, id := range ids {
+		propagation = append(propagation, Resp.NewBulkString(id.String()))
+	}
+	propagation = append(propagation, Resp.NewBulkString("TIME"), Resp.NewBulkString(strconv.FormatInt(options.deliveryTime, 10)))
+	if options.retryCount >= 0 {
+		propagation = append(propagation, Resp.NewBulkString("RETRYCOUNT"), Resp.NewBulkString(strconv.FormatInt(options.retryCount, 10)))
+	}
+	if options.force {
+		propagation = append(propagation, Resp.NewBulkString("FORCE"))
+	}
+	if options.justID {
+		propagation = append(propagation, Resp.NewBulkString("JUSTID"))
+	}
+	return append(propagation, Resp.NewBulkString("LASTID"), Resp.NewBulkString(group.LastID.String()))
+}
+
+// claim transfers the pending entry p to consumer. It reports false and
+// acknowledges p instead if the entry was deleted from the stream.
+func claim(stream *store.Stream, group *store.StreamGroup, consumer *store.StreamConsumer, p *store.StreamPending, options claimOptions) (store.StreamEntry, bool) {
+	entry, ok := stream.Entry(p.ID)
+	if !ok {
+		group.Ack(p.ID)
+		return entry, false
+	}
+	group.Claim(p, consumer, options.deliveryTime)
+	if options.retryCount >= 0 {
+		p.DeliveryCount = options.retryCount
+	} else if !options.justID {
+		p.DeliveryCount++
+	}
+	return entry, true
+}
+
+// xclaimCommand implements XCLAIM key group consumer min-idle-time id [id
+// ...] [IDLE ms] [TIME unix-time-milliseconds] [RETRYCOUNT count] [FORCE]
+// [JUSTID] [LASTID lastid]. It gives the consumer the entries pending for
+// at least min-idle-time milliseconds, FORCE adding the ones not pending
+// yet.
+func xclaimCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key, name := args[1].String(), args[2].String()
+	stream, group, errReply := lookupStreamGroup(client, key, name)
+	if errReply.IsError() {
+		return errReply
+	}
+	if group == nil {
+		return noGroupError(key, name)
+	}
+	minIdle, errReply := parseInteger(args[4])
+	if errReply.IsError() {
+		return Resp.NewError("ERR Invalid min-idle-time argument for XCLAIM")
+	}
+	i := 5
+	var ids []store.StreamID
+	for ; i < len(args); i++ {
+		id, ok := store.ParseStreamID(args[i].Bytes(), 0)
+		if !ok {
+			break
+		}
+		ids = append(ids, id)
+	}
+	now := store.Now()
+	options := claimOptions{deliveryTime: now, retryCount: -1}
+	var lastID store.StreamID
+	for ; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i].String()); {
+		case option == "FORCE":
+			options.force = true
+		case option == "JUSTID":
+			options.justID = true
+		case option == "IDLE" && i+1 < len(args):
+			idle, errReply := parseInteger(args[i+1])
+			if errReply.IsError() {
+				return Resp.NewError("ERR Invalid IDLE option argument for XCLAIM")
+			}
+			options.deliveryTime = now - idle
+			i++
+		case option == "TIME" && i+1 < len(args):
+			if options.deliveryTime, errReply = parseInteger(args[i+1]); errReply.IsError() {
+				return Resp.NewError("ERR Invalid TIME option argument for XCLAIM")
+			}
+			i++
+		case option == "RETRYCOUNT" && i+1 < len(args):
+			if options.retryCount, errReply = parseInteger(args[i+1]); errReply.IsError() {
+				return Resp.NewError("ERR Invalid RETRYCOUNT option argument for XCLAIM")
+			}
+			i++
+		case option == "LASTID" && i+1 < len(args):
+			var ok bool
+			if lastID, ok = store.ParseStreamID(args[i+1].Bytes(), 0); !ok {
+				return errInvalidStreamID
+			}
+			i++
+		default:
+			return Resp.NewError(fmt.Sprintf("ERR Unrecognized XCLAIM option '%s'", args[i].String()))
+		}
+	}
+	if options.deliveryTime < 0 || options.deliveryTime > now {
+		options.deliveryTime = now
+	}
+	lastIDSet := group.LastID.Less(lastID)
+	if lastIDSet {
+		group.LastID = lastID
+	}
+	consumer, created := group.CreateConsumer(args[3].String(), now)
+	consumer.SeenTime = now
+	claimed := []Resp.RESP{}
+	var processed []store.StreamID
+	for _, id := range ids {
+		p := group.Pending(id)
+		if p == nil {
+			if _, ok := stream.Entry(id); !ok || !options.force {
+				continue
+			}
+			p = group.AddPending(id, consumer, now)
+		} else if minIdle > 0 && now-p.DeliveryTime < minIdle {
+			continue
+		}
+		processed = append(processed, id)
+		entry, ok := claim(stream, group, consumer, p, options)
+		switch {
+		case !ok:
+			continue
+		case options.justID:
+			claimed = append(claimed, Resp.NewBulkString(id.String()))
+		default:
+			claimed = append(claimed, streamEntryReply(entry))
+		}
+		consumer.ActiveTime = now
+	}
+	if len(processed) > 0 || created || lastIDSet {
+		client.rewritePropagation(claimPropagation(args, processed, options, group))
+	} else {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewArray(claimed)
+}
+
+// xautoclaimCommand implements XAUTOCLAIM key group consumer min-idle-time
+// start [COUNT count] [JUSTID]. It claims up to count entries pending for
+// at least min-idle-time milliseconds with IDs from start on, looking at
+// no more than count*10 of them, and returns the ID to start from next
+// time, 0-0 when the pending entries list was scanned to the end, along
+// with the entries and the IDs of those deleted from the stream.
+func xautoclaimCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key, name := args[1].String(), args[2].String()
+	stream, group, errReply := lookupStreamGroup(client, key, name)
+	if errReply.IsError() {
+		return errReply
+	}
+	if group == nil {
+		return noGroupError(key, name)
+	}
+	minIdle, errReply := parseInteger(args[4])
+	if errReply.IsError() {
+		return Resp.NewError("ERR Invalid min-idle-time argument for XAUTOCLAIM")
+	}
+	start, startEx, ok := parseStreamRangeID(args[5].Bytes(), 0)
+	if !ok {
+		return errInvalidStreamID
+	}
+	if startEx {
+		if start, ok = start.Incr(); !ok {
+			return Resp.NewError("ERR invalid start ID for the interval")
+		}
+	}
+	count := int64(100)
+	now := store.Now()
+	options := claimOptions{deliveryTime: now, retryCount: -1}
+	for i := 6; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i].String()); {
+		case option == "COUNT" && i+1 < len(args):
+			if count, errReply = parseInteger(args[i+1]); errReply.IsError() {
+				return errReply
+			}
+			if count < 1 || count > math.MaxInt64/10 {
+				return Resp.NewError("ERR COUNT must be > 0")
+			}
+			i++
+		case option == "JUSTID":
+			options.justID = true
+		default:
+			return syntaxError
+		}
+	}
+	consumer, created := group.CreateConsumer(args[3].String(), now)
+	consumer.SeenTime = now
+	attempts := count * 10
+	claimed, deleted := []Resp.RESP{}, []Resp.RESP{}
+	var processed []store.StreamID
+	var next store.StreamID
+	group.PendingRange(start, func(p *store.StreamPending) bool {
+		if attempts == 0 || int64(len(claimed)) == count {
+			next = p.ID
+			return false
+		}
+		attempts--
+		if minIdle > 0 && now-p.DeliveryTime < minIdle {
+			return true
+		}
+		processed = append(processed, p.ID)
+		entry, ok := claim(stream, group, consumer, p, options)
+		switch {
+		case !ok:
+			deleted = append(deleted, Resp.NewBulkString(p.ID.String()))
+		case options.justID:
+			claimed = append(claimed, Resp.NewBulkString(p.ID.String()))
+		default:
+			claimed = append(claimed, streamEntryReply(entry))
+		}
+		return true
+	})
+	if len(claimed) > 0 {
+		consumer.ActiveTime = now
+	}
+	if len(processed) > 0 || created {
+		client.rewritePropagation(claimPropagation(args, processed, options, group))
+	} else {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewArray([]Resp.RESP{Resp.NewBulkString(next.String()), Resp.NewArray(claimed), Resp.NewArray(deleted)})
+}
+
+func lookupStreamOrError(client *Client, key string) (*store.Stream, Resp.RESP) {
+	stream, errReply := lookupStream(client, key)
+	if errReply.IsError() {
+		return nil, errReply
+	}
+	if stream == nil {
+		return nil, Resp.NewError("ERR no such key")
+	}
+	return stream, Resp.RESP{}
+}
+
+// nullableInteger replies n, or null when ok is false.
+func nullableInteger(n int64, ok bool) Resp.RESP {
+	if !ok {
+		return Resp.NewNull()
+	}
+	return Resp.NewInteger(n)
+}
+
+func streamEntryOrNull(entry store.StreamEntry, ok bool) Resp.RESP {
+	if !ok {
+		return Resp.NewNull()
+	}
+	return streamEntryReply(entry)
+}
+
+// xinfoStreamCommand implements XINFO STREAM key [FULL [COUNT count]]. FULL
+// replaces the first and last entries with up to count entries, all of
+// them if count is 0, and details the groups with their pending entries
+// lists.
+func xinfoStreamCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	full, count := false, int64(10)
+	if len(args) > 3 {
+		if strings.ToUpper(args[3].String()) != "FULL" {
+			return syntaxError
+		}
+		full = true
+		if len(args) > 4 {
+			if len(args) != 6 || strings.ToUpper(args[4].String()) != "COUNT" {
+				return syntaxError
+			}
+			var errReply Resp.RESP
+			if count, errReply = parseInteger(args[5]); errReply.IsError() {
+				return errReply
+			}
+			if count < 0 {
+				count = 0
+			}
+		}
+	}
+	stream, errReply := lookupStreamOrError(client, args[2].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	var recordedFirstID store.StreamID
+	first, hasFirst := stream.First()
+	if hasFirst {
+		recordedFirstID = first.ID
+	}
+	info := []Resp.RESP{
+		Resp.NewBulkString("length"), Resp.NewInteger(int64(stream.Len())),
+		Resp.NewBulkString("last-generated-id"), Resp.NewBulkString(stream.LastID().String()),
+		Resp.NewBulkString("max-deleted-entry-id"), Resp.NewBulkString(stream.MaxDeletedID().String()),
+		Resp.NewBulkString("entries-added"), Resp.NewInteger(stream.EntriesAdded()),
+		Resp.NewBulkString("recorded-first-entry-id"), Resp.NewBulkString(recordedFirstID.String()),
+	}
+	if !full {
+		last, hasLast := stream.Last()
+		return Resp.NewMap(append(info,
+			Resp.NewBulkString("groups"), Resp.NewInteger(int64(len(stream.Groups()))),
+			Resp.NewBulkString("first-entry"), streamEntryOrNull(first, hasFirst),
+			Resp.NewBulkString("last-entry"), streamEntryOrNull(last, hasLast),
+		))
+	}
+	entries := []Resp.RESP{}
+	stream.Range(store.StreamID{}, store.MaxStreamID, false, func(entry store.StreamEntry) bool {
+		entries = append(entries, streamEntryReply(entry))
+		return int64(len(entries)) != count
+	})
+	groups := []Resp.RESP{}
+	for _, group := range stream.Groups() {
+		groups = append(groups, groupFullInfo(stream, group, count))
+	}
+	return Resp.NewMap(append(info,
+		Resp.NewBulkString("entries"), Resp.NewArray(entries),
+		Resp.NewBulkString("groups"), Resp.NewArray(groups),
+	))
+}
+
+// groupFullInfo describes a group for XINFO STREAM FULL, listing up to
+// count pending entries for the group and each consumer.
+func groupFullInfo(stream *store.Stream, group *store.StreamGroup, count int64) Resp.RESP {
+	pending := []Resp.RESP{}
+	group.PendingRange(store.StreamID{}, func(p *store.StreamPending) bool {
+		pending = append(pending, Resp.NewArray([]Resp.RESP{
+			Resp.NewBulkString(p.ID.String()), Resp.NewBulkString(p.Consumer.Name),
+			Resp.NewInteger(p.DeliveryTime), Resp.NewInteger(p.DeliveryCount),
+		}))
+		return int64(len(pending)) != count
+	})
+	consumers := []Resp.RESP{}
+	for _, consumer := range group.Consumers() {
+		consumerPending := []Resp.RESP{}
+		consumer.PendingRange(store.StreamID{}, func(p *store.StreamPending) bool {
+			consumerPending = append(consumerPending, Resp.NewArray([]Resp.RESP{
+				Resp.NewBulkString(p.ID.String()), Resp.NewInteger(p.DeliveryTime), Resp.NewInteger(p.DeliveryCount),
+			}))
+			return int64(len(consumerPending)) != count
+		})
+		consumers = append(consumers, Resp.NewMap([]Resp.RESP{
+			Resp.NewBulkString("name"), Resp.NewBulkString(consumer.Name),
+			Resp.NewBulkString("seen-time"), Resp.NewInteger(consumer.SeenTime),
+			Resp.NewBulkString("active-time"), Resp.NewInteger(consumer.ActiveTime),
+			Resp.NewBulkString("pel-count"), Resp.NewInteger(int64(consumer.PendingLen())),
+			Resp.NewBulkString("pending"), Resp.NewArray(consumerPending),
+		}))
+	}
+	lag, ok := stream.Lag(group)
+	return Resp.NewMap([]Resp.RESP{
+		Resp.NewBulkString("name"), Resp.NewBulkString(group.Name),
+		Resp.NewBulkString("last-delivered-id"), Resp.NewBulkString(group.LastID.String()),
+		Resp.NewBulkString("entries-read"), nullableInteger(group.EntriesRead, group.EntriesRead >= 0),
+		Resp.NewBulkString("lag"), nullableInteger(lag, ok),
+		Resp.NewBulkString("pel-count"), Resp.NewInteger(int64(group.PendingLen())),
+		Resp.NewBulkString("pending"), Resp.NewArray(pending),
+		Resp.NewBulkString("consumers"), Resp.NewArray(consumers),
+	})
+}
+
+func xinfoGroupsCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	stream, errReply := lookupStreamOrError(client, args[2].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	groups := []Resp.RESP{}
+	for _, group := range stream.Groups() {
+		lag, ok := stream.Lag(group)
+		groups = append(groups, Resp.NewMap([]Resp.RESP{
+			Resp.NewBulkString("name"), Resp.NewBulkString(group.Name),
+			Resp.NewBulkString("consumers"), Resp.NewInteger(int64(len(group.Consumers()))),
+			Resp.NewBulkString("pending"), Resp.NewInteger(int64(group.PendingLen())),
+			Resp.NewBulkString("last-delivered-id"), Resp.NewBulkString(group.LastID.String()),
+			Resp.NewBulkString("entries-read"), nullableInteger(group.EntriesRead, group.EntriesRead >= 0),
+			Resp.NewBulkString("lag"), nullableInteger(lag, ok),
+		}))
+	}
+	return Resp.NewArray(groups)
+}
+
+// xinfoConsumersCommand implements XINFO CONSUMERS key group. The idle
+// time of a consumer counts from its last attempt to read or claim, the
+// inactive time from the last one that got entries, -1 if none did.
+func xinfoConsumersCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key, name := args[2].String(), args[3].String()
+	stream, errReply := lookupStreamOrError(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	group := stream.Group(name)
+	if group == nil {
+		return noSuchGroupError(key, name)
+	}
+	now := store.Now()
+	consumers := []Resp.RESP{}
+	for _, consumer := range group.Consumers() {
+		inactive := int64(-1)
+		if consumer.ActiveTime >= 0 {
+			inactive = now - consumer.ActiveTime
+		}
+		consumers = append(consumers, Resp.NewMap([]Resp.RESP{
+			Resp.NewBulkString("name"), Resp.NewBulkString(consumer.Name),
+			Resp.NewBulkString("pending"), Resp.NewInteger(int64(consumer.PendingLen())),
+			Resp.NewBulkString("idle"), Resp.NewInteger(now - consumer.SeenTime),
+			Resp.NewBulkString("inactive"), Resp.NewInteger(inactive),
+		}))
+	}
+	return Resp.NewArray(consumers)
+}
+
+func xinfoHelpCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return helpReply("XINFO", []string{
+		"CONSUMERS <key> <groupname>",
+		"    Show consumers of <groupname>.",
+		"GROUPS <key>",
+		"    Show the stream consumer groups.",
+		"STREAM <key> [FULL [COUNT <count>]",
+		"    Show information about the stream.",
+	})
+}
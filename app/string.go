@@ -0,0 +1,592 @@
+package main
+
+import (
+	"math"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "set", Arity: -3, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.",
+			Handler: setCommand,
+		},
+		&Command{
+			Name: "get", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Returns the string value of a key.",
+			Handler: getCommand,
+		},
+		&Command{
+			Name: "incr", Arity: 2, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+			Handler: incrCommand,
+		},
+		&Command{
+			Name: "decr", Arity: 2, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Decrements the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+			Handler: decrCommand,
+		},
+		&Command{
+			Name: "incrby", Arity: 3, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+			Handler: incrbyCommand,
+		},
+		&Command{
+			Name: "decrby", Arity: 3, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist.",
+			Handler: decrbyCommand,
+		},
+		&Command{
+			Name: "incrbyfloat", Arity: 3, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "2.6.0", Complexity: "O(1)",
+			Summary: "Increment the floating point value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+			Handler: incrbyfloatCommand,
+		},
+		&Command{
+			Name: "append", Arity: 3, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "2.0.0", Complexity: "O(1). The amortized time complexity is O(1) assuming the appended value is small and the already present value is of any size, since the dynamic string library used by Redis will double the free space available on every reallocation.",
+			Summary: "Appends a string to the value of a key. Creates the key if it doesn't exist.",
+			Handler: appendCommand,
+		},
+		&Command{
+			Name: "strlen", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "2.2.0", Complexity: "O(1)",
+			Summary: "Returns the length of a string value.",
+			Handler: strlenCommand,
+		},
+		&Command{
+			Name: "getrange", Arity: 4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "2.4.0", Complexity: "O(N) where N is the length of the returned string. The complexity is ultimately determined by the returned length, but because creating a substring from an existing string is very cheap, it can be considered O(1) for small strings.",
+			Summary: "Returns a substring of the string stored at a key.",
+			Handler: getrangeCommand,
+		},
+		&Command{
+			Name: "setrange", Arity: 4, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "2.2.0", Complexity: "O(1), not counting the time taken to copy the new string in place. Usually, this string is very small so the amortized complexity is O(1). Otherwise, complexity is O(M) with M being the length of the value argument.",
+			Summary: "Overwrites a part of a string value with another by an offset. Creates the key if it doesn't exist.",
+			Handler: setrangeCommand,
+		},
+		&Command{
+			Name: "getset", Arity: 3, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Returns the previous string value of a key after setting it to a new value.",
+			Handler: getsetCommand,
+		},
+		&Command{
+			Name: "getdel", Arity: 2, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "6.2.0", Complexity: "O(1)",
+			Summary: "Returns the string value of a key after deleting the key.",
+			Handler: getdelCommand,
+		},
+		&Command{
+			Name: "getex", Arity: -2, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "6.2.0", Complexity: "O(1)",
+			Summary: "Returns the string value of a key after setting its expiration time.",
+			Handler: getexCommand,
+		},
+		&Command{
+			Name: "setnx", Arity: 3, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "string", Since: "1.0.0", Complexity: "O(1)",
+			Summary: "Set the string value of a key only when the key doesn't exist.",
+			Handler: setnxCommand,
+		},
+		&Command{
+			Name: "mset", Arity: -3, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: -1, Step: 2,
+			Group: "string", Since: "1.0.1", Complexity: "O(N) where N is the number of keys to set.",
+			Summary: "Atomically creates or modifies the string values of one or more keys.",
+			Handler: msetCommand,
+		},
+		&Command{
+			Name: "mget", Arity: -2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: -1, Step: 1,
+			Group: "string", Since: "1.0.0", Complexity: "O(N) where N is the number of keys to retrieve.",
+			Summary: "Atomically returns the string values of one or more keys.",
+			Handler: mgetCommand,
+		},
+		&Command{
+			Name: "msetnx", Arity: -3, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: -1, Step: 2,
+			Group: "string", Since: "1.0.1", Complexity: "O(N) where N is the number of keys to set.",
+			Summary: "Atomically modifies the string values of one or more keys only when all keys don't exist.",
+			Handler: msetnxCommand,
+		},
+	)
+}
+
+// setCommand implements
+// SET key value [NX | XX] [GET] [EX seconds | PX milliseconds |
+// EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL].
+func setCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key := args[1].String()
+	value := args[2].Bytes()
+	var opts store.SetOptions
+	expireOption := ""
+	for i := 3; i < len(args); i++ {
+		option := strings.ToUpper(args[i].String())
+		switch option {
+		case "NX", "XX":
+			if opts.Condition != store.SetAlways {
+				return syntaxError
+			}
+			opts.Condition = store.SetIfNotExists
+			if option == "XX" {
+				opts.Condition = store.SetIfExists
+			}
+		case "GET":
+			opts.Get = true
+		case "KEEPTTL":
+			if expireOption != "" {
+				return syntaxError
+			}
+			expireOption = option
+			opts.KeepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if expireOption != "" || i+1 == len(args) {
+				return syntaxError
+			}
+			expireOption = option
+			i++
+			expireAt, errReply := parseExpireTime(args[i].String(), option, "set")
+			if errReply.IsError() {
+				return errReply
+			}
+			opts.ExpireAt = expireAt
+		default:
+			return syntaxError
+		}
+	}
+
+	old, existed, applied, err := client.db.SetWithOptions(key, value, opts)
+	if err != nil {
+		return wrongTypeError
+	}
+	if applied && (expireOption == "EX" || expireOption == "PX" || expireOption == "EXAT") {
+		// replicas must expire the key at the same moment, so relative
+		// expirations are propagated as an absolute PXAT
+		rewritten := []Resp.RESP{args[0], args[1], args[2], Resp.NewBulkString("PXAT"), Resp.NewBulkString(strconv.FormatInt(opts.ExpireAt, 10))}
+		if opts.Condition == store.SetIfNotExists {
+			rewritten = append(rewritten, Resp.NewBulkString("NX"))
+		} else if opts.Condition == store.SetIfExists {
+			rewritten = append(rewritten, Resp.NewBulkString("XX"))
+		}
+		client.rewritePropagation(rewritten)
+	} else if !applied {
+		client.rewritePropagation(nil)
+	}
+
+	if opts.Get {
+		if !existed {
+			return Resp.NewNullBulkString()
+		}
+		return Resp.NewBulk(old)
+	}
+	if !applied {
+		return Resp.NewNullBulkString()
+	}
+	return okReply
+}
+
+// parseExpireTime converts the argument of an EX, PX, EXAT or PXAT option
+// to an absolute unix time in milliseconds. On failure the returned reply
+// is the error to send.
+func parseExpireTime(arg string, unit string, command string) (int64, Resp.RESP) {
+	value, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, notIntegerError
+	}
+	if value <= 0 {
+		return 0, invalidExpireError(command)
+	}
+	if unit == "EX" || unit == "EXAT" {
+		if value > math.MaxInt64/1000 {
+			return 0, invalidExpireError(command)
+		}
+		value *= 1000
+	}
+	if unit == "EX" || unit == "PX" {
+		if value > math.MaxInt64-store.Now() {
+			return 0, invalidExpireError(command)
+		}
+		value += store.Now()
+	}
+	return value, Resp.RESP{}
+}
+
+func getCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, exist, err := client.db.Get(args[1].String())
+	if err != nil {
+		return wrongTypeError
+	}
+	if !exist {
+		return Resp.NewNullBulkString()
+	}
+	return Resp.NewBulk(value)
+}
+
+// maxStringLength is the size strings may not grow past, the default
+// proto-max-bulk-len of Redis.
+const maxStringLength = 512 * 1024 * 1024
+
+var errStringTooLong = Resp.NewError("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+
+// lookupString returns the string stored at key, or nil if there is none.
+// On failure the returned reply is the error to send.
+func lookupString(client *Client, key string) (*store.Value, Resp.RESP) {
+	value, err := client.db.LookupType(key, store.ObjString)
+	if err != nil {
+		return nil, wrongTypeError
+	}
+	return value, Resp.RESP{}
+}
+
+// replaceString stores value at key in place of old, nil if there was no
+// value, keeping its time to live.
+func replaceString(client *Client, key string, old, value *store.Value) {
+	if old != nil {
+		value.ExpireAt = old.ExpireAt
+	}
+	client.db.SetValue(key, value)
+}
+
+// setChangedString stores b, the string of value changed in place, at
+// key. A missing value is created with the contents b.
+func setChangedString(client *Client, key string, value *store.Value, b []byte) {
+	if value == nil {
+		value = store.NewString(nil)
+		client.db.SetValue(key, value)
+	}
+	value.SetBytes(b)
+}
+
+func incrCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return incrGeneric(client, args[1].String(), 1)
+}
+
+func decrCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return incrGeneric(client, args[1].String(), -1)
+}
+
+func incrbyCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	increment, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	return incrGeneric(client, args[1].String(), increment)
+}
+
+func decrbyCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	decrement, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	if decrement == math.MinInt64 {
+		return Resp.NewError("ERR decrement would overflow")
+	}
+	return incrGeneric(client, args[1].String(), -decrement)
+}
+
+// incrGeneric adds increment to the integer stored at key, 0 if the key
+// does not exist, and stores the result with the int encoding.
+func incrGeneric(client *Client, key string, increment int64) Resp.RESP {
+	value, errReply := lookupString(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	current := int64(0)
+	if value != nil {
+		var ok bool
+		if current, ok = value.Int(); !ok {
+			return notIntegerError
+		}
+	}
+	if (increment < 0 && current < math.MinInt64-increment) || (increment > 0 && current > math.MaxInt64-increment) {
+		return Resp.NewError("ERR increment or decrement would overflow")
+	}
+	current += increment
+	replaceString(client, key, value, store.NewInt(current))
+	return Resp.NewInteger(current)
+}
+
+// incrbyfloatCommand implements INCRBYFLOAT key increment. It is
+// propagated as a SET of the result so replicas don't depend on their own
+// float arithmetic.
+func incrbyfloatCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	increment, ok := parseFloat(args[2].Bytes())
+	if !ok {
+		return Resp.NewError("ERR value is not a valid float")
+	}
+	key := args[1].String()
+	value, errReply := lookupString(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	current := 0.0
+	if value != nil {
+		if current, ok = parseFloat(value.Peek()); !ok {
+			return Resp.NewError("ERR value is not a valid float")
+		}
+	}
+	current += increment
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return Resp.NewError("ERR increment would produce NaN or Infinity")
+	}
+	result := Resp.NewBulkString(Resp.FormatDouble(current))
+	replaceString(client, key, value, store.NewString(result.Bytes()))
+	client.rewritePropagation([]Resp.RESP{Resp.NewBulkString("SET"), args[1], result, Resp.NewBulkString("KEEPTTL")})
+	return result
+}
+
+// appendCommand implements APPEND key value. The string grows in place,
+// so appending to it over and over takes amortized constant time.
+func appendCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key := args[1].String()
+	value, errReply := lookupString(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		client.db.SetValue(key, store.NewString(args[2].Bytes()))
+		return Resp.NewInteger(int64(len(args[2].Bytes())))
+	}
+	if value.StrLen()+len(args[2].Bytes()) > maxStringLength {
+		return errStringTooLong
+	}
+	b := append(stringBytes(value), args[2].Bytes()...)
+	value.SetBytes(b)
+	return Resp.NewInteger(int64(len(b)))
+}
+
+func strlenCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupString(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(int64(value.StrLen()))
+}
+
+// getrangeCommand implements GETRANGE key start end, both ends inclusive
+// and negative offsets counting from the end of the string.
+func getrangeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	start, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	end, errReply := parseInteger(args[3])
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupString(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil || (start < 0 && end < 0 && start > end) {
+		return Resp.NewBulkString("")
+	}
+	b := value.Bytes()
+	n := int64(len(b))
+	if start < 0 {
+		start += n
+	}
+	if end < 0 {
+		end += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= n {
+		end = n - 1
+	}
+	if n == 0 || start > end {
+		return Resp.NewBulkString("")
+	}
+	return Resp.NewBulk(b[start : end+1])
+}
+
+// setrangeCommand implements SETRANGE key offset value. The string is
+// padded with zero bytes up to offset if needed.
+func setrangeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	offset, errReply := parseInteger(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	if offset < 0 {
+		return Resp.NewError("ERR offset is out of range")
+	}
+	key := args[1].String()
+	patch := args[3].Bytes()
+	value, errReply := lookupString(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	length := 0
+	if value != nil {
+		length = value.StrLen()
+	}
+	if len(patch) == 0 {
+		// nothing to write, and missing keys are not created
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(int64(length))
+	}
+	if offset+int64(len(patch)) > maxStringLength {
+		return errStringTooLong
+	}
+	size := int(offset) + len(patch)
+	if size < length {
+		size = length
+	}
+	b := stringBytes(value)
+	if size > len(b) {
+		b = append(b, make([]byte, size-len(b))...)
+	}
+	copy(b[offset:], patch)
+	setChangedString(client, key, value, b)
+	return Resp.NewInteger(int64(len(b)))
+}
+
+// getsetCommand implements GETSET key value, which like SET removes the
+// time to live of the key.
+func getsetCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	old, existed, _, err := client.db.SetWithOptions(args[1].String(), args[2].Bytes(), store.SetOptions{Get: true})
+	if err != nil {
+		return wrongTypeError
+	}
+	if !existed {
+		return Resp.NewNullBulkString()
+	}
+	return Resp.NewBulk(old)
+}
+
+func getdelCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key := args[1].String()
+	value, errReply := lookupString(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		client.rewritePropagation(nil)
+		return Resp.NewNullBulkString()
+	}
+	client.db.Delete(key)
+	return Resp.NewBulk(value.Bytes())
+}
+
+// getexCommand implements GETEX key [EX seconds | PX milliseconds | EXAT
+// unix-time-seconds | PXAT unix-time-milliseconds | PERSIST]. A new
+// expiration is propagated as a PEXPIREAT, like the EXPIRE family.
+func getexCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	option := ""
+	var expireAt int64
+	for i := 2; i < len(args); i++ {
+		if option != "" {
+			return syntaxError
+		}
+		switch option = strings.ToUpper(args[i].String()); option {
+		case "PERSIST":
+		case "EX", "PX", "EXAT", "PXAT":
+			if i+1 == len(args) {
+				return syntaxError
+			}
+			i++
+			var errReply Resp.RESP
+			if expireAt, errReply = parseExpireTime(args[i].String(), option, "getex"); errReply.IsError() {
+				return errReply
+			}
+		default:
+			return syntaxError
+		}
+	}
+	key := args[1].String()
+	value, errReply := lookupString(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		client.rewritePropagation(nil)
+		return Resp.NewNullBulkString()
+	}
+	reply := Resp.NewBulk(value.Bytes())
+	switch option {
+	case "":
+		client.rewritePropagation(nil)
+	case "PERSIST":
+		if client.db.Persist(key) {
+			client.rewritePropagation([]Resp.RESP{Resp.NewBulkString("PERSIST"), args[1]})
+		} else {
+			client.rewritePropagation(nil)
+		}
+	default:
+		client.db.Expire(key, expireAt, store.ExpireAlways)
+		client.rewritePropagation([]Resp.RESP{
+			Resp.NewBulkString("PEXPIREAT"),
+			args[1],
+			Resp.NewBulkString(strconv.FormatInt(expireAt, 10)),
+		})
+	}
+	return reply
+}
+
+func setnxCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	_, _, applied, _ := client.db.SetWithOptions(args[1].String(), args[2].Bytes(), store.SetOptions{Condition: store.SetIfNotExists})
+	if !applied {
+		client.rewritePropagation(nil)
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(1)
+}
+
+func msetCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args)%2 != 1 {
+		return wrongArityError("mset")
+	}
+	for i := 1; i < len(args); i += 2 {
+		client.db.Set(args[i].String(), args[i+1].Bytes())
+	}
+	return okReply
+}
+
+// msetnxCommand implements MSETNX key value [key value ...], which sets
+// none of the keys if any of them exists.
+func msetnxCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	if len(args)%2 != 1 {
+		return wrongArityError("msetnx")
+	}
+	for i := 1; i < len(args); i += 2 {
+		if client.db.Lookup(args[i].String()) != nil {
+			client.rewritePropagation(nil)
+			return Resp.NewInteger(0)
+		}
+	}
+	for i := 1; i < len(args); i += 2 {
+		client.db.Set(args[i].String(), args[i+1].Bytes())
+	}
+	return Resp.NewInteger(1)
+}
+
+// mgetCommand implements MGET key [key ...]. Keys that don't hold a
+// string reply nil rather than an error.
+func mgetCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	values := make([]Resp.RESP, len(args)-1)
+	for i, key := range args[1:] {
+		value, exist, err := client.db.Get(key.String())
+		if err != nil || !exist {
+			values[i] = Resp.NewNullBulkString()
+			continue
+		}
+		values[i] = Resp.NewBulk(value)
+	}
+	return Resp.NewArray(values)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"math"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"strconv"
+	"testing"
+)
+
+func TestSetOptionErrors(t *testing.T) {
+	client := newTestClient(newTestDB(t))
+	for _, args := range [][]string{
+		{"SET", "k", "v", "NX", "XX"},
+		{"SET", "k", "v", "XX", "XX"},
+		{"SET", "k", "v", "KEEPTTL", "EX", "10"},
+		{"SET", "k", "v", "EX", "10", "KEEPTTL"},
+		{"SET", "k", "v", "EX", "10", "PX", "10"},
+		{"SET", "k", "v", "EX"},
+		{"SET", "k", "v", "NX", "PXAT"},
+		{"SET", "k", "v", "SOON"},
+	} {
+		expectReply(t, run(client, args...), syntaxError)
+	}
+	invalid := invalidExpireError("set")
+	for _, args := range [][]string{
+		{"SET", "k", "v", "EX", "0"},
+		{"SET", "k", "v", "PX", "-1"},
+		{"SET", "k", "v", "EXAT", "0"},
+		{"SET", "k", "v", "EX", strconv.FormatInt(math.MaxInt64/1000+1, 10)},
+		{"SET", "k", "v", "PX", strconv.FormatInt(math.MaxInt64, 10)},
+	} {
+		expectReply(t, run(client, args...), invalid)
+	}
+	expectReply(t, run(client, "SET", "k", "v", "EX", "ten"), notIntegerError)
+	expectReply(t, run(client, "EXISTS", "k"), Resp.NewInteger(0))
+}
+
+func TestParseExpireTime(t *testing.T) {
+	if at, errReply := parseExpireTime("100", "EXAT", "set"); errReply.IsError() || at != 100000 {
+		t.Errorf("Expected EXAT in milliseconds, got %d %q", at, errReply.String())
+	}
+	if at, errReply := parseExpireTime("100", "PXAT", "set"); errReply.IsError() || at != 100 {
+		t.Errorf("Expected PXAT as is, got %d %q", at, errReply.String())
+	}
+	before := store.Now()
+	at, errReply := parseExpireTime("10", "EX", "set")
+	if errReply.IsError() || at < before+10000 || at > store.Now()+10000 {
+		t.Errorf("Expected EX to be 10 seconds from now, got %d %q", at, errReply.String())
+	}
+}
+
+func TestSetPropagatesAbsoluteExpiration(t *testing.T) {
+	db := newTestDB(t)
+	queue := captureReplication(t)
+	client := newTestClient(db)
+	pxat := func(key string) string {
+		return strconv.FormatInt(db.ExpireTime(key), 10)
+	}
+
+	expectReply(t, run(client, "SET", "a", "v", "EX", "100"), okReply)
+	expectPropagated(t, queue, []string{"SET", "a", "v", "PXAT", pxat("a")})
+	expectReply(t, run(client, "SET", "b", "v", "PX", "5000", "NX"), okReply)
+	expectPropagated(t, queue, []string{"SET", "b", "v", "PXAT", pxat("b"), "NX"})
+	at := strconv.FormatInt(store.Now()/1000+100, 10)
+	expectReply(t, run(client, "SET", "b", "w", "XX", "EXAT", at), okReply)
+	expectPropagated(t, queue, []string{"SET", "b", "w", "PXAT", at + "000", "XX"})
+
+	// other forms are propagated as they are, and not at all when the
+	// key is not set
+	expectReply(t, run(client, "SET", "a", "w", "KEEPTTL"), okReply)
+	expectPropagated(t, queue, []string{"SET", "a", "w", "KEEPTTL"})
+	expectReply(t, run(client, "SET", "a", "x", "NX", "EX", "10"), Resp.NewNullBulkString())
+	expectPropagated(t, queue)
+}
+
+func TestSetbitKeepsRepliesIntact(t *testing.T) {
+	db := newTestDB(t)
+	client := newTestClient(db)
+	expectReply(t, run(client, "SETBIT", "bits", "7", "1"), Resp.NewInteger(0))
+	reply := run(client, "GET", "bits")
+	expectReply(t, run(client, "SETBIT", "bits", "6", "1"), Resp.NewInteger(0))
+	expectReply(t, run(client, "BITFIELD", "bits", "SET", "u8", "8", "255"), Resp.NewArray([]Resp.RESP{Resp.NewInteger(0)}))
+	expectReply(t, reply, Resp.NewBulkString("\x01"))
+	expectReply(t, run(client, "GET", "bits"), Resp.NewBulkString("\x03\xff"))
+	if encoding := db.Lookup("bits").Encoding; encoding != store.EncodingRaw {
+		t.Errorf("Expected a raw string, got %s", encoding)
+	}
+
+	// a copy does not see the bits set afterwards
+	expectReply(t, run(client, "COPY", "bits", "dup"), Resp.NewInteger(1))
+	expectReply(t, run(client, "SETBIT", "bits", "0", "1"), Resp.NewInteger(0))
+	expectReply(t, run(client, "GET", "dup"), Resp.NewBulkString("\x03\xff"))
+}
+
+func TestAppendAndSetrangeKeepRepliesIntact(t *testing.T) {
+	db := newTestDB(t)
+	client := newTestClient(db)
+	expectReply(t, run(client, "APPEND", "s", "12"), Resp.NewInteger(2))
+	expectReply(t, run(client, "APPEND", "s", "3"), Resp.NewInteger(3))
+	reply := run(client, "GET", "s")
+	expectReply(t, run(client, "SETRANGE", "s", "0", "x"), Resp.NewInteger(3))
+	expectReply(t, run(client, "APPEND", "s", "4"), Resp.NewInteger(4))
+	expectReply(t, reply, Resp.NewBulkString("123"))
+	expectReply(t, run(client, "GET", "s"), Resp.NewBulkString("x234"))
+
+	expectReply(t, run(client, "SETRANGE", "s", "6", "y"), Resp.NewInteger(7))
+	expectReply(t, run(client, "GET", "s"), Resp.NewBulkString("x234\x00\x00y"))
+	if encoding := db.Lookup("s").Encoding; encoding != store.EncodingRaw {
+		t.Errorf("Expected a raw string, got %s", encoding)
+	}
+}
+
+func TestAppendGrowsInPlace(t *testing.T) {
+	db := newTestDB(t)
+	client := newTestClient(db)
+	run(client, "SET", "s", "start")
+	moves := 0
+	var last *byte
+	for i := 0; i < 10000; i++ {
+		run(client, "APPEND", "s", "-")
+		if b := db.Lookup("s").Peek(); &b[0] != last {
+			last = &b[0]
+			moves++
+		}
+	}
+	// the string only moves when it runs out of spare capacity
+	if moves > 30 {
+		t.Errorf("Expected appends to grow the string in place, it moved %d times", moves)
+	}
+}
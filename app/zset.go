@@ -0,0 +1,990 @@
+package main
+
+import (
+	"math"
+	"redis-go/internal/store"
+	Resp "redis-go/pkg/resp"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerCommands(
+		&Command{
+			Name: "zadd", Arity: -4, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "1.2.0", Complexity: "O(log(N)) for each item added, where N is the number of elements in the sorted set.",
+			Summary: "Adds one or more members to a sorted set, or updates their scores. Creates the key if it doesn't exist.",
+			Handler: zaddCommand,
+		},
+		&Command{
+			Name: "zincrby", Arity: 4, Flags: []string{"write", "denyoom", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "1.2.0", Complexity: "O(log(N)) where N is the number of elements in the sorted set.",
+			Summary: "Increments the score of a member in a sorted set.",
+			Handler: zincrbyCommand,
+		},
+		&Command{
+			Name: "zrem", Arity: -3, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "1.2.0", Complexity: "O(M*log(N)) with N being the number of elements in the sorted set and M the number of elements to be removed.",
+			Summary: "Removes one or more members from a sorted set. Deletes the sorted set if all members were removed.",
+			Handler: zremCommand,
+		},
+		&Command{
+			Name: "zcard", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "1.2.0", Complexity: "O(1)",
+			Summary: "Returns the number of members in a sorted set.",
+			Handler: zcardCommand,
+		},
+		&Command{
+			Name: "zscore", Arity: 3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "1.2.0", Complexity: "O(1)",
+			Summary: "Returns the score of a member in a sorted set.",
+			Handler: zscoreCommand,
+		},
+		&Command{
+			Name: "zmscore", Arity: -3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "6.2.0", Complexity: "O(N) where N is the number of members being requested.",
+			Summary: "Returns the score of one or more members in a sorted set.",
+			Handler: zmscoreCommand,
+		},
+		&Command{
+			Name: "zrank", Arity: 3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "2.0.0", Complexity: "O(log(N))",
+			Summary: "Returns the index of a member in a sorted set ordered by ascending scores.",
+			Handler: zrankCommand,
+		},
+		&Command{
+			Name: "zrevrank", Arity: 3, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "2.0.0", Complexity: "O(log(N))",
+			Summary: "Returns the index of a member in a sorted set ordered by descending scores.",
+			Handler: zrevrankCommand,
+		},
+		&Command{
+			Name: "zcount", Arity: 4, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "2.0.0", Complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+			Summary: "Returns the count of members in a sorted set that have scores within a range.",
+			Handler: zcountCommand,
+		},
+		&Command{
+			Name: "zlexcount", Arity: 4, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "2.8.9", Complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+			Summary: "Returns the number of members in a sorted set within a lexicographical range.",
+			Handler: zlexcountCommand,
+		},
+		&Command{
+			Name: "zrange", Arity: -4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "1.2.0", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements returned.",
+			Summary: "Returns members in a sorted set within a range of indexes.",
+			Handler: zrangeCommand,
+		},
+		&Command{
+			Name: "zrangestore", Arity: -5, Flags: []string{"write", "denyoom"}, FirstKey: 1, LastKey: 2, Step: 1,
+			Group: "sorted-set", Since: "6.2.0", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements stored into the destination key.",
+			Summary: "Stores a range of members from sorted set in a key.",
+			Handler: zrangestoreCommand,
+		},
+		&Command{
+			Name: "zrevrange", Arity: -4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "1.2.0", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements returned.",
+			Summary: "Returns members in a sorted set within a range of indexes in reverse order.",
+			Handler: zrevrangeCommand,
+		},
+		&Command{
+			Name: "zrangebyscore", Arity: -4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "1.0.5", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements being returned.",
+			Summary: "Returns members in a sorted set within a range of scores.",
+			Handler: zrangebyscoreCommand,
+		},
+		&Command{
+			Name: "zrevrangebyscore", Arity: -4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "2.2.0", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements being returned.",
+			Summary: "Returns members in a sorted set within a range of scores in reverse order.",
+			Handler: zrevrangebyscoreCommand,
+		},
+		&Command{
+			Name: "zrangebylex", Arity: -4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "2.8.9", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements being returned.",
+			Summary: "Returns members in a sorted set within a lexicographical range.",
+			Handler: zrangebylexCommand,
+		},
+		&Command{
+			Name: "zrevrangebylex", Arity: -4, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "2.8.9", Complexity: "O(log(N)+M) with N being the number of elements in the sorted set and M the number of elements being returned.",
+			Summary: "Returns members in a sorted set within a lexicographical range in reverse order.",
+			Handler: zrevrangebylexCommand,
+		},
+		&Command{
+			Name: "zpopmin", Arity: -2, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "5.0.0", Complexity: "O(log(N)*M) with N being the number of elements in the sorted set, and M being the number of elements popped.",
+			Summary: "Returns the lowest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+			Handler: zpopminCommand,
+		},
+		&Command{
+			Name: "zpopmax", Arity: -2, Flags: []string{"write", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "5.0.0", Complexity: "O(log(N)*M) with N being the number of elements in the sorted set, and M being the number of elements popped.",
+			Summary: "Returns the highest-scoring members from a sorted set after removing them. Deletes the sorted set if the last member was popped.",
+			Handler: zpopmaxCommand,
+		},
+		&Command{
+			Name: "bzpopmin", Arity: -3, Flags: []string{"write", "fast", "blocking"}, FirstKey: 1, LastKey: -2, Step: 1,
+			Group: "sorted-set", Since: "5.0.0", Complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+			Summary: "Removes and returns the member with the lowest score from one or more sorted sets. Blocks until a member is available otherwise. Deletes the sorted set if the last element was popped.",
+			Handler: bzpopminCommand,
+		},
+		&Command{
+			Name: "bzpopmax", Arity: -3, Flags: []string{"write", "fast", "blocking"}, FirstKey: 1, LastKey: -2, Step: 1,
+			Group: "sorted-set", Since: "5.0.0", Complexity: "O(log(N)) with N being the number of elements in the sorted set.",
+			Summary: "Removes and returns the member with the highest score from one or more sorted sets. Blocks until a member available otherwise. Deletes the sorted set if the last element was popped.",
+			Handler: bzpopmaxCommand,
+		},
+		&Command{
+			Name: "zunion", Arity: -3, Flags: []string{"readonly", "movablekeys"},
+			KeySpecs: []KeySpec{{Flags: []string{"RO", "ACCESS"}, BeginIndex: 1, KeyNumIndex: 0, FirstKey: 1, KeyStep: 1}},
+			Group:    "sorted-set", Since: "6.2.0", Complexity: "O(N)+O(M*log(M)) with N being the sum of the sizes of the input sorted sets, and M being the number of elements in the resulting sorted set.",
+			Summary: "Returns the union of multiple sorted sets.",
+			Handler: zunionCommand,
+		},
+		&Command{
+			Name: "zunionstore", Arity: -4, Flags: []string{"write", "denyoom", "movablekeys"}, FirstKey: 1, LastKey: 1, Step: 1,
+			KeySpecs: []KeySpec{
+				{Flags: []string{"OW", "UPDATE"}, BeginIndex: 1, KeyStep: 1},
+				{Flags: []string{"RO", "ACCESS"}, BeginIndex: 2, KeyNumIndex: 0, FirstKey: 1, KeyStep: 1},
+			},
+			Group: "sorted-set", Since: "2.0.0", Complexity: "O(N)+O(M log(M)) with N being the sum of the sizes of the input sorted sets, and M being the number of elements in the resulting sorted set.",
+			Summary: "Stores the union of multiple sorted sets in a key.",
+			Handler: zunionstoreCommand,
+		},
+		&Command{
+			Name: "zinter", Arity: -3, Flags: []string{"readonly", "movablekeys"},
+			KeySpecs: []KeySpec{{Flags: []string{"RO", "ACCESS"}, BeginIndex: 1, KeyNumIndex: 0, FirstKey: 1, KeyStep: 1}},
+			Group:    "sorted-set", Since: "6.2.0", Complexity: "O(N*K)+O(M*log(M)) worst case with N being the smallest input sorted set, K being the number of input sorted sets and M being the number of elements in the resulting sorted set.",
+			Summary: "Returns the intersect of multiple sorted sets.",
+			Handler: zinterCommand,
+		},
+		&Command{
+			Name: "zinterstore", Arity: -4, Flags: []string{"write", "denyoom", "movablekeys"}, FirstKey: 1, LastKey: 1, Step: 1,
+			KeySpecs: []KeySpec{
+				{Flags: []string{"OW", "UPDATE"}, BeginIndex: 1, KeyStep: 1},
+				{Flags: []string{"RO", "ACCESS"}, BeginIndex: 2, KeyNumIndex: 0, FirstKey: 1, KeyStep: 1},
+			},
+			Group: "sorted-set", Since: "2.0.0", Complexity: "O(N*K)+O(M*log(M)) worst case with N being the smallest input sorted set, K being the number of input sorted sets and M being the number of elements in the resulting sorted set.",
+			Summary: "Stores the intersect of multiple sorted sets in a key.",
+			Handler: zinterstoreCommand,
+		},
+		&Command{
+			Name: "zscan", Arity: -3, Flags: []string{"readonly"}, FirstKey: 1, LastKey: 1, Step: 1,
+			Group: "sorted-set", Since: "2.8.0", Complexity: "O(1) for every call. O(N) for a complete iteration, including enough command calls for the cursor to return back to 0. N is the number of elements inside the collection.",
+			Summary: "Iterates over members and scores of a sorted set.",
+			Handler: zscanCommand,
+		},
+	)
+}
+
+// zsetEntry is a member of a sorted set with its score.
+type zsetEntry struct {
+	member []byte
+	score  float64
+}
+
+var errNotFloat = Resp.NewError("ERR value is not a valid float")
+
+// lookupZSet returns the sorted set stored at key, or nil if there is
+// none. On failure the returned reply is the error to send.
+func lookupZSet(client *Client, key string) (*store.Value, Resp.RESP) {
+	value, err := client.db.LookupType(key, store.ObjZSet)
+	if err != nil {
+		return nil, wrongTypeError
+	}
+	return value, Resp.RESP{}
+}
+
+// zsetEntriesReply replies with members, followed by their scores with
+// withScores. RESP3 clients get a pair per member.
+func zsetEntriesReply(client *Client, entries []zsetEntry, withScores bool) Resp.RESP {
+	reply := make([]Resp.RESP, 0, len(entries)*2)
+	for _, entry := range entries {
+		switch {
+		case !withScores:
+			reply = append(reply, Resp.NewBulk(entry.member))
+		case client.protocol >= 3:
+			reply = append(reply, Resp.NewArray([]Resp.RESP{Resp.NewBulk(entry.member), Resp.NewDouble(entry.score)}))
+		default:
+			reply = append(reply, Resp.NewBulk(entry.member), Resp.NewDouble(entry.score))
+		}
+	}
+	return Resp.NewArray(reply)
+}
+
+// zsetEntries returns all the members of a sorted set in order.
+func zsetEntries(value *store.Value) []zsetEntry {
+	entries := make([]zsetEntry, 0, value.ZSetLen())
+	value.ZSetRangeByRank(0, value.ZSetLen()-1, false, func(member []byte, score float64) bool {
+		entries = append(entries, zsetEntry{member, score})
+		return true
+	})
+	return entries
+}
+
+// zaddFlags are the options of ZADD.
+type zaddFlags struct {
+	nx, xx, gt, lt, ch, incr bool
+}
+
+// zaddCommand implements
+// ZADD key [NX | XX] [GT | LT] [CH] [INCR] score member [score member ...].
+func zaddCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	var flags zaddFlags
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i].String()) {
+		case "NX":
+			flags.nx = true
+		case "XX":
+			flags.xx = true
+		case "GT":
+			flags.gt = true
+		case "LT":
+			flags.lt = true
+		case "CH":
+			flags.ch = true
+		case "INCR":
+			flags.incr = true
+		default:
+			break options
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return syntaxError
+	}
+	if flags.nx && flags.xx {
+		return Resp.NewError("ERR XX and NX options at the same time are not compatible")
+	}
+	if (flags.gt && flags.nx) || (flags.lt && flags.nx) || (flags.gt && flags.lt) {
+		return Resp.NewError("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if flags.incr && len(pairs) > 2 {
+		return Resp.NewError("ERR INCR option supports a single increment-element pair")
+	}
+	entries := make([]zsetEntry, len(pairs)/2)
+	for j := range entries {
+		score, ok := parseFloat(pairs[j*2].Bytes())
+		if !ok {
+			return errNotFloat
+		}
+		entries[j] = zsetEntry{pairs[j*2+1].Bytes(), score}
+	}
+	return zaddGeneric(client, args[1].String(), flags, entries)
+}
+
+func zincrbyCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	increment, ok := parseFloat(args[2].Bytes())
+	if !ok {
+		return errNotFloat
+	}
+	return zaddGeneric(client, args[1].String(), zaddFlags{incr: true}, []zsetEntry{{args[3].Bytes(), increment}})
+}
+
+// zaddGeneric adds or updates the members of the sorted set at key as
+// ZADD does with flags. With INCR it replies with the new score, or nil
+// when the flags prevented the update.
+func zaddGeneric(client *Client, key string, flags zaddFlags, entries []zsetEntry) Resp.RESP {
+	value, errReply := lookupZSet(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		if flags.xx {
+			client.rewritePropagation(nil)
+			if flags.incr {
+				return Resp.NewNullBulkString()
+			}
+			return Resp.NewInteger(0)
+		}
+		value = store.NewZSet()
+		client.db.SetValue(key, value)
+	}
+	added, updated := 0, 0
+	score, applied := 0.0, false
+	for _, entry := range entries {
+		score = entry.score
+		current, exists := value.ZSetScore(entry.member)
+		if !exists {
+			if flags.xx {
+				continue
+			}
+			value.ZSetAdd(entry.member, score)
+			added++
+			applied = true
+			continue
+		}
+		if flags.nx {
+			continue
+		}
+		if flags.incr {
+			score += current
+			if math.IsNaN(score) {
+				client.db.Updated(key, value)
+				return Resp.NewError("ERR resulting score is not a number (NaN)")
+			}
+		}
+		if (flags.lt && score >= current) || (flags.gt && score <= current) {
+			continue
+		}
+		applied = true
+		if score != current {
+			value.ZSetAdd(entry.member, score)
+			updated++
+		}
+	}
+	client.db.Updated(key, value)
+	if added+updated == 0 {
+		client.rewritePropagation(nil)
+	}
+	if added > 0 {
+		signalKeyAsReady(client.db, key)
+	}
+	if flags.incr {
+		if !applied {
+			return Resp.NewNullBulkString()
+		}
+		return Resp.NewDouble(score)
+	}
+	if flags.ch {
+		return Resp.NewInteger(int64(added + updated))
+	}
+	return Resp.NewInteger(int64(added))
+}
+
+func zremCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	key := args[1].String()
+	value, errReply := lookupZSet(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	removed := 0
+	if value != nil {
+		for _, member := range args[2:] {
+			if value.ZSetRemove(member.Bytes()) {
+				removed++
+			}
+		}
+		client.db.Updated(key, value)
+	}
+	if removed == 0 {
+		client.rewritePropagation(nil)
+	}
+	return Resp.NewInteger(int64(removed))
+}
+
+func zcardCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(int64(value.ZSetLen()))
+}
+
+func zscoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value != nil {
+		if score, ok := value.ZSetScore(args[2].Bytes()); ok {
+			return Resp.NewDouble(score)
+		}
+	}
+	return Resp.NewNullBulkString()
+}
+
+func zmscoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	replies := make([]Resp.RESP, len(args)-2)
+	for i, member := range args[2:] {
+		replies[i] = Resp.NewNullBulkString()
+		if value == nil {
+			continue
+		}
+		if score, ok := value.ZSetScore(member.Bytes()); ok {
+			replies[i] = Resp.NewDouble(score)
+		}
+	}
+	return Resp.NewArray(replies)
+}
+
+func zrankCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zrankGeneric(client, args, false)
+}
+
+func zrevrankCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zrankGeneric(client, args, true)
+}
+
+func zrankGeneric(client *Client, args []Resp.RESP, reverse bool) Resp.RESP {
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value != nil {
+		if rank, ok := value.ZSetRank(args[2].Bytes(), reverse); ok {
+			return Resp.NewInteger(int64(rank))
+		}
+	}
+	return Resp.NewNullBulkString()
+}
+
+// parseScoreRange parses the min and max of a score range, each excluded
+// when prefixed with "(".
+func parseScoreRange(min, max Resp.RESP) (store.ScoreRange, Resp.RESP) {
+	var r store.ScoreRange
+	var minOk, maxOk bool
+	r.Min, r.MinEx, minOk = parseScoreBound(min.Bytes())
+	r.Max, r.MaxEx, maxOk = parseScoreBound(max.Bytes())
+	if !minOk || !maxOk {
+		return r, Resp.NewError("ERR min or max is not a float")
+	}
+	return r, Resp.RESP{}
+}
+
+func parseScoreBound(arg []byte) (float64, bool, bool) {
+	exclusive := len(arg) > 0 && arg[0] == '('
+	if exclusive {
+		arg = arg[1:]
+	}
+	score, ok := parseFloat(arg)
+	return score, exclusive, ok
+}
+
+// parseLexRange parses the min and max of a lexicographical range: "-" and
+// "+" stand for the smallest and largest strings, other ends start with
+// "[" when included or "(" when excluded.
+func parseLexRange(min, max Resp.RESP) (store.LexRange, Resp.RESP) {
+	var r store.LexRange
+	var minOk, maxOk bool
+	r.Min, minOk = parseLexBound(min.Bytes())
+	r.Max, maxOk = parseLexBound(max.Bytes())
+	if !minOk || !maxOk {
+		return r, Resp.NewError("ERR min or max not valid string range item")
+	}
+	return r, Resp.RESP{}
+}
+
+func parseLexBound(arg []byte) (store.LexBound, bool) {
+	switch {
+	case len(arg) == 1 && arg[0] == '+':
+		return store.LexBound{Inf: 1}, true
+	case len(arg) == 1 && arg[0] == '-':
+		return store.LexBound{Inf: -1}, true
+	case len(arg) > 0 && (arg[0] == '[' || arg[0] == '('):
+		return store.LexBound{Member: string(arg[1:]), Exclusive: arg[0] == '('}, true
+	}
+	return store.LexBound{}, false
+}
+
+func zcountCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	r, errReply := parseScoreRange(args[2], args[3])
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(int64(value.ZSetCount(r)))
+}
+
+func zlexcountCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	r, errReply := parseLexRange(args[2], args[3])
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewInteger(0)
+	}
+	return Resp.NewInteger(int64(value.ZSetLexCount(r)))
+}
+
+type zrangeKind int
+
+const (
+	zrangeAuto zrangeKind = iota
+	zrangeByRank
+	zrangeByScore
+	zrangeByLex
+)
+
+// zrangeRequest describes a range of a sorted set as given to ZRANGE and
+// its older variants.
+type zrangeRequest struct {
+	kind       zrangeKind
+	reverse    bool
+	withScores bool
+	// start and stop are ranks, or the min and max of the range when
+	// reading forward
+	start, stop Resp.RESP
+	// offset and count are set by LIMIT, a negative count meaning all
+	offset, count int64
+}
+
+// parseZrangeOptions parses the arguments of a range following start and
+// stop. BYSCORE, BYLEX and REV are only accepted when the kind is still
+// zrangeAuto, as ZRANGE has it. WITHSCORES is not accepted when storing.
+func parseZrangeOptions(args []Resp.RESP, req *zrangeRequest, storing bool) Resp.RESP {
+	auto := req.kind == zrangeAuto
+	limit := false
+	req.count = -1
+	for i := 0; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i].String()); {
+		case option == "WITHSCORES" && !storing:
+			req.withScores = true
+		case option == "LIMIT" && i+2 < len(args):
+			var errReply Resp.RESP
+			if req.offset, errReply = parseInteger(args[i+1]); errReply.IsError() {
+				return errReply
+			}
+			if req.count, errReply = parseInteger(args[i+2]); errReply.IsError() {
+				return errReply
+			}
+			limit = true
+			i += 2
+		case auto && option == "BYSCORE" && req.kind == zrangeAuto:
+			req.kind = zrangeByScore
+		case auto && option == "BYLEX" && req.kind == zrangeAuto:
+			req.kind = zrangeByLex
+		case auto && option == "REV":
+			req.reverse = true
+		default:
+			return syntaxError
+		}
+	}
+	if req.kind == zrangeAuto {
+		req.kind = zrangeByRank
+	}
+	if limit && req.kind == zrangeByRank {
+		return Resp.NewError("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if req.withScores && req.kind == zrangeByLex {
+		return Resp.NewError("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+	return Resp.RESP{}
+}
+
+// zrangeGeneric returns the members of the sorted set at key in the range
+// described by req.
+func zrangeGeneric(client *Client, key string, req zrangeRequest) ([]zsetEntry, Resp.RESP) {
+	min, max := req.start, req.stop
+	if req.reverse {
+		min, max = max, min
+	}
+	var scoreRange store.ScoreRange
+	var lexRange store.LexRange
+	var start, stop int64
+	var errReply Resp.RESP
+	switch req.kind {
+	case zrangeByRank:
+		if start, errReply = parseInteger(req.start); errReply.IsError() {
+			return nil, errReply
+		}
+		if stop, errReply = parseInteger(req.stop); errReply.IsError() {
+			return nil, errReply
+		}
+	case zrangeByScore:
+		scoreRange, errReply = parseScoreRange(min, max)
+	case zrangeByLex:
+		lexRange, errReply = parseLexRange(min, max)
+	}
+	if errReply.IsError() {
+		return nil, errReply
+	}
+	value, errReply := lookupZSet(client, key)
+	if errReply.IsError() || value == nil || req.offset < 0 {
+		return nil, errReply
+	}
+	var entries []zsetEntry
+	skip := req.offset
+	collect := func(member []byte, score float64) bool {
+		if skip > 0 {
+			skip--
+			return true
+		}
+		if req.count >= 0 && int64(len(entries)) == req.count {
+			return false
+		}
+		entries = append(entries, zsetEntry{member, score})
+		return true
+	}
+	switch req.kind {
+	case zrangeByRank:
+		if first, last, ok := listRange(start, stop, value.ZSetLen()); ok {
+			value.ZSetRangeByRank(first, last, req.reverse, collect)
+		}
+	case zrangeByScore:
+		value.ZSetRangeByScore(scoreRange, req.reverse, collect)
+	case zrangeByLex:
+		value.ZSetRangeByLex(lexRange, req.reverse, collect)
+	}
+	return entries, Resp.RESP{}
+}
+
+func zrangeReply(client *Client, args []Resp.RESP, req zrangeRequest) Resp.RESP {
+	req.start, req.stop = args[2], args[3]
+	if errReply := parseZrangeOptions(args[4:], &req, false); errReply.IsError() {
+		return errReply
+	}
+	entries, errReply := zrangeGeneric(client, args[1].String(), req)
+	if errReply.IsError() {
+		return errReply
+	}
+	return zsetEntriesReply(client, entries, req.withScores)
+}
+
+// zrangeCommand implements ZRANGE key start stop [BYSCORE | BYLEX] [REV]
+// [LIMIT offset count] [WITHSCORES].
+func zrangeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zrangeReply(client, args, zrangeRequest{})
+}
+
+func zrevrangeCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zrangeReply(client, args, zrangeRequest{kind: zrangeByRank, reverse: true})
+}
+
+func zrangebyscoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zrangeReply(client, args, zrangeRequest{kind: zrangeByScore})
+}
+
+func zrevrangebyscoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zrangeReply(client, args, zrangeRequest{kind: zrangeByScore, reverse: true})
+}
+
+func zrangebylexCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zrangeReply(client, args, zrangeRequest{kind: zrangeByLex})
+}
+
+func zrevrangebylexCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zrangeReply(client, args, zrangeRequest{kind: zrangeByLex, reverse: true})
+}
+
+// zrangestoreCommand implements ZRANGESTORE dst src min max
+// [BYSCORE | BYLEX] [REV] [LIMIT offset count].
+func zrangestoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	req := zrangeRequest{start: args[3], stop: args[4]}
+	if errReply := parseZrangeOptions(args[5:], &req, true); errReply.IsError() {
+		return errReply
+	}
+	entries, errReply := zrangeGeneric(client, args[2].String(), req)
+	if errReply.IsError() {
+		return errReply
+	}
+	result := store.NewZSet()
+	for _, entry := range entries {
+		result.ZSetAdd(entry.member, entry.score)
+	}
+	zsetStore(client, args[1].String(), result)
+	return Resp.NewInteger(int64(len(entries)))
+}
+
+// zsetStore stores the result of a command at key, replacing what was
+// there. An empty result deletes the key.
+func zsetStore(client *Client, key string, result *store.Value) {
+	if result.ZSetLen() == 0 {
+		client.db.Delete(key)
+		return
+	}
+	client.db.SetValue(key, result)
+	signalKeyAsReady(client.db, key)
+}
+
+func zpopminCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zpopGeneric(client, args, false)
+}
+
+func zpopmaxCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zpopGeneric(client, args, true)
+}
+
+// zpopGeneric implements ZPOPMIN and ZPOPMAX key [count]. Without count a
+// flat member and score pair is returned, whatever the protocol.
+func zpopGeneric(client *Client, args []Resp.RESP, highest bool) Resp.RESP {
+	if len(args) > 3 {
+		return syntaxError
+	}
+	count := int64(1)
+	if len(args) == 3 {
+		var errReply Resp.RESP
+		if count, errReply = parseInteger(args[2]); errReply.IsError() || count < 0 {
+			return Resp.NewError("ERR value is out of range, must be positive")
+		}
+	}
+	key := args[1].String()
+	value, errReply := lookupZSet(client, key)
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil || count == 0 {
+		client.rewritePropagation(nil)
+		return Resp.NewArray([]Resp.RESP{})
+	}
+	var entries []zsetEntry
+	for ; count > 0 && value.ZSetLen() > 0; count-- {
+		member, score := value.ZSetPop(highest)
+		entries = append(entries, zsetEntry{member, score})
+	}
+	client.db.Updated(key, value)
+	if len(args) == 2 {
+		return Resp.NewArray([]Resp.RESP{Resp.NewBulk(entries[0].member), Resp.NewDouble(entries[0].score)})
+	}
+	return zsetEntriesReply(client, entries, true)
+}
+
+func bzpopminCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return bzpopGeneric(client, args, false)
+}
+
+func bzpopmaxCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return bzpopGeneric(client, args, true)
+}
+
+// bzpopGeneric implements BZPOPMIN and BZPOPMAX key [key ...] timeout. It
+// pops from the first non empty key and is propagated as ZPOPMIN or
+// ZPOPMAX of that key.
+func bzpopGeneric(client *Client, args []Resp.RESP, highest bool) Resp.RESP {
+	timeout, errReply := parseTimeout(args[len(args)-1])
+	if errReply.IsError() {
+		return errReply
+	}
+	keys := args[1 : len(args)-1]
+	for _, key := range keys {
+		value, errReply := lookupZSet(client, key.String())
+		if errReply.IsError() {
+			return errReply
+		}
+		if value == nil {
+			continue
+		}
+		member, score := value.ZSetPop(highest)
+		client.db.Updated(key.String(), value)
+		command := "ZPOPMIN"
+		if highest {
+			command = "ZPOPMAX"
+		}
+		client.rewritePropagation([]Resp.RESP{Resp.NewBulkString(command), key})
+		return Resp.NewArray([]Resp.RESP{key, Resp.NewBulk(member), Resp.NewDouble(score)})
+	}
+	return blockForKeys(client, respStrings(keys), timeout, Resp.NewNullArray())
+}
+
+// zsetAggregate combines the scores a member has in several inputs.
+type zsetAggregate func(a, b float64) float64
+
+func zsetAggregateSum(a, b float64) float64 {
+	sum := a + b
+	if math.IsNaN(sum) {
+		// inf and -inf add up to 0 as in Redis
+		return 0
+	}
+	return sum
+}
+
+func zunionCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zsetAlgebraGeneric(client, args, "", true)
+}
+
+func zunionstoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zsetAlgebraGeneric(client, args[1:], args[1].String(), true)
+}
+
+func zinterCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zsetAlgebraGeneric(client, args, "", false)
+}
+
+func zinterstoreCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	return zsetAlgebraGeneric(client, args[1:], args[1].String(), false)
+}
+
+// zsetAlgebraGeneric implements the union and intersection of sorted sets,
+// args starting at the argument before numkeys:
+// numkeys key [key ...] [WEIGHTS weight [weight ...]]
+// [AGGREGATE <SUM | MIN | MAX>] [WITHSCORES]. Plain sets are accepted as
+// inputs with a score of 1. The result is stored at dstKey unless empty.
+func zsetAlgebraGeneric(client *Client, args []Resp.RESP, dstKey string, union bool) Resp.RESP {
+	storing := dstKey != ""
+	numKeys, errReply := parseInteger(args[1])
+	if errReply.IsError() {
+		return errReply
+	}
+	if numKeys < 1 {
+		return Resp.NewError("ERR at least 1 input key is needed for '" + client.cmd.Name + "' command")
+	}
+	if numKeys > int64(len(args)-2) {
+		return syntaxError
+	}
+	keys := args[2 : 2+numKeys]
+	weights := make([]float64, numKeys)
+	for i := range weights {
+		weights[i] = 1
+	}
+	aggregate := zsetAggregate(zsetAggregateSum)
+	withScores := false
+	rest := args[2+numKeys:]
+	for i := 0; i < len(rest); i++ {
+		switch option := strings.ToUpper(rest[i].String()); {
+		case option == "WEIGHTS" && int64(len(rest)-i-1) >= numKeys:
+			for j := range weights {
+				weight, ok := parseFloat(rest[i+1+j].Bytes())
+				if !ok {
+					return Resp.NewError("ERR weight value is not a float")
+				}
+				weights[j] = weight
+			}
+			i += int(numKeys)
+		case option == "AGGREGATE" && i+1 < len(rest):
+			switch strings.ToUpper(rest[i+1].String()) {
+			case "SUM":
+				aggregate = zsetAggregateSum
+			case "MIN":
+				aggregate = math.Min
+			case "MAX":
+				aggregate = math.Max
+			default:
+				return syntaxError
+			}
+			i++
+		case option == "WITHSCORES" && !storing:
+			withScores = true
+		default:
+			return syntaxError
+		}
+	}
+	inputs := make([]*store.Value, numKeys)
+	for i, key := range keys {
+		value := client.db.Lookup(key.String())
+		if value != nil && value.Type != store.ObjZSet && value.Type != store.ObjSet {
+			return wrongTypeError
+		}
+		inputs[i] = value
+	}
+	result := store.NewZSet()
+	if union {
+		zsetUnion(result, inputs, weights, aggregate)
+	} else {
+		zsetInter(result, inputs, weights, aggregate)
+	}
+	if storing {
+		zsetStore(client, dstKey, result)
+		return Resp.NewInteger(int64(result.ZSetLen()))
+	}
+	return zsetEntriesReply(client, zsetEntries(result), withScores)
+}
+
+// weightedScore multiplies a score by the weight of its input, where inf
+// times 0 makes 0.
+func weightedScore(score, weight float64) float64 {
+	score *= weight
+	if math.IsNaN(score) {
+		return 0
+	}
+	return score
+}
+
+func zsetUnion(result *store.Value, inputs []*store.Value, weights []float64, aggregate zsetAggregate) {
+	for i, input := range inputs {
+		if input == nil {
+			continue
+		}
+		zsetInputRange(input, func(member []byte, score float64) bool {
+			score = weightedScore(score, weights[i])
+			if current, ok := result.ZSetScore(member); ok {
+				score = aggregate(current, score)
+			}
+			result.ZSetAdd(member, score)
+			return true
+		})
+	}
+}
+
+func zsetInter(result *store.Value, inputs []*store.Value, weights []float64, aggregate zsetAggregate) {
+	order := make([]int, len(inputs))
+	for i, input := range inputs {
+		if input == nil {
+			return
+		}
+		order[i] = i
+	}
+	// walk the smallest input and look the members up in the others
+	sort.Slice(order, func(i, j int) bool { return zsetInputLen(inputs[order[i]]) < zsetInputLen(inputs[order[j]]) })
+	first := order[0]
+	zsetInputRange(inputs[first], func(member []byte, score float64) bool {
+		score = weightedScore(score, weights[first])
+		for _, i := range order[1:] {
+			other, ok := zsetInputScore(inputs[i], member)
+			if !ok {
+				return true
+			}
+			score = aggregate(score, weightedScore(other, weights[i]))
+		}
+		result.ZSetAdd(member, score)
+		return true
+	})
+}
+
+// zsetInputRange calls fn for the members of an input of ZUNION or ZINTER,
+// a sorted set or a set whose members score 1.
+func zsetInputRange(input *store.Value, fn func(member []byte, score float64) bool) {
+	if input.Type == store.ObjSet {
+		input.SetRange(func(member []byte) bool { return fn(member, 1) })
+		return
+	}
+	input.ZSetRangeByRank(0, input.ZSetLen()-1, false, fn)
+}
+
+func zsetInputScore(input *store.Value, member []byte) (float64, bool) {
+	if input.Type == store.ObjSet {
+		return 1, input.SetIsMember(member)
+	}
+	return input.ZSetScore(member)
+}
+
+func zsetInputLen(input *store.Value) int {
+	if input.Type == store.ObjSet {
+		return input.SetLen()
+	}
+	return input.ZSetLen()
+}
+
+// zscanCommand implements ZSCAN key cursor [MATCH pattern] [COUNT count].
+func zscanCommand(client *Client, args []Resp.RESP) Resp.RESP {
+	cursor, errReply := parseScanCursor(args[2])
+	if errReply.IsError() {
+		return errReply
+	}
+	opts, errReply := parseScanOptions(args[3:], false)
+	if errReply.IsError() {
+		return errReply
+	}
+	value, errReply := lookupZSet(client, args[1].String())
+	if errReply.IsError() {
+		return errReply
+	}
+	if value == nil {
+		return Resp.NewArray([]Resp.RESP{Resp.NewBulkString("0"), Resp.NewArray([]Resp.RESP{})})
+	}
+	return scanGeneric(cursor, opts, true, func(cursor uint64, fn func(element, value []byte)) uint64 {
+		return value.ZSetScan(cursor, func(member []byte, score float64) {
+			fn(member, Resp.AppendDouble(nil, score))
+		})
+	})
+}
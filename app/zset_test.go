@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestScoreRepliesOnRESP2(t *testing.T) {
+	client := newTestClient(newTestDB(t))
+	run(client, "ZADD", "z", "1234567", "a", "1700000000", "b", "1.5", "c", "0.00001", "d")
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ZSCORE", "z", "a"}, "$7\r\n1234567\r\n"},
+		{[]string{"ZSCORE", "z", "b"}, "$10\r\n1700000000\r\n"},
+		{[]string{"ZSCORE", "z", "d"}, "$7\r\n0.00001\r\n"},
+		{[]string{"ZRANGE", "z", "1", "-1", "WITHSCORES"}, "*6\r\n" +
+			"$1\r\nc\r\n$3\r\n1.5\r\n" +
+			"$1\r\na\r\n$7\r\n1234567\r\n" +
+			"$1\r\nb\r\n$10\r\n1700000000\r\n"},
+		{[]string{"ZADD", "z", "INCR", "1", "b"}, "$10\r\n1700000001\r\n"},
+	} {
+		reply := run(client, tt.args...)
+		if got := reply.SerializeAs(2); got != tt.want {
+			t.Errorf("%v: expected %q, got %q", tt.args, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package store
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Bitmaps are strings addressed bit by bit, bit 0 being the most
+// significant bit of the first byte. Bits past the end of a string read
+// as 0 and writing them grows the string with zero bytes. The functions
+// writing bits modify the slice in place, so callers pass the bytes of a
+// stored value obtained from Value.Unshare.
+
+// growBits returns b grown with zero bytes to hold the bit at offset.
+func growBits(b []byte, offset uint64) []byte {
+	if n := offset/8 + 1; uint64(len(b)) < n {
+		b = append(b, make([]byte, n-uint64(len(b)))...)
+	}
+	return b
+}
+
+// GetBit returns the bit at offset.
+func GetBit(b []byte, offset uint64) int {
+	if offset/8 >= uint64(len(b)) {
+		return 0
+	}
+	return int(b[offset/8]>>(7-offset%8)) & 1
+}
+
+// SetBit sets the bit at offset to bit and returns the grown slice and
+// the previous bit.
+func SetBit(b []byte, offset uint64, bit int) ([]byte, int) {
+	b = growBits(b, offset)
+	old := GetBit(b, offset)
+	mask := byte(1) << (7 - offset%8)
+	if bit == 0 {
+		b[offset/8] &^= mask
+	} else {
+		b[offset/8] |= mask
+	}
+	return b, old
+}
+
+// BitCount returns the number of bits set from bit start to bit end
+// included. The bits must be within b.
+func BitCount(b []byte, start, end uint64) int64 {
+	if end < start {
+		return 0
+	}
+	firstByte, lastByte := start/8, end/8
+	// the bits of the first and last bytes outside of the range
+	headMask := byte(0xff) >> (start % 8)
+	tailMask := byte(0xff) << (7 - end%8)
+	if firstByte == lastByte {
+		return int64(bits.OnesCount8(b[firstByte] & headMask & tailMask))
+	}
+	count := bits.OnesCount8(b[firstByte]&headMask) + bits.OnesCount8(b[lastByte]&tailMask)
+	for _, c := range b[firstByte+1 : lastByte] {
+		count += bits.OnesCount8(c)
+	}
+	return int64(count)
+}
+
+// BitPos returns the position of the first bit equal to bit from bit
+// start to bit end included, or -1 if there is none. The bits must be
+// within b.
+func BitPos(b []byte, bit int, start, end uint64) int64 {
+	// skip the whole bytes that can't hold the bit
+	skip := byte(0)
+	if bit == 0 {
+		skip = 0xff
+	}
+	for pos := start; pos <= end; {
+		if pos%8 == 0 && pos+7 <= end && b[pos/8] == skip {
+			pos += 8
+			continue
+		}
+		if GetBit(b, pos) == bit {
+			return int64(pos)
+		}
+		pos++
+	}
+	return -1
+}
+
+// BitOp is an operation of BITOP.
+type BitOp int
+
+const (
+	BitAnd BitOp = iota
+	BitOr
+	BitXor
+	BitNot
+)
+
+// BitOperation returns the result of op over the strings srcs, the
+// shorter ones padded with zero bytes. BitNot takes a single string.
+func BitOperation(op BitOp, srcs [][]byte) []byte {
+	size := 0
+	for _, src := range srcs {
+		if len(src) > size {
+			size = len(src)
+		}
+	}
+	dst := make([]byte, size)
+	if op == BitNot {
+		for i, c := range srcs[0] {
+			dst[i] = ^c
+		}
+		return dst
+	}
+	for i := range dst {
+		var result byte
+		for j, src := range srcs {
+			var c byte
+			if i < len(src) {
+				c = src[i]
+			}
+			switch {
+			case j == 0:
+				result = c
+			case op == BitAnd:
+				result &= c
+			case op == BitOr:
+				result |= c
+			case op == BitXor:
+				result ^= c
+			}
+		}
+		dst[i] = result
+	}
+	return dst
+}
+
+// BitfieldType is the type of a BITFIELD integer: signed fields have up
+// to 64 bits and unsigned ones up to 63 so they always fit an int64.
+type BitfieldType struct {
+	Signed bool
+	Bits   uint
+}
+
+// GetBitfield returns the integer of type t stored from bit offset on.
+func GetBitfield(b []byte, offset uint64, t BitfieldType) int64 {
+	var u uint64
+	for i := uint64(0); i < uint64(t.Bits); i++ {
+		u = u<<1 | uint64(GetBit(b, offset+i))
+	}
+	if t.Signed && t.Bits < 64 && u&(1<<(t.Bits-1)) != 0 {
+		// sign extend
+		u |= math.MaxUint64 << t.Bits
+	}
+	return int64(u)
+}
+
+// SetBitfield stores the integer n as type t from bit offset on, keeping
+// its t.Bits least significant bits, and returns the grown slice.
+func SetBitfield(b []byte, offset uint64, t BitfieldType, n int64) []byte {
+	b = growBits(b, offset+uint64(t.Bits)-1)
+	u := uint64(n)
+	for i := uint64(0); i < uint64(t.Bits); i++ {
+		b, _ = SetBit(b, offset+i, int(u>>(uint64(t.Bits)-1-i))&1)
+	}
+	return b
+}
+
+// BitfieldOverflow is how BITFIELD handles results that don't fit their
+// type.
+type BitfieldOverflow int
+
+const (
+	// OverflowWrap keeps the least significant bits, like two's
+	// complement arithmetic
+	OverflowWrap BitfieldOverflow = iota
+	// OverflowSat saturates to the smallest or largest value of the type
+	OverflowSat
+	// OverflowFail refuses the write
+	OverflowFail
+)
+
+// BitfieldAdd returns n+incr as stored in a field of type t under the
+// overflow policy, and reports false if the sum overflows the type and
+// the policy is OverflowFail. Setting a value is adding it to 0.
+func BitfieldAdd(n, incr int64, t BitfieldType, overflow BitfieldOverflow) (int64, bool) {
+	var min, max int64
+	if t.Signed {
+		max = math.MaxInt64 >> (64 - t.Bits)
+		min = -max - 1
+	} else {
+		max = math.MaxInt64 >> (63 - t.Bits)
+	}
+	sum := n + incr
+	switch {
+	case incr > 0 && (sum < n || sum > max):
+		sum = max
+	case incr < 0 && (sum > n || sum < min):
+		sum = min
+	default:
+		return sum, true
+	}
+	switch overflow {
+	case OverflowSat:
+		return sum, true
+	case OverflowFail:
+		return 0, false
+	}
+	// wrap to the t.Bits least significant bits of the exact sum
+	u := uint64(n) + uint64(incr)
+	if t.Bits < 64 {
+		u &= 1<<t.Bits - 1
+		if t.Signed && u&(1<<(t.Bits-1)) != 0 {
+			u |= math.MaxUint64 << t.Bits
+		}
+	}
+	return int64(u), true
+}
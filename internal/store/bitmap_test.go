@@ -0,0 +1,82 @@
+package store
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBits(t *testing.T) {
+	b, old := SetBit(nil, 7, 1)
+	if len(b) != 1 || b[0] != 1 || old != 0 {
+		t.Fatalf("Expected a single byte 0x01, got %x", b)
+	}
+	b, _ = SetBit(b, 17, 1)
+	if len(b) != 3 || GetBit(b, 17) != 1 || GetBit(b, 100) != 0 {
+		t.Errorf("Expected the string to grow to 3 bytes with bit 17 set, got %x", b)
+	}
+	if b, old = SetBit(b, 7, 0); old != 1 || b[0] != 0 {
+		t.Errorf("Expected bit 7 to be cleared, got %x", b)
+	}
+	b = []byte{0xff, 0xf0, 0x00}
+	if n := BitCount(b, 0, 23); n != 12 {
+		t.Errorf("Expected 12 bits set, got %d", n)
+	}
+	if n := BitCount(b, 4, 9); n != 6 {
+		t.Errorf("Expected 6 bits set from bit 4 to 9, got %d", n)
+	}
+	if pos := BitPos(b, 0, 0, 23); pos != 12 {
+		t.Errorf("Expected the first clear bit at 12, got %d", pos)
+	}
+	if pos := BitPos(b, 1, 16, 23); pos != -1 {
+		t.Errorf("Expected no set bit in the last byte, got %d", pos)
+	}
+	if got := BitOperation(BitAnd, [][]byte{{0xff, 0x0f}, {0x0f}}); len(got) != 2 || got[0] != 0x0f || got[1] != 0 {
+		t.Errorf("Expected AND to pad with zeros, got %x", got)
+	}
+	if got := BitOperation(BitNot, [][]byte{{0x0f}}); got[0] != 0xf0 {
+		t.Errorf("Expected NOT to invert, got %x", got)
+	}
+}
+
+func TestBitfields(t *testing.T) {
+	i8, u4 := BitfieldType{Signed: true, Bits: 8}, BitfieldType{Bits: 4}
+	b := SetBitfield(nil, 4, i8, -2)
+	if len(b) != 2 || b[0] != 0x0f || b[1] != 0xe0 {
+		t.Errorf("Expected -2 to span two bytes, got %x", b)
+	}
+	if n := GetBitfield(b, 4, i8); n != -2 {
+		t.Errorf("Expected -2 back, got %d", n)
+	}
+	if n := GetBitfield(b, 4, u4); n != 15 {
+		t.Errorf("Expected 15 as an unsigned nibble, got %d", n)
+	}
+	i64 := BitfieldType{Signed: true, Bits: 64}
+	if n := GetBitfield(SetBitfield(nil, 3, i64, math.MinInt64), 3, i64); n != math.MinInt64 {
+		t.Errorf("Expected the smallest int64 back, got %d", n)
+	}
+
+	for _, tc := range []struct {
+		n, incr  int64
+		typ      BitfieldType
+		overflow BitfieldOverflow
+		want     int64
+		ok       bool
+	}{
+		{100, 100, i8, OverflowWrap, -56, true},
+		{100, 100, i8, OverflowSat, 127, true},
+		{-100, -100, i8, OverflowSat, -128, true},
+		{100, 100, i8, OverflowFail, 0, false},
+		{14, 3, u4, OverflowWrap, 1, true},
+		{1, -3, u4, OverflowWrap, 14, true},
+		{1, -3, u4, OverflowSat, 0, true},
+		{0, 300, u4, OverflowSat, 15, true},
+		{math.MaxInt64, 1, i64, OverflowWrap, math.MinInt64, true},
+		{math.MaxInt64, 1, i64, OverflowSat, math.MaxInt64, true},
+		{5, 5, i8, OverflowFail, 10, true},
+	} {
+		got, ok := BitfieldAdd(tc.n, tc.incr, tc.typ, tc.overflow)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("Expected %d+%d as %+v under %d to give %d %v, got %d %v", tc.n, tc.incr, tc.typ, tc.overflow, tc.want, tc.ok, got, ok)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package store
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"math/rand"
+)
+
+// Dict is a hash table with chaining modeled after the dict of Redis. It
+// grows and shrinks in powers of two and rehashes incrementally: while a
+// resize is in progress entries live in two tables and every lookup or
+// update moves one bucket over. Unlike Go maps it can be scanned with a
+// cursor that stays valid across calls and resizes.
+type Dict struct {
+	tables [2]dictTable
+	// rehashIndex is the next bucket of tables[0] to move to tables[1], or
+	// -1 when no rehashing is in progress
+	rehashIndex int
+}
+
+type dictTable struct {
+	buckets []*dictEntry
+	used    int
+}
+
+type dictEntry struct {
+	key   string
+	value interface{}
+	next  *dictEntry
+}
+
+const (
+	dictInitialSize = 4
+	// dictMinFill is the percentage of used buckets under which the table
+	// shrinks
+	dictMinFill = 10
+)
+
+var dictSeed = maphash.MakeSeed()
+
+func NewDict() *Dict {
+	return &Dict{rehashIndex: -1}
+}
+
+func dictHash(key string) uint64 {
+	var h maphash.Hash
+	h.SetSeed(dictSeed)
+	h.WriteString(key)
+	return h.Sum64()
+}
+
+func (t *dictTable) mask() uint64 {
+	return uint64(len(t.buckets) - 1)
+}
+
+// Len returns the number of entries.
+func (d *Dict) Len() int {
+	return d.tables[0].used + d.tables[1].used
+}
+
+func (d *Dict) rehashing() bool {
+	return d.rehashIndex != -1
+}
+
+// find returns the entry of key.
+func (d *Dict) find(key string) *dictEntry {
+	if d.Len() == 0 {
+		return nil
+	}
+	d.rehashStep()
+	h := dictHash(key)
+	for i := range d.tables {
+		t := &d.tables[i]
+		if len(t.buckets) == 0 {
+			continue
+		}
+		for e := t.buckets[h&t.mask()]; e != nil; e = e.next {
+			if e.key == key {
+				return e
+			}
+		}
+		if !d.rehashing() {
+			break
+		}
+	}
+	return nil
+}
+
+// Get returns the value stored for key.
+func (d *Dict) Get(key string) (interface{}, bool) {
+	if e := d.find(key); e != nil {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// Set stores value for key and reports whether the key was added.
+func (d *Dict) Set(key string, value interface{}) bool {
+	if e := d.find(key); e != nil {
+		e.value = value
+		return false
+	}
+	d.expandIfNeeded()
+	// new entries go to the new table while rehashing
+	t := &d.tables[0]
+	if d.rehashing() {
+		t = &d.tables[1]
+	}
+	index := dictHash(key) & t.mask()
+	t.buckets[index] = &dictEntry{key: key, value: value, next: t.buckets[index]}
+	t.used++
+	return true
+}
+
+// Delete removes key and reports whether it was there.
+func (d *Dict) Delete(key string) bool {
+	if d.Len() == 0 {
+		return false
+	}
+	d.rehashStep()
+	h := dictHash(key)
+	for i := range d.tables {
+		t := &d.tables[i]
+		if len(t.buckets) == 0 {
+			continue
+		}
+		index := h & t.mask()
+		var prev *dictEntry
+		for e := t.buckets[index]; e != nil; prev, e = e, e.next {
+			if e.key != key {
+				continue
+			}
+			if prev == nil {
+				t.buckets[index] = e.next
+			} else {
+				prev.next = e.next
+			}
+			t.used--
+			d.shrinkIfNeeded()
+			return true
+		}
+		if !d.rehashing() {
+			break
+		}
+	}
+	return false
+}
+
+func (d *Dict) expandIfNeeded() {
+	if d.rehashing() {
+		return
+	}
+	t := &d.tables[0]
+	if len(t.buckets) == 0 {
+		d.resize(dictInitialSize)
+	} else if t.used >= len(t.buckets) {
+		d.resize(t.used * 2)
+	}
+}
+
+func (d *Dict) shrinkIfNeeded() {
+	t := &d.tables[0]
+	if d.rehashing() || len(t.buckets) <= dictInitialSize || t.used*100/len(t.buckets) >= dictMinFill {
+		return
+	}
+	d.resize(t.used)
+}
+
+// resize starts rehashing into a table of the smallest power of two that
+// holds size entries.
+func (d *Dict) resize(size int) {
+	n := dictInitialSize
+	for n < size {
+		n *= 2
+	}
+	if n == len(d.tables[0].buckets) {
+		return
+	}
+	table := dictTable{buckets: make([]*dictEntry, n)}
+	if d.tables[0].buckets == nil {
+		d.tables[0] = table
+		return
+	}
+	d.tables[1] = table
+	d.rehashIndex = 0
+}
+
+// rehashStep moves one bucket to the new table, skipping up to ten empty
+// ones, so rehashing never pauses for long.
+func (d *Dict) rehashStep() {
+	if !d.rehashing() {
+		return
+	}
+	from, to := &d.tables[0], &d.tables[1]
+	for empty := 0; from.buckets[d.rehashIndex] == nil; d.rehashIndex++ {
+		if empty++; empty > 10 || d.rehashIndex == len(from.buckets)-1 {
+			d.finishRehashIfDone()
+			return
+		}
+	}
+	for e := from.buckets[d.rehashIndex]; e != nil; {
+		next := e.next
+		index := dictHash(e.key) & to.mask()
+		e.next = to.buckets[index]
+		to.buckets[index] = e
+		from.used--
+		to.used++
+		e = next
+	}
+	from.buckets[d.rehashIndex] = nil
+	d.rehashIndex++
+	d.finishRehashIfDone()
+}
+
+func (d *Dict) finishRehashIfDone() {
+	if d.tables[0].used > 0 {
+		return
+	}
+	d.tables[0] = d.tables[1]
+	d.tables[1] = dictTable{}
+	d.rehashIndex = -1
+	// entries may have been deleted in the meantime
+	d.shrinkIfNeeded()
+}
+
+// Range calls fn for every entry until it returns false. fn must not
+// modify the dict.
+func (d *Dict) Range(fn func(key string, value interface{}) bool) {
+	for i := range d.tables {
+		for _, e := range d.tables[i].buckets {
+			for ; e != nil; e = e.next {
+				if !fn(e.key, e.value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// release empties a dict nothing references any more, unlinking its
+// entries one by one and calling fn, when not nil, with their values.
+func (d *Dict) release(fn func(value interface{})) {
+	for i := range d.tables {
+		for j, e := range d.tables[i].buckets {
+			for e != nil {
+				next := e.next
+				if fn != nil {
+					fn(e.value)
+				}
+				e.value, e.next = nil, nil
+				e = next
+			}
+			d.tables[i].buckets[j] = nil
+		}
+		d.tables[i] = dictTable{}
+	}
+	d.rehashIndex = -1
+}
+
+// copy returns a dict with the same entries. The values are shared.
+func (d *Dict) copy() *Dict {
+	dup := NewDict()
+	d.Range(func(key string, value interface{}) bool {
+		dup.Set(key, value)
+		return true
+	})
+	return dup
+}
+
+// Random returns a random entry of a non empty dict.
+func (d *Dict) Random() (string, interface{}) {
+	d.rehashStep()
+	var bucket *dictEntry
+	for bucket == nil {
+		t0, t1 := &d.tables[0], &d.tables[1]
+		if d.rehashing() {
+			// buckets of tables[0] before rehashIndex are empty
+			n := len(t0.buckets) - d.rehashIndex + len(t1.buckets)
+			i := d.rehashIndex + rand.Intn(n)
+			if i < len(t0.buckets) {
+				bucket = t0.buckets[i]
+			} else {
+				bucket = t1.buckets[i-len(t0.buckets)]
+			}
+		} else {
+			bucket = t0.buckets[rand.Intn(len(t0.buckets))]
+		}
+	}
+	chain := 0
+	for e := bucket; e != nil; e = e.next {
+		chain++
+	}
+	e := bucket
+	for i := rand.Intn(chain); i > 0; i-- {
+		e = e.next
+	}
+	return e.key, e.value
+}
+
+// Scan calls fn for the entries of the buckets at cursor and returns the
+// cursor to continue with, 0 once the scan is complete. The cursor is
+// incremented on its reversed bits, so when the table grows or shrinks
+// between calls the buckets already visited map to cursors already
+// passed: every entry present for the whole scan is returned at least
+// once, though some may be returned more than once.
+func (d *Dict) Scan(cursor uint64, fn func(key string, value interface{})) uint64 {
+	if d.Len() == 0 {
+		return 0
+	}
+	emit := func(e *dictEntry) {
+		for ; e != nil; e = e.next {
+			fn(e.key, e.value)
+		}
+	}
+	t0 := &d.tables[0]
+	if !d.rehashing() {
+		m0 := t0.mask()
+		emit(t0.buckets[cursor&m0])
+		return nextCursor(cursor, m0)
+	}
+	t1 := &d.tables[1]
+	if len(t0.buckets) > len(t1.buckets) {
+		t0, t1 = t1, t0
+	}
+	m0, m1 := t0.mask(), t1.mask()
+	emit(t0.buckets[cursor&m0])
+	// visit the buckets of the larger table that the bucket of the smaller
+	// one expands to
+	for {
+		emit(t1.buckets[cursor&m1])
+		cursor = nextCursor(cursor, m1)
+		if cursor&(m0^m1) == 0 {
+			return cursor
+		}
+	}
+}
+
+// nextCursor increments the bits of cursor covered by mask in reverse
+// order.
+func nextCursor(cursor, mask uint64) uint64 {
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	return bits.Reverse64(cursor)
+}
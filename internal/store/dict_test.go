@@ -0,0 +1,91 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestDictGrowsAndShrinks(t *testing.T) {
+	d := NewDict()
+	for i := 0; i < 1000; i++ {
+		if !d.Set(strconv.Itoa(i), i) {
+			t.Fatalf("Expected key %d to be added", i)
+		}
+	}
+	if d.Set("10", -10) {
+		t.Errorf("Expected an existing key to be replaced")
+	}
+	if value, ok := d.Get("10"); !ok || value.(int) != -10 {
+		t.Errorf("Expected -10, got %v", value)
+	}
+	for i := 0; i < 990; i++ {
+		if !d.Delete(strconv.Itoa(i)) {
+			t.Fatalf("Expected key %d to be deleted", i)
+		}
+	}
+	if d.Len() != 10 {
+		t.Errorf("Expected 10 keys, got %d", d.Len())
+	}
+	for d.rehashing() {
+		d.rehashStep()
+	}
+	if size := len(d.tables[0].buckets); size > 16 {
+		t.Errorf("Expected the table to shrink, got %d buckets", size)
+	}
+	for i := 990; i < 1000; i++ {
+		if _, ok := d.Get(strconv.Itoa(i)); !ok {
+			t.Errorf("Expected key %d to be kept", i)
+		}
+	}
+}
+
+func TestDictScanAcrossResizes(t *testing.T) {
+	d := NewDict()
+	for i := 0; i < 100; i++ {
+		d.Set("keep:"+strconv.Itoa(i), nil)
+	}
+	seen := map[string]bool{}
+	cursor := uint64(0)
+	for calls := 0; ; calls++ {
+		cursor = d.Scan(cursor, func(key string, value interface{}) {
+			seen[key] = true
+		})
+		// grow and shrink the table while the scan is in progress
+		switch calls {
+		case 5:
+			for i := 0; i < 1000; i++ {
+				d.Set("tmp:"+strconv.Itoa(i), nil)
+			}
+		case 40:
+			for i := 0; i < 1000; i++ {
+				d.Delete("tmp:" + strconv.Itoa(i))
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if !seen["keep:"+strconv.Itoa(i)] {
+			t.Errorf("Expected keep:%d to be returned by the scan", i)
+		}
+	}
+}
+
+func TestDictRandom(t *testing.T) {
+	d := NewDict()
+	for i := 0; i < 50; i++ {
+		d.Set(strconv.Itoa(i), i)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 2000; i++ {
+		key, value := d.Random()
+		if strconv.Itoa(value.(int)) != key {
+			t.Fatalf("Expected the value of %s, got %v", key, value)
+		}
+		seen[key] = true
+	}
+	if len(seen) < 40 {
+		t.Errorf("Expected random keys to cover the dict, got %d of 50", len(seen))
+	}
+}
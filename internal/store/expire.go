@@ -0,0 +1,104 @@
+package store
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultHz = 10
+	MinHz     = 1
+	MaxHz     = 500
+
+	// activeExpireKeysPerLoop is how many keys with a time to live are
+	// sampled on each iteration of the active expire cycle
+	activeExpireKeysPerLoop = 20
+	// activeExpireAcceptableStale is the percentage of expired keys in a
+	// sample under which the cycle stops, assuming few are left
+	activeExpireAcceptableStale = 25
+	// activeExpireCycleTimePerc is the share of CPU time, in percent, the
+	// cycle may use
+	activeExpireCycleTimePerc = 25
+)
+
+// Hz returns how many times per second the active expire cycle runs.
+func (k *Store) Hz() int {
+	return int(atomic.LoadInt32(&k.hz))
+}
+
+// SetHz changes the frequency of the active expire cycle, clamped to the
+// range Redis accepts.
+func (k *Store) SetHz(hz int) {
+	if hz < MinHz {
+		hz = MinHz
+	}
+	if hz > MaxHz {
+		hz = MaxHz
+	}
+	atomic.StoreInt32(&k.hz, int32(hz))
+}
+
+// StartActiveExpire runs the active expire cycle in the background, so
+// keys that are never read again still get deleted once they expire.
+func (k *Store) StartActiveExpire() {
+	go func() {
+		for {
+			time.Sleep(time.Second / time.Duration(k.Hz()))
+			k.activeExpireCycle()
+		}
+	}()
+}
+
+// activeExpireCycle is the adaptive algorithm of Redis: it samples keys
+// with a time to live and deletes the expired ones, and keeps going while
+// more than a quarter of the sample was expired, as long as it stays within
+// its share of CPU time.
+func (k *Store) activeExpireCycle() {
+	start := time.Now()
+	timeLimit := time.Second * activeExpireCycleTimePerc / 100 / time.Duration(k.Hz())
+	for iteration := 1; ; iteration++ {
+		sampled, expired := k.expireSample(activeExpireKeysPerLoop)
+		if sampled == 0 || expired*100 <= sampled*activeExpireAcceptableStale {
+			return
+		}
+		// checking the clock is not free, do it every 16 iterations
+		if iteration%16 == 0 && time.Since(start) > timeLimit {
+			return
+		}
+	}
+}
+
+// expireSample looks at up to count keys with a time to live and deletes
+// those that expired. Ranging over a Go map starts at a random position
+// each time, so consecutive calls sample different keys.
+func (k *Store) expireSample(count int) (sampled int, expired int) {
+	k.Lock()
+	defer k.Unlock()
+	if len(k.expires) == 0 {
+		k.avgTTL = 0
+		return 0, 0
+	}
+	now := Now()
+	var ttlSum int64
+	for key, value := range k.expires {
+		if sampled == count {
+			break
+		}
+		sampled++
+		if k.expireIfNeeded(key, value) {
+			expired++
+		} else {
+			ttlSum += value.ExpireAt - now
+		}
+	}
+	if live := int64(sampled - expired); live > 0 {
+		// a running average giving each sample a small weight, like Redis
+		avg := ttlSum / live
+		if k.avgTTL == 0 {
+			k.avgTTL = avg
+		} else {
+			k.avgTTL = k.avgTTL/50*49 + avg/50
+		}
+	}
+	return sampled, expired
+}
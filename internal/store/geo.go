@@ -0,0 +1,302 @@
+package store
+
+import (
+	"math"
+)
+
+// Geo indexes are sorted sets whose scores are 52 bits geohashes, like in
+// Redis: the longitude and the latitude are each quantized to 26 bits
+// and interleaved, the latitude in the even bits. Latitudes are limited
+// to the range of the Web Mercator projection. Searches look up the
+// geohash box holding the center of the shape and its 8 neighbours at a
+// precision where they cover the shape, then filter the members by
+// distance.
+const (
+	GeoLonMin = -180.0
+	GeoLonMax = 180.0
+	GeoLatMin = -85.05112878
+	GeoLatMax = 85.05112878
+
+	geoStepMax = 26
+	// earthRadius is the radius of the earth in meters Redis computes
+	// distances with
+	earthRadius = 6372797.560856
+	// mercatorMax is the half circumference of the earth in meters
+	mercatorMax = 20037726.37
+)
+
+// geoHash is a geohash of step bits per coordinate.
+type geoHash struct {
+	bits uint64
+	step uint
+}
+
+// geoArea is the box of coordinates a geohash stands for.
+type geoArea struct {
+	lonMin, lonMax, latMin, latMax float64
+}
+
+// interleave returns the bits of x in the even bits and those of y in the
+// odd bits.
+func interleave(x, y uint32) uint64 {
+	var bits uint64
+	for i := uint(0); i < 32; i++ {
+		bits |= uint64(x>>i&1)<<(2*i) | uint64(y>>i&1)<<(2*i+1)
+	}
+	return bits
+}
+
+// deinterleave is the inverse of interleave.
+func deinterleave(bits uint64) (x, y uint32) {
+	for i := uint(0); i < 32; i++ {
+		x |= uint32(bits>>(2*i)&1) << i
+		y |= uint32(bits>>(2*i+1)&1) << i
+	}
+	return x, y
+}
+
+// geoEncode returns the geohash of the point within the latitude range
+// latMin..latMax.
+func geoEncode(lon, lat, latMin, latMax float64, step uint) geoHash {
+	latOffset := (lat - latMin) / (latMax - latMin)
+	lonOffset := (lon - GeoLonMin) / (GeoLonMax - GeoLonMin)
+	latOffset *= float64(uint64(1) << step)
+	lonOffset *= float64(uint64(1) << step)
+	return geoHash{interleave(uint32(latOffset), uint32(lonOffset)), step}
+}
+
+func (h geoHash) area() geoArea {
+	lat, lon := deinterleave(h.bits)
+	scale := float64(uint64(1) << h.step)
+	return geoArea{
+		lonMin: GeoLonMin + (float64(lon)/scale)*(GeoLonMax-GeoLonMin),
+		lonMax: GeoLonMin + (float64(lon+1)/scale)*(GeoLonMax-GeoLonMin),
+		latMin: GeoLatMin + (float64(lat)/scale)*(GeoLatMax-GeoLatMin),
+		latMax: GeoLatMin + (float64(lat+1)/scale)*(GeoLatMax-GeoLatMin),
+	}
+}
+
+// move returns the geohash dx boxes east and dy boxes north of h, dx and
+// dy being -1, 0 or 1. Moving wraps around the ranges.
+func (h geoHash) move(dx, dy int) geoHash {
+	const even, odd = 0x5555555555555555, 0xaaaaaaaaaaaaaaaa
+	shift := 64 - h.step*2
+	x, y := h.bits&odd, h.bits&even
+	if zz := uint64(even) >> shift; dx > 0 {
+		x += zz + 1
+	} else if dx < 0 {
+		x = (x | zz) - (zz + 1)
+	}
+	if zz := uint64(odd) >> shift; dy > 0 {
+		y += zz + 1
+	} else if dy < 0 {
+		y = (y | zz) - (zz + 1)
+	}
+	return geoHash{x&(odd>>shift) | y&(even>>shift), h.step}
+}
+
+// scoreRange returns the scores of the points within the box of h.
+func (h geoHash) scoreRange() ScoreRange {
+	shift := 52 - h.step*2
+	return ScoreRange{Min: float64(h.bits << shift), Max: float64((h.bits + 1) << shift), MaxEx: true}
+}
+
+// GeoValid reports whether the point can be indexed.
+func GeoValid(lon, lat float64) bool {
+	return lon >= GeoLonMin && lon <= GeoLonMax && lat >= GeoLatMin && lat <= GeoLatMax
+}
+
+// GeoEncode returns the score indexing the point, which must be valid.
+func GeoEncode(lon, lat float64) float64 {
+	return float64(geoEncode(lon, lat, GeoLatMin, GeoLatMax, geoStepMax).bits)
+}
+
+// GeoDecode returns the point at the center of the box of a score.
+func GeoDecode(score float64) (lon, lat float64) {
+	area := geoHash{uint64(score), geoStepMax}.area()
+	lon = math.Max(GeoLonMin, math.Min(GeoLonMax, (area.lonMin+area.lonMax)/2))
+	lat = math.Max(GeoLatMin, math.Min(GeoLatMax, (area.latMin+area.latMax)/2))
+	return lon, lat
+}
+
+// GeoHashString returns the standard 11 characters geohash of the point
+// indexed by score. Unlike scores, it is computed over latitudes from -90
+// to 90.
+func GeoHashString(score float64) string {
+	const alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+	lon, lat := GeoDecode(score)
+	bits := geoEncode(lon, lat, -90, 90, geoStepMax).bits
+	buf := make([]byte, 11)
+	for i := range buf {
+		// the 52 bits only fill 10 characters
+		idx := uint64(0)
+		if i < 10 {
+			idx = bits >> uint(52-(i+1)*5) & 0x1f
+		}
+		buf[i] = alphabet[idx]
+	}
+	return string(buf)
+}
+
+func degToRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func radToDeg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+func geoLatDistance(lat1, lat2 float64) float64 {
+	return earthRadius * math.Abs(degToRad(lat2)-degToRad(lat1))
+}
+
+// GeoDistance returns the distance in meters between two points with the
+// haversine formula.
+func GeoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	v := math.Sin((degToRad(lon2) - degToRad(lon1)) / 2)
+	if v == 0 {
+		return geoLatDistance(lat1, lat2)
+	}
+	lat1r, lat2r := degToRad(lat1), degToRad(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	a := u*u + math.Cos(lat1r)*math.Cos(lat2r)*v*v
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// GeoShape is the area of a geo search: a circle of Radius meters, or a
+// box of Width by Height meters with Box, around the center Lon, Lat.
+type GeoShape struct {
+	Lon, Lat      float64
+	Radius        float64
+	Box           bool
+	Width, Height float64
+}
+
+// distance returns the distance in meters from the center of the shape
+// to the point, and reports whether the point is within the shape.
+func (s GeoShape) distance(lon, lat float64) (float64, bool) {
+	if !s.Box {
+		d := GeoDistance(s.Lon, s.Lat, lon, lat)
+		return d, d <= s.Radius
+	}
+	// the latitude distance is cheaper, so it is checked first
+	if geoLatDistance(lat, s.Lat) > s.Height/2 || GeoDistance(lon, lat, s.Lon, lat) > s.Width/2 {
+		return 0, false
+	}
+	return GeoDistance(s.Lon, s.Lat, lon, lat), true
+}
+
+// bounds returns the box of coordinates enclosing the shape.
+func (s GeoShape) bounds() geoArea {
+	width, height := s.Radius, s.Radius
+	if s.Box {
+		width, height = s.Width/2, s.Height/2
+	}
+	latDelta := radToDeg(height / earthRadius)
+	lonDeltaTop := radToDeg(width / earthRadius / math.Cos(degToRad(s.Lat+latDelta)))
+	lonDeltaBottom := radToDeg(width / earthRadius / math.Cos(degToRad(s.Lat-latDelta)))
+	// a distance spans the most longitude on the side nearest to the pole,
+	// where cos(lat) is smallest
+	lonDelta := lonDeltaTop
+	if s.Lat < 0 {
+		lonDelta = lonDeltaBottom
+	}
+	return geoArea{s.Lon - lonDelta, s.Lon + lonDelta, s.Lat - latDelta, s.Lat + latDelta}
+}
+
+// geoSteps estimates the precision of the geohash boxes covering a
+// distance of meters around latitude lat.
+func geoSteps(meters, lat float64) uint {
+	if meters == 0 {
+		return geoStepMax
+	}
+	step := 1
+	for ; meters < mercatorMax; meters *= 2 {
+		step++
+	}
+	// make sure the distance fits most of the time
+	step -= 2
+	// boxes narrow towards the poles
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	if step < 1 {
+		step = 1
+	}
+	if step > geoStepMax {
+		step = geoStepMax
+	}
+	return uint(step)
+}
+
+// boxes returns the geohash boxes to look into for the points within the
+// shape: the box of its center first, then its neighbours overlapping the
+// shape.
+func (s GeoShape) boxes() []geoHash {
+	bounds := s.bounds()
+	meters := s.Radius
+	if s.Box {
+		meters = math.Sqrt(s.Width/2*s.Width/2 + s.Height/2*s.Height/2)
+	}
+	step := geoSteps(meters, s.Lat)
+	center := geoEncode(s.Lon, s.Lat, GeoLatMin, GeoLatMax, step)
+	// near the edges of the center box a neighbour may not reach as far
+	// as the shape, so the boxes are made larger
+	if step > 1 {
+		north, south := center.move(0, 1).area(), center.move(0, -1).area()
+		east, west := center.move(1, 0).area(), center.move(-1, 0).area()
+		if north.latMax < bounds.latMax || south.latMin > bounds.latMin ||
+			east.lonMax < bounds.lonMax || west.lonMin > bounds.lonMin {
+			step--
+			center = geoEncode(s.Lon, s.Lat, GeoLatMin, GeoLatMax, step)
+		}
+	}
+	area := center.area()
+	boxes := []geoHash{center}
+	for _, d := range [][2]int{{0, 1}, {0, -1}, {1, 0}, {-1, 0}, {1, 1}, {-1, 1}, {1, -1}, {-1, -1}} {
+		// skip the neighbours on sides the shape doesn't reach
+		if step >= 2 && ((d[1] < 0 && area.latMin < bounds.latMin) || (d[1] > 0 && area.latMax > bounds.latMax) ||
+			(d[0] < 0 && area.lonMin < bounds.lonMin) || (d[0] > 0 && area.lonMax > bounds.lonMax)) {
+			continue
+		}
+		boxes = append(boxes, center.move(d[0], d[1]))
+	}
+	return boxes
+}
+
+// GeoPoint is a member of a geo index found by a search, with its
+// coordinates and its distance in meters to the center of the search.
+type GeoPoint struct {
+	Member   []byte
+	Score    float64
+	Lon, Lat float64
+	Dist     float64
+}
+
+// GeoSearch returns the members of the geo index v within the shape,
+// stopping after limit of them unless limit is 0.
+func (v *Value) GeoSearch(shape GeoShape, limit int) []GeoPoint {
+	var points []GeoPoint
+	var last geoHash
+	for i, box := range shape.boxes() {
+		if limit > 0 && len(points) >= limit {
+			break
+		}
+		// with huge shapes adjacent neighbours can be the same box
+		if i > 0 && box == last {
+			continue
+		}
+		last = box
+		v.ZSetRangeByScore(box.scoreRange(), false, func(member []byte, score float64) bool {
+			lon, lat := GeoDecode(score)
+			if dist, ok := shape.distance(lon, lat); ok {
+				points = append(points, GeoPoint{member, score, lon, lat, dist})
+			}
+			return limit == 0 || len(points) < limit
+		})
+	}
+	return points
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeohash(t *testing.T) {
+	score := GeoEncode(13.361389, 38.115556)
+	if score != 3479099956230698 {
+		t.Errorf("Expected the Redis score of Palermo, got %.0f", score)
+	}
+	lon, lat := GeoDecode(score)
+	if math.Abs(lon-13.361389) > 1e-5 || math.Abs(lat-38.115556) > 1e-5 {
+		t.Errorf("Expected Palermo back, got %v,%v", lon, lat)
+	}
+	if s := GeoHashString(score); s != "sqc8b49rny0" {
+		t.Errorf("Expected the geohash of Palermo, got %s", s)
+	}
+	lon2, lat2 := GeoDecode(GeoEncode(15.087269, 37.502669))
+	d := GeoDistance(lon, lat, lon2, lat2)
+	if math.Abs(d-166274.1516) > 0.01 {
+		t.Errorf("Expected 166274.1516 meters between Palermo and Catania, got %.4f", d)
+	}
+	if GeoValid(0, 86) || GeoValid(-181, 0) || !GeoValid(180, GeoLatMax) {
+		t.Errorf("Expected the latitudes to be limited to the Mercator range")
+	}
+	h := geoEncode(0, 0, GeoLatMin, GeoLatMax, 3)
+	if got := h.move(1, 0).move(-1, 0).move(0, -1).move(0, 1); got != h {
+		t.Errorf("Expected moving back and forth to return to %b, got %b", h.bits, got.bits)
+	}
+}
+
+func TestGeoSearch(t *testing.T) {
+	v := NewZSet()
+	cities := map[string][2]float64{
+		"Palermo": {13.361389, 38.115556},
+		"Catania": {15.087269, 37.502669},
+		"Rome":    {12.496366, 41.902782},
+		"Paris":   {2.352222, 48.856614},
+	}
+	for name, p := range cities {
+		v.ZSetAdd([]byte(name), GeoEncode(p[0], p[1]))
+	}
+	found := func(points []GeoPoint) map[string]bool {
+		names := make(map[string]bool)
+		for _, p := range points {
+			names[string(p.Member)] = true
+		}
+		return names
+	}
+	points := v.GeoSearch(GeoShape{Lon: 15, Lat: 37, Radius: 200000}, 0)
+	if names := found(points); len(names) != 2 || !names["Palermo"] || !names["Catania"] {
+		t.Errorf("Expected Palermo and Catania within 200 km, got %v", names)
+	}
+	points = v.GeoSearch(GeoShape{Lon: 15, Lat: 37, Box: true, Width: 400000, Height: 400000}, 0)
+	if names := found(points); len(names) != 2 {
+		t.Errorf("Expected Palermo and Catania within the box, got %v", names)
+	}
+	points = v.GeoSearch(GeoShape{Lon: 15, Lat: 37, Radius: 2000000}, 0)
+	if len(points) != 4 {
+		t.Errorf("Expected every city within 2000 km, got %v", found(points))
+	}
+	if points = v.GeoSearch(GeoShape{Lon: 15, Lat: 37, Radius: 2000000}, 1); len(points) != 1 {
+		t.Errorf("Expected the search to stop after 1 city, got %d", len(points))
+	}
+}
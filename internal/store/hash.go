@@ -0,0 +1,151 @@
+package store
+
+import (
+	"bytes"
+	"math/rand"
+)
+
+// Small hashes are kept in a listpack: a flat slice alternating fields and
+// values, searched linearly, which is compact and fast at that size. Past
+// hash-max-listpack-entries fields or hash-max-listpack-value bytes the
+// hash converts to a Dict and never goes back.
+const (
+	hashMaxListpackEntries = 128
+	hashMaxListpackValue   = 64
+)
+
+type listpack [][]byte
+
+// NewHash returns an empty hash value.
+func NewHash() *Value {
+	return &Value{Type: ObjHash, Encoding: EncodingListpack, Ptr: &listpack{}}
+}
+
+func (v *Value) listpack() *listpack {
+	return v.Ptr.(*listpack)
+}
+
+func (v *Value) dict() *Dict {
+	return v.Ptr.(*Dict)
+}
+
+// hashFind returns the index of field in a listpack encoded hash, or -1.
+func (lp listpack) hashFind(field []byte) int {
+	for i := 0; i < len(lp); i += 2 {
+		if bytes.Equal(lp[i], field) {
+			return i
+		}
+	}
+	return -1
+}
+
+// HashLen returns the number of fields of a hash.
+func (v *Value) HashLen() int {
+	if v.Encoding == EncodingListpack {
+		return len(*v.listpack()) / 2
+	}
+	return v.dict().Len()
+}
+
+// HashGet returns the value of field.
+func (v *Value) HashGet(field []byte) ([]byte, bool) {
+	if v.Encoding == EncodingListpack {
+		lp := *v.listpack()
+		if i := lp.hashFind(field); i >= 0 {
+			return lp[i+1], true
+		}
+		return nil, false
+	}
+	value, ok := v.dict().Get(string(field))
+	if !ok {
+		return nil, false
+	}
+	return value.([]byte), true
+}
+
+// HashSet sets field to value and reports whether the field was added.
+func (v *Value) HashSet(field, value []byte) bool {
+	if v.Encoding == EncodingListpack {
+		if len(field) > hashMaxListpackValue || len(value) > hashMaxListpackValue {
+			v.hashConvertToDict()
+		} else {
+			lp := v.listpack()
+			if i := lp.hashFind(field); i >= 0 {
+				(*lp)[i+1] = value
+				return false
+			}
+			*lp = append(*lp, field, value)
+			if len(*lp)/2 > hashMaxListpackEntries {
+				v.hashConvertToDict()
+			}
+			return true
+		}
+	}
+	return v.dict().Set(string(field), value)
+}
+
+// HashDelete removes field and reports whether it was there.
+func (v *Value) HashDelete(field []byte) bool {
+	if v.Encoding == EncodingListpack {
+		lp := v.listpack()
+		i := lp.hashFind(field)
+		if i < 0 {
+			return false
+		}
+		*lp = append((*lp)[:i], (*lp)[i+2:]...)
+		return true
+	}
+	return v.dict().Delete(string(field))
+}
+
+// HashRange calls fn for every field and value until it returns false.
+func (v *Value) HashRange(fn func(field, value []byte) bool) {
+	if v.Encoding == EncodingListpack {
+		lp := *v.listpack()
+		for i := 0; i < len(lp); i += 2 {
+			if !fn(lp[i], lp[i+1]) {
+				return
+			}
+		}
+		return
+	}
+	v.dict().Range(func(key string, value interface{}) bool {
+		return fn([]byte(key), value.([]byte))
+	})
+}
+
+// HashRandom returns a random field of a non empty hash and its value.
+func (v *Value) HashRandom() ([]byte, []byte) {
+	if v.Encoding == EncodingListpack {
+		lp := *v.listpack()
+		i := rand.Intn(len(lp)/2) * 2
+		return lp[i], lp[i+1]
+	}
+	key, value := v.dict().Random()
+	return []byte(key), value.([]byte)
+}
+
+// HashScan calls fn for the fields at cursor and returns the next cursor,
+// see Dict.Scan. A listpack encoded hash is returned whole at once.
+func (v *Value) HashScan(cursor uint64, fn func(field, value []byte)) uint64 {
+	if v.Encoding == EncodingListpack {
+		v.HashRange(func(field, value []byte) bool {
+			fn(field, value)
+			return true
+		})
+		return 0
+	}
+	return v.dict().Scan(cursor, func(key string, value interface{}) {
+		fn([]byte(key), value.([]byte))
+	})
+}
+
+func (v *Value) hashConvertToDict() {
+	d := NewDict()
+	lp := *v.listpack()
+	for i := 0; i < len(lp); i += 2 {
+		d.Set(string(lp[i]), lp[i+1])
+	}
+	v.Encoding = EncodingHashtable
+	v.Ptr = d
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// hashScanAll returns the fields and values a full HashScan iteration
+// returns.
+func hashScanAll(h *Value) map[string]string {
+	fields := make(map[string]string)
+	cursor := uint64(0)
+	for {
+		cursor = h.HashScan(cursor, func(field, value []byte) {
+			fields[string(field)] = string(value)
+		})
+		if cursor == 0 {
+			return fields
+		}
+	}
+}
+
+func testHashOperations(t *testing.T, h *Value) {
+	encoding := h.Encoding
+	if !h.HashSet([]byte("f"), []byte("1")) || h.HashSet([]byte("f"), []byte("2")) {
+		t.Errorf("%s: Expected f to be added then updated", encoding)
+	}
+	if value, ok := h.HashGet([]byte("f")); !ok || string(value) != "2" {
+		t.Errorf("%s: Expected f to be 2, got %q", encoding, value)
+	}
+	size := h.HashLen()
+	if fields := hashScanAll(h); len(fields) != size || fields["f"] != "2" {
+		t.Errorf("%s: Expected the scan to return the %d fields, got %d", encoding, size, len(fields))
+	}
+	if !h.HashDelete([]byte("f")) || h.HashDelete([]byte("f")) {
+		t.Errorf("%s: Expected f to be deleted once", encoding)
+	}
+	if _, ok := h.HashGet([]byte("f")); ok || h.HashLen() != size-1 {
+		t.Errorf("%s: Expected f to be gone", encoding)
+	}
+	if h.Encoding != encoding {
+		t.Errorf("Expected the hash to stay %s, got %s", encoding, h.Encoding)
+	}
+}
+
+func TestHashListpack(t *testing.T) {
+	h := NewHash()
+	h.HashSet([]byte("a"), []byte("x"))
+	testHashOperations(t, h)
+	if h.Encoding != EncodingListpack {
+		t.Errorf("Expected listpack, got %s", h.Encoding)
+	}
+}
+
+func TestHashConvertByEntries(t *testing.T) {
+	h := NewHash()
+	for i := 0; i < hashMaxListpackEntries; i++ {
+		h.HashSet([]byte(strconv.Itoa(i)), []byte("v"))
+	}
+	if h.Encoding != EncodingListpack {
+		t.Errorf("Expected listpack at %d entries, got %s", hashMaxListpackEntries, h.Encoding)
+	}
+	h.HashSet([]byte("last"), []byte("v"))
+	if h.Encoding != EncodingHashtable {
+		t.Errorf("Expected hashtable past %d entries, got %s", hashMaxListpackEntries, h.Encoding)
+	}
+	if value, ok := h.HashGet([]byte("42")); h.HashLen() != hashMaxListpackEntries+1 || !ok || string(value) != "v" {
+		t.Errorf("Expected the fields to be kept across the conversion")
+	}
+	testHashOperations(t, h)
+}
+
+func TestHashConvertByValue(t *testing.T) {
+	long := []byte(strings.Repeat("x", hashMaxListpackValue+1))
+	h := NewHash()
+	h.HashSet([]byte("a"), []byte(strings.Repeat("x", hashMaxListpackValue)))
+	if h.Encoding != EncodingListpack {
+		t.Errorf("Expected listpack with a %d bytes value, got %s", hashMaxListpackValue, h.Encoding)
+	}
+	h.HashSet([]byte("b"), long)
+	if h.Encoding != EncodingHashtable {
+		t.Errorf("Expected hashtable with a long value, got %s", h.Encoding)
+	}
+	testHashOperations(t, h)
+
+	h = NewHash()
+	h.HashSet(long, []byte("v"))
+	if h.Encoding != EncodingHashtable {
+		t.Errorf("Expected hashtable with a long field, got %s", h.Encoding)
+	}
+	if value, ok := h.HashGet(long); !ok || string(value) != "v" {
+		t.Errorf("Expected the long field to be set")
+	}
+}
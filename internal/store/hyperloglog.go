@@ -0,0 +1,374 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// HyperLogLogs are strings laid out like in Redis so they can be
+// exchanged with it: a 16 bytes header, "HYLL", the encoding, 3 unused
+// bytes and the cached cardinality as a little endian uint64 whose most
+// significant bit flags it as stale, followed by 16384 registers of 6
+// bits. The dense encoding packs the registers, least significant bits
+// first. The sparse encoding run-length encodes them with opcodes:
+//
+//	00xxxxxx           xxxxxx+1 registers set to 0
+//	01xxxxxx yyyyyyyy  xxxxxxyyyyyyyy+1 registers set to 0
+//	1vvvvvxx           xx+1 registers set to vvvvv+1
+//
+// New HyperLogLogs are sparse and turn dense when a register exceeds 32,
+// the largest sparse value, or the string exceeds hllSparseMaxBytes.
+const (
+	hllP            = 14
+	hllQ            = 64 - hllP
+	hllRegisters    = 1 << hllP
+	hllBits         = 6
+	hllRegisterMax  = 1<<hllBits - 1
+	hllHeaderSize   = 16
+	hllDenseSize    = hllHeaderSize + (hllRegisters*hllBits+7)/8
+	hllDense        = 0
+	hllSparse       = 1
+	hllAlphaInf     = 0.721347520444481703680
+	hllSparseValMax = 32
+	// hllSparseMaxBytes is the default hll-sparse-max-bytes of Redis
+	hllSparseMaxBytes = 3000
+)
+
+var (
+	ErrNotHLL     = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	ErrHLLCorrupt = errors.New("INVALIDOBJ Corrupted HLL object detected")
+)
+
+// hllRegisterSet holds the registers of a HyperLogLog being worked on.
+type hllRegisterSet [hllRegisters]uint8
+
+// NewHLL returns an empty HyperLogLog string.
+func NewHLL() []byte {
+	b := make([]byte, hllHeaderSize, hllHeaderSize+2)
+	copy(b, "HYLL")
+	b[4] = hllSparse
+	// a single opcode zeroes all the registers
+	return append(b, 0x40|byte((hllRegisters-1)>>8), byte((hllRegisters-1)&0xff))
+}
+
+// checkHLL reports whether b looks like a HyperLogLog. Sparse opcodes are
+// only checked when decoded.
+func checkHLL(b []byte) error {
+	if len(b) < hllHeaderSize || string(b[:4]) != "HYLL" {
+		return ErrNotHLL
+	}
+	switch b[4] {
+	case hllDense:
+		if len(b) != hllDenseSize {
+			return ErrNotHLL
+		}
+	case hllSparse:
+	default:
+		return ErrNotHLL
+	}
+	return nil
+}
+
+// murmurHash64A is the hash function HyperLogLogs use in Redis.
+func murmurHash64A(key []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+	h := seed ^ (uint64(len(key)) * m)
+	for ; len(key) >= 8; key = key[8:] {
+		k := binary.LittleEndian.Uint64(key)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+	if len(key) > 0 {
+		for i := len(key) - 1; i >= 0; i-- {
+			h ^= uint64(key[i]) << (8 * uint(i))
+		}
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// hllPatLen returns the register an element maps to and the value it
+// proposes for it: the position of the first set bit of the rest of its
+// hash.
+func hllPatLen(element []byte) (int, uint8) {
+	hash := murmurHash64A(element, 0xadc83b19)
+	index := int(hash & (hllRegisters - 1))
+	hash >>= hllP
+	hash |= 1 << hllQ
+	count := uint8(1)
+	for bit := uint64(1); hash&bit == 0; bit <<= 1 {
+		count++
+	}
+	return index, count
+}
+
+func hllDenseGet(registers []byte, i int) uint8 {
+	byteIndex, fb := i*hllBits/8, uint(i*hllBits&7)
+	v := uint(registers[byteIndex]) >> fb
+	if byteIndex+1 < len(registers) {
+		v |= uint(registers[byteIndex+1]) << (8 - fb)
+	}
+	return uint8(v & hllRegisterMax)
+}
+
+func hllDenseSet(registers []byte, i int, value uint8) {
+	byteIndex, fb := i*hllBits/8, uint(i*hllBits&7)
+	registers[byteIndex] &^= byte(hllRegisterMax << fb)
+	registers[byteIndex] |= value << fb
+	if byteIndex+1 < len(registers) {
+		registers[byteIndex+1] &^= byte(hllRegisterMax >> (8 - fb))
+		registers[byteIndex+1] |= value >> (8 - fb)
+	}
+}
+
+// decode fills the registers from the HyperLogLog string b, keeping the
+// greatest value when it already holds some, the way HyperLogLogs merge.
+func (rs *hllRegisterSet) decode(b []byte) error {
+	if err := checkHLL(b); err != nil {
+		return err
+	}
+	if b[4] == hllDense {
+		for i := range rs {
+			if v := hllDenseGet(b[hllHeaderSize:], i); v > rs[i] {
+				rs[i] = v
+			}
+		}
+		return nil
+	}
+	i := 0
+	for p := hllHeaderSize; p < len(b); p++ {
+		op := b[p]
+		var run int
+		var v uint8
+		switch {
+		case op&0xc0 == 0x00:
+			run = int(op&0x3f) + 1
+		case op&0xc0 == 0x40:
+			if p+1 == len(b) {
+				return ErrHLLCorrupt
+			}
+			p++
+			run = int(op&0x3f)<<8 | int(b[p]) + 1
+		default:
+			run, v = int(op&0x3)+1, (op>>2)&0x1f+1
+		}
+		if i+run > hllRegisters {
+			return ErrHLLCorrupt
+		}
+		for end := i + run; i < end; i++ {
+			if v > rs[i] {
+				rs[i] = v
+			}
+		}
+	}
+	if i != hllRegisters {
+		return ErrHLLCorrupt
+	}
+	return nil
+}
+
+// encode returns the registers as a HyperLogLog string, sparse unless
+// dense is set or the sparse encoding can't hold them. The cached
+// cardinality is flagged as stale.
+func (rs *hllRegisterSet) encode(dense bool) []byte {
+	if !dense {
+		if b, ok := rs.encodeSparse(); ok {
+			return b
+		}
+	}
+	b := make([]byte, hllDenseSize)
+	copy(b, "HYLL")
+	b[4] = hllDense
+	b[15] = 0x80
+	for i, v := range rs {
+		hllDenseSet(b[hllHeaderSize:], i, v)
+	}
+	return b
+}
+
+func (rs *hllRegisterSet) encodeSparse() ([]byte, bool) {
+	b := make([]byte, hllHeaderSize, 64)
+	copy(b, "HYLL")
+	b[4] = hllSparse
+	b[15] = 0x80
+	for i := 0; i < hllRegisters; {
+		v := rs[i]
+		if v > hllSparseValMax {
+			return nil, false
+		}
+		run := 1
+		for i+run < hllRegisters && rs[i+run] == v {
+			run++
+		}
+		i += run
+		for run > 0 {
+			switch {
+			case v != 0:
+				n := min(run, 4)
+				b = append(b, 0x80|(v-1)<<2|byte(n-1))
+				run -= n
+			case run > 64:
+				b = append(b, 0x40|byte((run-1)>>8), byte((run-1)&0xff))
+				run = 0
+			default:
+				b = append(b, byte(run-1))
+				run = 0
+			}
+		}
+		if len(b) > hllSparseMaxBytes {
+			return nil, false
+		}
+	}
+	return b, true
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// HLLAdd adds elements to the HyperLogLog string b and reports whether
+// any register changed, in which case the returned string is a new slice.
+func HLLAdd(b []byte, elements [][]byte) ([]byte, bool, error) {
+	if err := checkHLL(b); err != nil {
+		return nil, false, err
+	}
+	if b[4] == hllDense {
+		// no need to decode: the registers are updated in a copy of b
+		var updated []byte
+		for _, element := range elements {
+			index, count := hllPatLen(element)
+			if count <= hllDenseGet(b[hllHeaderSize:], index) {
+				continue
+			}
+			if updated == nil {
+				updated = append([]byte(nil), b...)
+				updated[15] |= 0x80
+				b = updated
+			}
+			hllDenseSet(b[hllHeaderSize:], index, count)
+		}
+		return b, updated != nil, nil
+	}
+	var rs hllRegisterSet
+	if err := rs.decode(b); err != nil {
+		return nil, false, err
+	}
+	changed := false
+	for _, element := range elements {
+		index, count := hllPatLen(element)
+		if count > rs[index] {
+			rs[index] = count
+			changed = true
+		}
+	}
+	if !changed {
+		return b, false, nil
+	}
+	return rs.encode(false), true, nil
+}
+
+// HLLMerge returns the union of the HyperLogLog strings srcs, dense if
+// any of them is.
+func HLLMerge(srcs [][]byte) ([]byte, error) {
+	var rs hllRegisterSet
+	dense := false
+	for _, src := range srcs {
+		if err := rs.decode(src); err != nil {
+			return nil, err
+		}
+		dense = dense || src[4] == hllDense
+	}
+	return rs.encode(dense), nil
+}
+
+// HLLCount returns the estimated cardinality of the HyperLogLog string b.
+// When the cached cardinality is stale it also returns a copy of b
+// caching the new one, nil otherwise.
+func HLLCount(b []byte) (uint64, []byte, error) {
+	if err := checkHLL(b); err != nil {
+		return 0, nil, err
+	}
+	if b[15]&0x80 == 0 {
+		return binary.LittleEndian.Uint64(b[8:16]), nil, nil
+	}
+	var rs hllRegisterSet
+	if err := rs.decode(b); err != nil {
+		return 0, nil, err
+	}
+	count := rs.count()
+	cached := append([]byte(nil), b...)
+	binary.LittleEndian.PutUint64(cached[8:16], count)
+	return count, cached, nil
+}
+
+// HLLCountUnion returns the estimated cardinality of the union of the
+// HyperLogLog strings srcs.
+func HLLCountUnion(srcs [][]byte) (uint64, error) {
+	var rs hllRegisterSet
+	for _, src := range srcs {
+		if err := rs.decode(src); err != nil {
+			return 0, err
+		}
+	}
+	return rs.count(), nil
+}
+
+// count estimates the cardinality with the improved estimator of Otmar
+// Ertl that Redis uses.
+func (rs *hllRegisterSet) count() uint64 {
+	var histogram [hllRegisterMax + 1]int
+	for _, v := range rs {
+		histogram[v]++
+	}
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histogram[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histogram[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histogram[0])/m)
+	return uint64(math.Round(hllAlphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		zPrime := z
+		z += x * y
+		y += y
+		if zPrime == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		zPrime := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if zPrime == z {
+			return z / 3
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func hllAddN(t *testing.T, b []byte, prefix string, n int) []byte {
+	elements := make([][]byte, n)
+	for i := range elements {
+		elements[i] = []byte(prefix + strconv.Itoa(i))
+	}
+	b, _, err := HLLAdd(b, elements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestHLLEncodings(t *testing.T) {
+	empty := NewHLL()
+	want := append([]byte("HYLL\x01\x00\x00\x00"), make([]byte, 8)...)
+	if !bytes.Equal(empty, append(want, 0x7f, 0xff)) {
+		t.Errorf("Expected the Redis empty HyperLogLog, got %q", empty)
+	}
+	if n, cached, _ := HLLCount(empty); n != 0 || cached != nil {
+		t.Errorf("Expected a cached cardinality of 0, got %d", n)
+	}
+
+	b, changed, _ := HLLAdd(empty, [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	if !changed || b[4] != hllSparse || b[15]&0x80 == 0 {
+		t.Fatalf("Expected a sparse HyperLogLog with a stale cardinality")
+	}
+	if _, changed, _ := HLLAdd(b, [][]byte{[]byte("a")}); changed {
+		t.Errorf("Expected adding an element twice to change nothing")
+	}
+	n, cached, _ := HLLCount(b)
+	if n != 3 || cached == nil || cached[15]&0x80 != 0 {
+		t.Errorf("Expected 3 to be cached, got %d", n)
+	}
+	if n, again, _ := HLLCount(cached); n != 3 || again != nil {
+		t.Errorf("Expected the cached 3, got %d", n)
+	}
+
+	b = hllAddN(t, b, "e", 5000)
+	if b[4] != hllDense || len(b) != hllDenseSize {
+		t.Errorf("Expected a dense HyperLogLog past %d bytes", hllSparseMaxBytes)
+	}
+	var sparse, dense hllRegisterSet
+	sparse[0], sparse[hllRegisters-1] = 33, 7
+	if b := sparse.encode(false); b[4] != hllDense {
+		t.Errorf("Expected registers above %d to need the dense encoding", hllSparseValMax)
+	}
+	if err := dense.decode(sparse.encode(false)); err != nil || dense != sparse {
+		t.Errorf("Expected the dense registers back, got %v", err)
+	}
+}
+
+func TestHLLCount(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		count, _, _ := HLLCount(hllAddN(t, NewHLL(), "x", n))
+		if math.Abs(float64(count)-float64(n)) > float64(n)*0.02 {
+			t.Errorf("Expected about %d, got %d", n, count)
+		}
+	}
+	a := hllAddN(t, NewHLL(), "a", 3000)
+	b := hllAddN(t, NewHLL(), "b", 3000)
+	union, _ := HLLCountUnion([][]byte{a, b})
+	merged, _ := HLLMerge([][]byte{a, b})
+	count, _, _ := HLLCount(merged)
+	if count != union || math.Abs(float64(count)-6000) > 6000*0.02 {
+		t.Errorf("Expected the union of about 6000 either way, got %d and %d", union, count)
+	}
+}
+
+func TestHLLInvalid(t *testing.T) {
+	if _, _, err := HLLAdd([]byte("not a HyperLogLog"), nil); err != ErrNotHLL {
+		t.Errorf("Expected ErrNotHLL, got %v", err)
+	}
+	corrupt := append(NewHLL()[:hllHeaderSize], 0x3f)
+	corrupt[15] = 0x80
+	if _, _, err := HLLCount(corrupt); err != ErrHLLCorrupt {
+		t.Errorf("Expected ErrHLLCorrupt, got %v", err)
+	}
+}
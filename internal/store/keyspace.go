@@ -0,0 +1,156 @@
+package store
+
+import (
+	"sync/atomic"
+)
+
+// lazyfreeThreshold is the number of elements past which UNLINK frees a
+// value in the background, like LAZYFREE_THRESHOLD in Redis. Smaller
+// values cost less to free than to hand over.
+const lazyfreeThreshold = 64
+
+// Len returns the number of keys, counting the expired keys not deleted
+// yet like DBSIZE does in Redis.
+func (k *Store) Len() int {
+	return k.keys.Len()
+}
+
+// Keys returns the keys matching the glob-style pattern.
+func (k *Store) Keys(pattern []byte) []string {
+	all := string(pattern) == "*"
+	keys := []string{}
+	var expired []string
+	k.keys.Range(func(key string, value interface{}) bool {
+		if k.expired(value.(*Value)) {
+			expired = append(expired, key)
+		} else if all || MatchPattern(pattern, []byte(key), false) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	// the dict can't change while ranging over it, looking the expired
+	// keys up deletes them unless they are hidden
+	for _, key := range expired {
+		k.Lookup(key)
+	}
+	return keys
+}
+
+// randomKeyMaxTries is how many expired keys RandomKey picks before it
+// returns one anyway when they are hidden rather than deleted, which
+// would loop forever if they all were expired.
+const randomKeyMaxTries = 100
+
+// RandomKey returns a random key, or false if there are none.
+func (k *Store) RandomKey() (string, bool) {
+	for tries := 1; k.keys.Len() > 0; tries++ {
+		key, value := k.keys.Random()
+		if !k.expireIfNeeded(key, value.(*Value)) || (k.expirePolicy == ExpireHide && tries == randomKeyMaxTries) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Scan calls fn for the keys at cursor and returns the next cursor, see
+// Dict.Scan. Expired keys are skipped.
+func (k *Store) Scan(cursor uint64, fn func(key string, value *Value)) uint64 {
+	var keys []string
+	var values []*Value
+	cursor = k.keys.Scan(cursor, func(key string, value interface{}) {
+		keys = append(keys, key)
+		values = append(values, value.(*Value))
+	})
+	// the dict can't change while scanning a bucket
+	for i, key := range keys {
+		if !k.expireIfNeeded(key, values[i]) {
+			fn(key, values[i])
+		}
+	}
+	return cursor
+}
+
+// Expires returns the number of keys with a time to live.
+func (k *Store) Expires() int {
+	return len(k.expires)
+}
+
+// AvgTTL returns the estimated average time to live in milliseconds of
+// the keys with one, 0 if unknown.
+func (k *Store) AvgTTL() int64 {
+	return k.avgTTL
+}
+
+// Rename moves the value stored at src, with its time to live, to dst,
+// replacing what was there. It reports whether src existed.
+func (k *Store) Rename(src, dst string) bool {
+	value := k.Lookup(src)
+	if value == nil {
+		return false
+	}
+	if src != dst {
+		k.Delete(src)
+		k.SetValue(dst, value)
+	}
+	return true
+}
+
+// Move moves the value stored at key, with its time to live, to the
+// database dst. It reports false if key doesn't exist or already exists
+// in dst.
+func (k *Store) Move(key string, dst *Store) bool {
+	value := k.Lookup(key)
+	if value == nil || dst.Lookup(key) != nil {
+		return false
+	}
+	k.Delete(key)
+	dst.SetValue(key, value)
+	return true
+}
+
+// Swap exchanges the keys of the two databases, for SWAPDB.
+func (k *Store) Swap(other *Store) {
+	k.keys, other.keys = other.keys, k.keys
+	k.expires, other.expires = other.expires, k.expires
+	k.avgTTL, other.avgTTL = other.avgTTL, k.avgTTL
+}
+
+// Unlink removes key like Delete, but a large value is taken apart by a
+// background goroutine. Once deleted nothing else references the value, so
+// the goroutine owns it.
+func (k *Store) Unlink(key string) bool {
+	value, ok := k.keys.Get(key)
+	if !ok || !k.Delete(key) {
+		return false
+	}
+	if v := value.(*Value); v.Len() > lazyfreeThreshold {
+		k.lazyFree(1, v.release)
+	}
+	return true
+}
+
+// Flush deletes every key. With async the detached keys are taken apart by
+// a background goroutine, and the keyspace is empty right away.
+func (k *Store) Flush(async bool) {
+	keys := k.keys
+	k.keys, k.expires = NewDict(), map[string]*Value{}
+	if async && keys.Len() > 0 {
+		k.lazyFree(int64(keys.Len()), func() {
+			keys.release(func(value interface{}) {
+				value.(*Value).release()
+			})
+		})
+	}
+}
+
+// lazyFree runs free, which releases objects no longer reachable from the
+// keyspace, in a goroutine. The garbage collector reclaims their memory
+// once free dropped the references between them.
+func (k *Store) lazyFree(objects int64, free func()) {
+	atomic.AddInt64(&k.lazyfreePending, objects)
+	go func() {
+		free()
+		atomic.AddInt64(&k.lazyfreePending, -objects)
+		atomic.AddInt64(&k.lazyfreed, objects)
+	}()
+}
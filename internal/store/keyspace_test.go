@@ -0,0 +1,159 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyspace(t *testing.T) {
+	s := NewStore()
+	for _, key := range []string{"user:1", "user:2", "order:1"} {
+		s.Set(key, []byte("v"))
+	}
+	expired := NewString([]byte("v"))
+	expired.ExpireAt = Now() - 1
+	s.SetValue("user:3", expired)
+	keys := s.Keys([]byte("user:*"))
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "user:1,user:2" {
+		t.Errorf("Expected the unexpired user keys, got %v", keys)
+	}
+	if s.Len() != 3 {
+		t.Errorf("Expected 3 keys left, got %d", s.Len())
+	}
+
+	ttl := NewString([]byte("ttl"))
+	ttl.ExpireAt = Now() + 60000
+	s.SetValue("src", ttl)
+	if !s.Rename("src", "user:1") || s.Lookup("src") != nil || s.ExpireTime("user:1") != ttl.ExpireAt {
+		t.Errorf("Expected the value and its time to live to move")
+	}
+	if s.Rename("src", "dst") {
+		t.Errorf("Expected renaming a missing key to fail")
+	}
+
+	flushed := s.keys
+	s.Flush(true)
+	if _, ok := s.RandomKey(); ok || s.Len() != 0 {
+		t.Errorf("Expected the keyspace to be empty right away")
+	}
+	waitLazyfreed(t, s, 3)
+	if flushed.Len() != 0 {
+		t.Errorf("Expected the flushed keys to be released, got %d", flushed.Len())
+	}
+
+	big := NewHash()
+	for i := 0; i <= lazyfreeThreshold; i++ {
+		big.HashSet([]byte(fmt.Sprint(i)), []byte("v"))
+	}
+	s.SetValue("big", big)
+	s.Set("small", []byte("v"))
+	if !s.Unlink("big") || !s.Unlink("small") || s.Unlink("big") || s.Len() != 0 {
+		t.Errorf("Expected the keys to be unlinked once")
+	}
+	// only the value past the threshold is freed in the background
+	waitLazyfreed(t, s, 4)
+	if big.HashLen() != 0 {
+		t.Errorf("Expected the unlinked hash to be released, got %d fields", big.HashLen())
+	}
+}
+
+// waitLazyfreed waits for the background goroutines to have freed n
+// objects in total.
+func waitLazyfreed(t *testing.T, s *Store, n int64) {
+	t.Helper()
+	for i := 0; s.Stats().LazyfreedObjects != n; i++ {
+		if i == 100 {
+			t.Fatalf("Expected %d objects to be freed, got %d", n, s.Stats().LazyfreedObjects)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	list := NewList()
+	list.List().PushTail([]byte("a"))
+	dup := list.Copy()
+	dup.List().PushTail([]byte("b"))
+	if list.Len() != 1 || dup.Len() != 2 {
+		t.Errorf("Expected the copy of a list to change independently")
+	}
+
+	zs := NewZSet()
+	zs.ZSetAdd([]byte("a"), 1)
+	dup = zs.Copy()
+	dup.ZSetAdd([]byte("a"), 2)
+	if score, _ := zs.ZSetScore([]byte("a")); score != 1 {
+		t.Errorf("Expected the original score to stay 1, got %v", score)
+	}
+
+	stream := NewStream()
+	s := stream.Stream()
+	s.Add(StreamID{1, 0}, [][]byte{[]byte("f"), []byte("v")})
+	g := s.CreateGroup("g", StreamID{}, 0)
+	alice, _ := g.CreateConsumer("alice", 0)
+	s.ReadGroup(g, alice, 10, false, 0)
+	dupGroup := stream.Copy().Stream().Group("g")
+	dupGroup.Ack(StreamID{1, 0})
+	if g.PendingLen() != 1 || dupGroup.PendingLen() != 0 || dupGroup.Consumer("alice").pending.find(StreamID{1, 0}) != nil {
+		t.Errorf("Expected the pending entries of the copy to change independently")
+	}
+}
+
+func TestScan(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 500; i++ {
+		s.Set(fmt.Sprintf("key:%d", i), []byte("v"))
+	}
+	expired := NewString([]byte("v"))
+	expired.ExpireAt = Now() - 1
+	s.SetValue("expired", expired)
+	seen := map[string]bool{}
+	cursor, calls := uint64(0), 0
+	for {
+		cursor = s.Scan(cursor, func(key string, value *Value) {
+			seen[key] = true
+		})
+		// the keyspace grows and gets rehashed during the scan
+		if calls++; calls%10 == 0 && calls <= 50 {
+			for i := 0; i < 100; i++ {
+				s.Set(fmt.Sprintf("new:%d:%d", calls, i), []byte("v"))
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+	for i := 0; i < 500; i++ {
+		if !seen[fmt.Sprintf("key:%d", i)] {
+			t.Fatalf("Expected key:%d to be returned", i)
+		}
+	}
+	if seen["expired"] || s.Lookup("expired") != nil {
+		t.Errorf("Expected the expired key to be deleted rather than returned")
+	}
+}
+
+func TestDatabases(t *testing.T) {
+	dbs := NewDatabases(2)
+	if dbs[0].mu != dbs[1].mu {
+		t.Fatalf("Expected the databases to share their lock")
+	}
+	ttl := NewString([]byte("v"))
+	ttl.ExpireAt = Now() + 60000
+	dbs[0].SetValue("key", ttl)
+	if !dbs[0].Move("key", dbs[1]) || dbs[0].Len() != 0 || dbs[1].ExpireTime("key") != ttl.ExpireAt {
+		t.Errorf("Expected the key to move with its time to live")
+	}
+	dbs[0].Set("key", []byte("other"))
+	if dbs[0].Move("key", dbs[1]) {
+		t.Errorf("Expected moving onto an existing key to fail")
+	}
+	dbs[0].Swap(dbs[1])
+	if got, _, _ := dbs[0].Get("key"); string(got) != "v" || dbs[0].Expires() != 1 || dbs[1].Expires() != 0 {
+		t.Errorf("Expected the keys and their expirations to be swapped, got %q", got)
+	}
+}
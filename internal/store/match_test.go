@@ -0,0 +1,40 @@
+package store
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		nocase  bool
+		want    bool
+	}{
+		{"*", "anything", false, true},
+		{"user:*", "user:1000", false, true},
+		{"user:*", "users", false, false},
+		{"h?llo", "hello", false, true},
+		{"h?llo", "hllo", false, false},
+		{"h*llo", "heeeello", false, true},
+		{"h[ae]llo", "hallo", false, true},
+		{"h[ae]llo", "hillo", false, false},
+		{"h[^e]llo", "hallo", false, true},
+		{"h[^e]llo", "hello", false, false},
+		{"h[a-b]llo", "hbllo", false, true},
+		{"h[b-a]llo", "hallo", false, true},
+		{"h\\*llo", "h*llo", false, true},
+		{"h\\*llo", "hello", false, false},
+		{"HELLO", "hello", true, true},
+		{"HELLO", "hello", false, false},
+		{"h[A-Z]llo", "hello", true, true},
+		{"*a*a*a*a*a*a*a*a*a*a*b", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", false, false},
+		{"abc*", "abc", false, true},
+		{"[abc", "a", false, true},
+		{"", "", false, true},
+		{"a", "", false, false},
+	}
+	for _, test := range tests {
+		if got := MatchPattern([]byte(test.pattern), []byte(test.s), test.nocase); got != test.want {
+			t.Errorf("MatchPattern(%q, %q, %v) = %v, want %v", test.pattern, test.s, test.nocase, got, test.want)
+		}
+	}
+}
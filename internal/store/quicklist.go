@@ -0,0 +1,264 @@
+package store
+
+// Quicklist is the list value: a doubly linked list of nodes that each
+// hold a small array of elements, like the quicklist of Redis. Locating
+// an index skips whole nodes, and elements are packed in few allocations.
+type Quicklist struct {
+	head  *quicklistNode
+	tail  *quicklistNode
+	count int
+	nodes int
+	// bytes is the total size of the elements
+	bytes int
+}
+
+type quicklistNode struct {
+	prev    *quicklistNode
+	next    *quicklistNode
+	entries [][]byte
+	bytes   int
+}
+
+const (
+	// a node is split once it holds more elements or bytes than this,
+	// the default list-max-listpack-size of -2 is 8kb
+	quicklistNodeMaxEntries = 128
+	quicklistNodeMaxBytes   = 8192
+)
+
+func NewQuicklist() *Quicklist {
+	return &Quicklist{}
+}
+
+// release empties a list nothing references any more, unlinking its nodes
+// one by one.
+func (q *Quicklist) release() {
+	for node := q.head; node != nil; {
+		next := node.next
+		node.prev, node.next, node.entries = nil, nil, nil
+		node = next
+	}
+	*q = Quicklist{}
+}
+
+// Len returns the number of elements of the list.
+func (q *Quicklist) Len() int {
+	return q.count
+}
+
+func (n *quicklistNode) full(extra []byte) bool {
+	return len(n.entries) >= quicklistNodeMaxEntries || n.bytes+len(extra) > quicklistNodeMaxBytes
+}
+
+func (q *Quicklist) PushHead(value []byte) {
+	if q.head == nil || q.head.full(value) {
+		q.linkAfter(nil, &quicklistNode{})
+	}
+	node := q.head
+	node.entries = append(node.entries, nil)
+	copy(node.entries[1:], node.entries)
+	node.entries[0] = value
+	q.added(node, value)
+}
+
+func (q *Quicklist) PushTail(value []byte) {
+	if q.tail == nil || q.tail.full(value) {
+		q.linkAfter(q.tail, &quicklistNode{})
+	}
+	node := q.tail
+	node.entries = append(node.entries, value)
+	q.added(node, value)
+}
+
+// PopHead removes and returns the first element.
+func (q *Quicklist) PopHead() ([]byte, bool) {
+	if q.count == 0 {
+		return nil, false
+	}
+	value := q.head.entries[0]
+	q.deleteEntry(q.head, 0)
+	return value, true
+}
+
+// PopTail removes and returns the last element.
+func (q *Quicklist) PopTail() ([]byte, bool) {
+	if q.count == 0 {
+		return nil, false
+	}
+	value := q.tail.entries[len(q.tail.entries)-1]
+	q.deleteEntry(q.tail, len(q.tail.entries)-1)
+	return value, true
+}
+
+// Index returns the element at index, which must be in [0, Len()).
+func (q *Quicklist) Index(index int) []byte {
+	node, offset := q.locate(index)
+	return node.entries[offset]
+}
+
+// Set replaces the element at index, which must be in [0, Len()).
+func (q *Quicklist) Set(index int, value []byte) {
+	node, offset := q.locate(index)
+	delta := len(value) - len(node.entries[offset])
+	node.entries[offset] = value
+	node.bytes += delta
+	q.bytes += delta
+}
+
+// Insert adds value before the element at index; an index of Len()
+// appends it.
+func (q *Quicklist) Insert(index int, value []byte) {
+	if index == q.count {
+		q.PushTail(value)
+		return
+	}
+	node, offset := q.locate(index)
+	if node.full(value) {
+		// split the node in halves and insert into the matching half
+		half := len(node.entries) / 2
+		next := &quicklistNode{entries: append([][]byte(nil), node.entries[half:]...)}
+		for _, entry := range next.entries {
+			next.bytes += len(entry)
+		}
+		node.entries = node.entries[:half:half]
+		node.bytes -= next.bytes
+		q.linkAfter(node, next)
+		if offset >= half {
+			node, offset = next, offset-half
+		}
+	}
+	node.entries = append(node.entries, nil)
+	copy(node.entries[offset+1:], node.entries[offset:])
+	node.entries[offset] = value
+	q.added(node, value)
+}
+
+// Delete removes the element at index, which must be in [0, Len()).
+func (q *Quicklist) Delete(index int) {
+	node, offset := q.locate(index)
+	q.deleteEntry(node, offset)
+}
+
+// Trim keeps only the elements from start to stop, inclusive. Whole nodes
+// outside of the range are dropped at once.
+func (q *Quicklist) Trim(start, stop int) {
+	for head := start; head > 0; {
+		if n := len(q.head.entries); n <= head {
+			head -= n
+			q.count -= n
+			q.bytes -= q.head.bytes
+			q.unlink(q.head)
+		} else {
+			q.deleteEntry(q.head, 0)
+			head--
+		}
+	}
+	for tail := q.count - (stop - start + 1); tail > 0; {
+		if n := len(q.tail.entries); n <= tail {
+			tail -= n
+			q.count -= n
+			q.bytes -= q.tail.bytes
+			q.unlink(q.tail)
+		} else {
+			q.deleteEntry(q.tail, len(q.tail.entries)-1)
+			tail--
+		}
+	}
+}
+
+// Range calls fn for the elements from start to stop, inclusive, moving
+// towards the tail, or towards the head when start is after stop. It stops
+// early when fn returns false.
+func (q *Quicklist) Range(start, stop int, fn func(index int, value []byte) bool) {
+	if q.count == 0 {
+		return
+	}
+	node, offset := q.locate(start)
+	step := 1
+	if start > stop {
+		step = -1
+	}
+	for index := start; ; index += step {
+		if !fn(index, node.entries[offset]) || index == stop {
+			return
+		}
+		offset += step
+		if offset == len(node.entries) {
+			node, offset = node.next, 0
+		} else if offset < 0 {
+			node = node.prev
+			offset = len(node.entries) - 1
+		}
+	}
+}
+
+// locate returns the node holding index and the offset of the element in
+// it, walking from whichever end of the list is closer.
+func (q *Quicklist) locate(index int) (*quicklistNode, int) {
+	if index < q.count/2 {
+		node := q.head
+		for index >= len(node.entries) {
+			index -= len(node.entries)
+			node = node.next
+		}
+		return node, index
+	}
+	node := q.tail
+	fromTail := q.count - 1 - index
+	for fromTail >= len(node.entries) {
+		fromTail -= len(node.entries)
+		node = node.prev
+	}
+	return node, len(node.entries) - 1 - fromTail
+}
+
+func (q *Quicklist) added(node *quicklistNode, value []byte) {
+	node.bytes += len(value)
+	q.bytes += len(value)
+	q.count++
+}
+
+func (q *Quicklist) deleteEntry(node *quicklistNode, offset int) {
+	size := len(node.entries[offset])
+	copy(node.entries[offset:], node.entries[offset+1:])
+	node.entries[len(node.entries)-1] = nil
+	node.entries = node.entries[:len(node.entries)-1]
+	node.bytes -= size
+	q.bytes -= size
+	q.count--
+	if len(node.entries) == 0 {
+		q.unlink(node)
+	}
+}
+
+// linkAfter inserts node after prev, or at the head when prev is nil.
+func (q *Quicklist) linkAfter(prev, node *quicklistNode) {
+	node.prev = prev
+	if prev == nil {
+		node.next = q.head
+		q.head = node
+	} else {
+		node.next = prev.next
+		prev.next = node
+	}
+	if node.next == nil {
+		q.tail = node
+	} else {
+		node.next.prev = node
+	}
+	q.nodes++
+}
+
+func (q *Quicklist) unlink(node *quicklistNode) {
+	if node.prev == nil {
+		q.head = node.next
+	} else {
+		node.prev.next = node.next
+	}
+	if node.next == nil {
+		q.tail = node.prev
+	} else {
+		node.next.prev = node.prev
+	}
+	q.nodes--
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+)
+
+// quicklistElements returns the elements of q from head to tail.
+func quicklistElements(q *Quicklist) []string {
+	var elements []string
+	if q.Len() > 0 {
+		q.Range(0, q.Len()-1, func(index int, value []byte) bool {
+			elements = append(elements, string(value))
+			return true
+		})
+	}
+	return elements
+}
+
+func TestQuicklistSpansNodes(t *testing.T) {
+	q := NewQuicklist()
+	for i := 0; i < 1000; i++ {
+		q.PushTail([]byte(strconv.Itoa(i)))
+	}
+	if q.nodes < 1000/quicklistNodeMaxEntries {
+		t.Fatalf("Expected the list to be split in nodes, got %d", q.nodes)
+	}
+	for _, i := range []int{0, 127, 128, 500, 999} {
+		if got := string(q.Index(i)); got != strconv.Itoa(i) {
+			t.Errorf("Expected %d at index %d, got %s", i, i, got)
+		}
+	}
+	q.PushHead([]byte("head"))
+	if got := string(q.Index(0)); got != "head" || q.Len() != 1001 {
+		t.Errorf("Expected head at index 0 of 1001 elements, got %s of %d", got, q.Len())
+	}
+	var reversed []string
+	q.Range(q.Len()-1, q.Len()-3, func(index int, value []byte) bool {
+		reversed = append(reversed, string(value))
+		return true
+	})
+	if len(reversed) != 3 || reversed[0] != "999" || reversed[2] != "997" {
+		t.Errorf("Expected to range backwards from the tail, got %v", reversed)
+	}
+}
+
+func TestQuicklistInsertSplitsFullNode(t *testing.T) {
+	q := NewQuicklist()
+	for i := 0; i < quicklistNodeMaxEntries; i++ {
+		q.PushTail([]byte(strconv.Itoa(i)))
+	}
+	q.Insert(10, []byte("x"))
+	if q.nodes != 2 {
+		t.Errorf("Expected the full node to be split, got %d nodes", q.nodes)
+	}
+	if got := string(q.Index(10)); got != "x" {
+		t.Errorf("Expected x at index 10, got %s", got)
+	}
+	if got := string(q.Index(11)); got != "10" {
+		t.Errorf("Expected 10 at index 11, got %s", got)
+	}
+}
+
+func TestQuicklistDeleteAndTrim(t *testing.T) {
+	q := NewQuicklist()
+	for i := 0; i < 300; i++ {
+		q.PushTail([]byte(strconv.Itoa(i)))
+	}
+	q.Trim(100, 104)
+	if got := quicklistElements(q); len(got) != 5 || got[0] != "100" || got[4] != "104" {
+		t.Fatalf("Expected elements 100 to 104, got %v", got)
+	}
+	if q.nodes != 1 {
+		t.Errorf("Expected trimmed nodes to be dropped, got %d nodes", q.nodes)
+	}
+	q.Delete(2)
+	q.Set(0, []byte("first"))
+	if got := quicklistElements(q); len(got) != 4 || got[0] != "first" || got[2] != "103" {
+		t.Errorf("Expected [first 101 103 104], got %v", got)
+	}
+	for q.Len() > 0 {
+		q.PopTail()
+	}
+	if q.head != nil || q.tail != nil || q.bytes != 0 {
+		t.Errorf("Expected an empty list to have no nodes")
+	}
+}
+
+func TestListEncodingConversion(t *testing.T) {
+	s := NewStore()
+	list := NewList()
+	s.SetValue("list", list)
+	for i := 0; i <= quicklistNodeMaxEntries; i++ {
+		list.List().PushTail([]byte("x"))
+	}
+	s.Updated("list", list)
+	if list.Encoding != EncodingQuicklist {
+		t.Errorf("Expected quicklist encoding, got %s", list.Encoding)
+	}
+	list.List().Trim(0, quicklistNodeMaxEntries/2-1)
+	s.Updated("list", list)
+	if list.Encoding != EncodingListpack {
+		t.Errorf("Expected listpack encoding, got %s", list.Encoding)
+	}
+	for list.Len() > 0 {
+		list.List().PopHead()
+	}
+	s.Updated("list", list)
+	if s.Lookup("list") != nil {
+		t.Errorf("Expected the empty list to be deleted")
+	}
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// Sets of integers are kept in an intset, a sorted slice searched with a
+// binary search. Other small sets are kept in a listpack, and sets past
+// the limits below in a Dict.
+const (
+	setMaxIntsetEntries   = 512
+	setMaxListpackEntries = 128
+	setMaxListpackValue   = 64
+)
+
+type intset []int64
+
+// NewSet returns an empty set value.
+func NewSet() *Value {
+	return &Value{Type: ObjSet, Encoding: EncodingIntset, Ptr: &intset{}}
+}
+
+func (v *Value) intset() *intset {
+	return v.Ptr.(*intset)
+}
+
+// parseInt parses s as an integer only if formatting the integer gives s
+// back, so "01" or "+1" are not integers, the way Redis decides whether a
+// string can be stored as one.
+func parseInt(s []byte) (int64, bool) {
+	if len(s) == 0 || len(s) > 20 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(string(s), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	var buf [20]byte
+	return n, bytes.Equal(strconv.AppendInt(buf[:0], n, 10), s)
+}
+
+func (is intset) search(n int64) (int, bool) {
+	i := sort.Search(len(is), func(i int) bool { return is[i] >= n })
+	return i, i < len(is) && is[i] == n
+}
+
+// SetLen returns the number of members of a set.
+func (v *Value) SetLen() int {
+	switch v.Encoding {
+	case EncodingIntset:
+		return len(*v.intset())
+	case EncodingListpack:
+		return len(*v.listpack())
+	}
+	return v.dict().Len()
+}
+
+// SetIsMember reports whether member belongs to the set.
+func (v *Value) SetIsMember(member []byte) bool {
+	switch v.Encoding {
+	case EncodingIntset:
+		n, ok := parseInt(member)
+		if !ok {
+			return false
+		}
+		_, found := v.intset().search(n)
+		return found
+	case EncodingListpack:
+		return v.listpack().setFind(member) >= 0
+	}
+	_, ok := v.dict().Get(string(member))
+	return ok
+}
+
+func (lp listpack) setFind(member []byte) int {
+	for i, m := range lp {
+		if bytes.Equal(m, member) {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetAdd adds member to the set and reports whether it was added. The set
+// converts to a bigger encoding when the member doesn't fit the current
+// one.
+func (v *Value) SetAdd(member []byte) bool {
+	switch v.Encoding {
+	case EncodingIntset:
+		if n, ok := parseInt(member); ok {
+			is := v.intset()
+			i, found := is.search(n)
+			if found {
+				return false
+			}
+			*is = append(*is, 0)
+			copy((*is)[i+1:], (*is)[i:])
+			(*is)[i] = n
+			if len(*is) > setMaxIntsetEntries {
+				v.setConvertToDict()
+			}
+			return true
+		}
+		if len(*v.intset()) < setMaxListpackEntries && len(member) <= setMaxListpackValue {
+			v.setConvertToListpack()
+		} else {
+			v.setConvertToDict()
+		}
+		return v.SetAdd(member)
+	case EncodingListpack:
+		lp := v.listpack()
+		if lp.setFind(member) >= 0 {
+			return false
+		}
+		if len(*lp) >= setMaxListpackEntries || len(member) > setMaxListpackValue {
+			v.setConvertToDict()
+			return v.SetAdd(member)
+		}
+		*lp = append(*lp, member)
+		return true
+	}
+	return v.dict().Set(string(member), nil)
+}
+
+// SetRemove removes member from the set and reports whether it was there.
+func (v *Value) SetRemove(member []byte) bool {
+	switch v.Encoding {
+	case EncodingIntset:
+		n, ok := parseInt(member)
+		if !ok {
+			return false
+		}
+		is := v.intset()
+		i, found := is.search(n)
+		if !found {
+			return false
+		}
+		*is = append((*is)[:i], (*is)[i+1:]...)
+		return true
+	case EncodingListpack:
+		lp := v.listpack()
+		i := lp.setFind(member)
+		if i < 0 {
+			return false
+		}
+		*lp = append((*lp)[:i], (*lp)[i+1:]...)
+		return true
+	}
+	return v.dict().Delete(string(member))
+}
+
+// SetScan calls fn for the members at cursor and returns the next cursor,
+// see Dict.Scan. Intset and listpack encoded sets are returned whole at
+// once.
+func (v *Value) SetScan(cursor uint64, fn func(member []byte)) uint64 {
+	if v.Encoding != EncodingHashtable {
+		v.SetRange(func(member []byte) bool {
+			fn(member)
+			return true
+		})
+		return 0
+	}
+	return v.dict().Scan(cursor, func(key string, value interface{}) {
+		fn([]byte(key))
+	})
+}
+
+// SetRange calls fn for every member until it returns false. fn must not
+// modify the set.
+func (v *Value) SetRange(fn func(member []byte) bool) {
+	switch v.Encoding {
+	case EncodingIntset:
+		for _, n := range *v.intset() {
+			if !fn(strconv.AppendInt(nil, n, 10)) {
+				return
+			}
+		}
+	case EncodingListpack:
+		for _, m := range *v.listpack() {
+			if !fn(m) {
+				return
+			}
+		}
+	default:
+		v.dict().Range(func(key string, value interface{}) bool {
+			return fn([]byte(key))
+		})
+	}
+}
+
+// SetRandom returns a random member of a non empty set.
+func (v *Value) SetRandom() []byte {
+	switch v.Encoding {
+	case EncodingIntset:
+		is := *v.intset()
+		return strconv.AppendInt(nil, is[rand.Intn(len(is))], 10)
+	case EncodingListpack:
+		lp := *v.listpack()
+		return lp[rand.Intn(len(lp))]
+	}
+	key, _ := v.dict().Random()
+	return []byte(key)
+}
+
+func (v *Value) setConvertToListpack() {
+	lp := make(listpack, 0, len(*v.intset())+1)
+	for _, n := range *v.intset() {
+		lp = append(lp, strconv.AppendInt(nil, n, 10))
+	}
+	v.Encoding = EncodingListpack
+	v.Ptr = &lp
+}
+
+func (v *Value) setConvertToDict() {
+	d := NewDict()
+	v.SetRange(func(member []byte) bool {
+		d.Set(string(member), nil)
+		return true
+	})
+	v.Encoding = EncodingHashtable
+	v.Ptr = d
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSetEncodings(t *testing.T) {
+	s := NewSet()
+	for i := 0; i < 100; i++ {
+		s.SetAdd([]byte(strconv.Itoa(i)))
+	}
+	if s.Encoding != EncodingIntset {
+		t.Errorf("Expected intset, got %s", s.Encoding)
+	}
+	if s.SetAdd([]byte("7")) || !s.SetAdd([]byte("007")) {
+		t.Errorf("Expected 7 to exist and 007 to be added as a string")
+	}
+	if s.Encoding != EncodingListpack {
+		t.Errorf("Expected listpack, got %s", s.Encoding)
+	}
+	if !s.SetIsMember([]byte("42")) || !s.SetIsMember([]byte("007")) {
+		t.Errorf("Expected members to be kept across the conversion")
+	}
+	s.SetAdd([]byte(strings.Repeat("x", 65)))
+	if s.Encoding != EncodingHashtable {
+		t.Errorf("Expected hashtable, got %s", s.Encoding)
+	}
+	if s.SetLen() != 102 {
+		t.Errorf("Expected 102 members, got %d", s.SetLen())
+	}
+	if !s.SetRemove([]byte("42")) || s.SetIsMember([]byte("42")) {
+		t.Errorf("Expected 42 to be removed")
+	}
+}
+
+func TestSetIntsetOverflow(t *testing.T) {
+	s := NewSet()
+	for i := 0; i <= setMaxIntsetEntries; i++ {
+		s.SetAdd([]byte(strconv.Itoa(-i)))
+	}
+	if s.Encoding != EncodingHashtable {
+		t.Errorf("Expected hashtable, got %s", s.Encoding)
+	}
+	if !s.SetIsMember([]byte("-512")) {
+		t.Errorf("Expected -512 to be a member")
+	}
+}
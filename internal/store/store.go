@@ -3,46 +3,325 @@ package store
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Store is a keyspace. Its methods are not safe for concurrent use: callers
+// hold the lock while running a command, the way Redis runs commands one
+// at a time on its event loop.
 type Store struct {
-	db  sync.Map
-	exp sync.Map
+	// expiredKeys counts keys deleted because their time to live passed;
+	// it comes first to stay 64-bit aligned for atomic access
+	expiredKeys int64
+	// lazyfreePending counts the objects waiting to be freed in the
+	// background and lazyfreed those freed so far
+	lazyfreePending int64
+	lazyfreed       int64
+	// hz is how many times per second the active expire cycle runs
+	hz int32
+	// mu is shared by the databases of a server, see NewDatabases
+	mu *sync.Mutex
+	// keys is a Dict rather than a map so SCAN can iterate it with a
+	// cursor
+	keys *Dict
+	// expires indexes the keys with a time to live
+	expires map[string]*Value
+	// avgTTL estimates the average time to live in milliseconds of the
+	// keys with one, from the samples of the active expire cycle
+	avgTTL int64
+	// expirePolicy is what lookups do with expired keys, and expireHook
+	// is called for every key they delete
+	expirePolicy ExpirePolicy
+	expireHook   func(key string)
+}
+
+// ExpirePolicy is what happens to the keys found expired.
+type ExpirePolicy int
+
+const (
+	// ExpireDelete deletes them, on masters.
+	ExpireDelete ExpirePolicy = iota
+	// ExpireHide reports them as missing but keeps them, on replicas:
+	// the master propagates a DEL when it expires them.
+	ExpireHide
+	// ExpireKeep ignores time to live, for replicas applying the commands
+	// of their master, which saw the keys live when it ran them.
+	ExpireKeep
+)
+
+// SetCondition restricts when SetWithOptions writes a value.
+type SetCondition int
+
+const (
+	SetAlways SetCondition = iota
+	// SetIfNotExists only sets keys that do not exist (NX)
+	SetIfNotExists
+	// SetIfExists only sets keys that already exist (XX)
+	SetIfExists
+)
+
+// SetOptions are the modifiers of the SET command.
+type SetOptions struct {
+	Condition SetCondition
+	// ExpireAt is the absolute expiration as unix time in milliseconds, 0
+	// means the key does not expire
+	ExpireAt int64
+	// KeepTTL retains the time to live of the key being replaced
+	KeepTTL bool
+	// Get makes the write fail with ErrWrongType when the key holds a
+	// value that is not a string, since it would be returned
+	Get bool
 }
 
 func NewStore() *Store {
 	fmt.Println("Initialize key value store...")
-	store := &Store{db: sync.Map{}, exp: sync.Map{}}
-	return store
-}
-
-func (k *Store) Get(key string) (string, bool) {
-	value, ok := k.db.Load(key)
-	if !ok {
-		return "", false
-	}
-	expiration, expOk := k.exp.Load(key)
-	if expOk {
-		expTime := expiration.(int64)
-		now := time.Now().UnixNano() / int64(time.Millisecond)
-		if expTime < now {
-			k.db.Delete(key)
-			k.exp.Delete(key)
-			return "", false
+	return newStore(&sync.Mutex{})
+}
+
+// NewDatabases returns the n numbered keyspaces of a server. They share
+// their lock, so a command holding it can work on several of them, e.g.
+// MOVE.
+func NewDatabases(n int) []*Store {
+	fmt.Println("Initialize key value store...")
+	mu := &sync.Mutex{}
+	dbs := make([]*Store, n)
+	for i := range dbs {
+		dbs[i] = newStore(mu)
+	}
+	return dbs
+}
+
+func newStore(mu *sync.Mutex) *Store {
+	return &Store{keys: NewDict(), expires: map[string]*Value{}, hz: DefaultHz, mu: mu}
+}
+
+// Stats are counters about the keyspace reported by INFO.
+type Stats struct {
+	ExpiredKeys            int64
+	LazyfreePendingObjects int64
+	LazyfreedObjects       int64
+}
+
+func (k *Store) Stats() Stats {
+	return Stats{
+		ExpiredKeys:            atomic.LoadInt64(&k.expiredKeys),
+		LazyfreePendingObjects: atomic.LoadInt64(&k.lazyfreePending),
+		LazyfreedObjects:       atomic.LoadInt64(&k.lazyfreed),
+	}
+}
+
+func (k *Store) Lock() {
+	k.mu.Lock()
+}
+
+func (k *Store) Unlock() {
+	k.mu.Unlock()
+}
+
+// Now returns the current unix time in milliseconds, the unit expirations
+// are stored in.
+func Now() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// Lookup returns the value stored at key, or nil if there is none.
+func (k *Store) Lookup(key string) *Value {
+	value, ok := k.keys.Get(key)
+	if !ok || k.expireIfNeeded(key, value.(*Value)) {
+		return nil
+	}
+	return value.(*Value)
+}
+
+// LookupType returns the value stored at key, or nil if there is none. It
+// fails with ErrWrongType if the value is not of type t.
+func (k *Store) LookupType(key string, t ObjectType) (*Value, error) {
+	value := k.Lookup(key)
+	if value != nil && value.Type != t {
+		return nil, ErrWrongType
+	}
+	return value, nil
+}
+
+// SetExpirePolicy changes what lookups do with expired keys.
+func (k *Store) SetExpirePolicy(policy ExpirePolicy) {
+	k.expirePolicy = policy
+}
+
+// SetExpireHook makes fn be called, with the lock held, for every key
+// deleted because its time to live passed, so a master can propagate it.
+func (k *Store) SetExpireHook(fn func(key string)) {
+	k.expireHook = fn
+}
+
+// expired reports whether value is to be treated as expired.
+func (k *Store) expired(value *Value) bool {
+	return k.expirePolicy != ExpireKeep && value.ExpireAt != 0 && value.ExpireAt <= Now()
+}
+
+// expireIfNeeded reports whether the value stored at key is expired, and
+// deletes it if the policy says so.
+func (k *Store) expireIfNeeded(key string, value *Value) bool {
+	if !k.expired(value) {
+		return false
+	}
+	if k.expirePolicy == ExpireHide {
+		return true
+	}
+	k.Delete(key)
+	atomic.AddInt64(&k.expiredKeys, 1)
+	if k.expireHook != nil {
+		k.expireHook(key)
+	}
+	return true
+}
+
+// SetValue stores value at key, replacing what was there. The key expires
+// according to value.ExpireAt.
+func (k *Store) SetValue(key string, value *Value) {
+	k.keys.Set(key, value)
+	if value.ExpireAt > 0 {
+		k.expires[key] = value
+	} else {
+		delete(k.expires, key)
+	}
+}
+
+// Delete removes key and reports whether it existed.
+func (k *Store) Delete(key string) bool {
+	if !k.keys.Delete(key) {
+		return false
+	}
+	delete(k.expires, key)
+	return true
+}
+
+// Updated must be called after changing the collection stored at key in
+// place. Empty collections are deleted, since Redis never keeps them, and
+// the encoding follows the new size.
+func (k *Store) Updated(key string, value *Value) {
+	if value.Len() == 0 {
+		k.Delete(key)
+		return
+	}
+	switch value.Type {
+	case ObjList:
+		value.updateListEncoding()
+	}
+}
+
+// Get returns the string stored at key. Values are kept as raw bytes so
+// binary payloads round-trip unchanged.
+func (k *Store) Get(key string) ([]byte, bool, error) {
+	value, err := k.LookupType(key, ObjString)
+	if value == nil {
+		return nil, false, err
+	}
+	return value.Bytes(), true, nil
+}
+
+func (k *Store) Set(key string, value []byte) {
+	k.SetValue(key, NewString(value))
+}
+
+// SetWithOptions stores the string value at key subject to opts. It returns
+// the value previously stored at key, whether there was one, and whether
+// the new value was written.
+func (k *Store) SetWithOptions(key string, value []byte, opts SetOptions) ([]byte, bool, bool, error) {
+	var old []byte
+	previous := k.Lookup(key)
+	existed := previous != nil
+	if existed && opts.Get {
+		if previous.Type != ObjString {
+			return nil, true, false, ErrWrongType
 		}
+		old = previous.Bytes()
+	}
+	if (opts.Condition == SetIfNotExists && existed) || (opts.Condition == SetIfExists && !existed) {
+		return old, existed, false, nil
 	}
-	return value.(string), ok
+	v := NewString(value)
+	v.ExpireAt = opts.ExpireAt
+	if opts.KeepTTL && existed {
+		v.ExpireAt = previous.ExpireAt
+	}
+	k.SetValue(key, v)
+	return old, existed, true, nil
 }
 
-func (k *Store) Set(key string, value string) string {
-	k.db.Store(key, value)
-	return "OK"
+// ExpireCondition restricts when Expire changes the time to live of a key.
+type ExpireCondition int
+
+const (
+	ExpireAlways ExpireCondition = iota
+	// ExpireIfNoTTL only sets an expiration on keys without one (NX)
+	ExpireIfNoTTL
+	// ExpireIfHasTTL only sets an expiration on keys with one (XX)
+	ExpireIfHasTTL
+	// ExpireIfGreater only moves the expiration later (GT); keys without
+	// an expiration count as never expiring
+	ExpireIfGreater
+	// ExpireIfLess only moves the expiration earlier (LT)
+	ExpireIfLess
+)
+
+// Expire sets the expiration of key to the unix time at, in milliseconds.
+// An expiration in the past deletes the key. It reports whether the key
+// exists and the condition allowed the change.
+func (k *Store) Expire(key string, at int64, cond ExpireCondition) bool {
+	value := k.Lookup(key)
+	if value == nil {
+		return false
+	}
+	hasTTL := value.ExpireAt > 0
+	switch cond {
+	case ExpireIfNoTTL:
+		if hasTTL {
+			return false
+		}
+	case ExpireIfHasTTL:
+		if !hasTTL {
+			return false
+		}
+	case ExpireIfGreater:
+		if !hasTTL || at <= value.ExpireAt {
+			return false
+		}
+	case ExpireIfLess:
+		if hasTTL && at >= value.ExpireAt {
+			return false
+		}
+	}
+	if at <= Now() {
+		k.Delete(key)
+		return true
+	}
+	value.ExpireAt = at
+	k.expires[key] = value
+	return true
 }
 
-func (k *Store) SetPx(key string, value string, exp int64) string {
-	now := time.Now().UnixNano() / int64(time.Millisecond)
-	k.db.Store(key, value)
-	k.exp.Store(key, now+exp)
-	return "OK"
+// ExpireTime returns the expiration of key as unix time in milliseconds,
+// -1 if the key exists but has no expiration and -2 if it does not exist.
+func (k *Store) ExpireTime(key string) int64 {
+	value := k.Lookup(key)
+	if value == nil {
+		return -2
+	}
+	if value.ExpireAt == 0 {
+		return -1
+	}
+	return value.ExpireAt
+}
+
+// Persist removes the expiration of key and reports whether it had one.
+func (k *Store) Persist(key string) bool {
+	value := k.Lookup(key)
+	if value == nil || value.ExpireAt == 0 {
+		return false
+	}
+	value.ExpireAt = 0
+	delete(k.expires, key)
+	return true
 }
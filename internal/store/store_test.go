@@ -0,0 +1,186 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSetWithOptionsConditions(t *testing.T) {
+	s := NewStore()
+	if _, _, applied, _ := s.SetWithOptions("lock", []byte("a"), SetOptions{Condition: SetIfNotExists}); !applied {
+		t.Fatalf("Expected NX set on a missing key to apply")
+	}
+	old, existed, applied, _ := s.SetWithOptions("lock", []byte("b"), SetOptions{Condition: SetIfNotExists, Get: true})
+	if applied || !existed || string(old) != "a" {
+		t.Errorf("Expected NX set on an existing key to be refused, got old=%q existed=%v applied=%v", old, existed, applied)
+	}
+	if _, _, applied, _ := s.SetWithOptions("missing", []byte("c"), SetOptions{Condition: SetIfExists}); applied {
+		t.Errorf("Expected XX set on a missing key to be refused")
+	}
+}
+
+func TestWrongType(t *testing.T) {
+	s := NewStore()
+	s.SetValue("list", &Value{Type: ObjList, Encoding: EncodingQuicklist})
+	if _, _, err := s.Get("list"); err != ErrWrongType {
+		t.Errorf("Expected ErrWrongType reading a list as a string, got %v", err)
+	}
+	if _, _, applied, err := s.SetWithOptions("list", []byte("v"), SetOptions{Get: true}); err != ErrWrongType || applied {
+		t.Errorf("Expected SET with GET on a list to fail with ErrWrongType, got %v", err)
+	}
+	if _, _, applied, err := s.SetWithOptions("list", []byte("v"), SetOptions{}); err != nil || !applied {
+		t.Errorf("Expected SET to overwrite a list, got %v", err)
+	}
+	if value := s.Lookup("list"); value.Type != ObjString || value.Encoding != EncodingEmbstr {
+		t.Errorf("Expected an embstr string, got %s/%s", value.Type, value.Encoding)
+	}
+}
+
+func TestSetWithOptionsKeepTTL(t *testing.T) {
+	s := NewStore()
+	expireAt := Now() + 100000
+	s.SetWithOptions("k", []byte("v1"), SetOptions{ExpireAt: expireAt})
+	s.SetWithOptions("k", []byte("v2"), SetOptions{KeepTTL: true})
+	if got := s.ExpireTime("k"); got != expireAt {
+		t.Errorf("Expected KEEPTTL to keep expiration %d, got %d", expireAt, got)
+	}
+	s.SetWithOptions("k", []byte("v3"), SetOptions{})
+	if got := s.ExpireTime("k"); got != -1 {
+		t.Errorf("Expected plain set to clear the expiration, got %d", got)
+	}
+}
+
+func TestExpireConditions(t *testing.T) {
+	s := NewStore()
+	s.Set("k", []byte("v"))
+	now := Now()
+
+	if s.Expire("k", now+1000, ExpireIfHasTTL) {
+		t.Errorf("Expected XX to be refused on a key without expiration")
+	}
+	if s.Expire("k", now+1000, ExpireIfGreater) {
+		t.Errorf("Expected GT to be refused on a key without expiration")
+	}
+	if !s.Expire("k", now+5000, ExpireIfLess) {
+		t.Errorf("Expected LT to apply on a key without expiration")
+	}
+	if s.Expire("k", now+1000, ExpireIfNoTTL) {
+		t.Errorf("Expected NX to be refused on a key with expiration")
+	}
+	if !s.Expire("k", now+9000, ExpireIfGreater) {
+		t.Errorf("Expected GT to apply with a later expiration")
+	}
+	if got := s.ExpireTime("k"); got != now+9000 {
+		t.Errorf("Expected expiration %d, got %d", now+9000, got)
+	}
+	if !s.Persist("k") || s.ExpireTime("k") != -1 {
+		t.Errorf("Expected PERSIST to remove the expiration")
+	}
+	if !s.Expire("k", now-1, ExpireAlways) {
+		t.Errorf("Expected an expiration in the past to apply")
+	}
+	if _, ok, _ := s.Get("k"); ok {
+		t.Errorf("Expected an expiration in the past to delete the key")
+	}
+	if s.ExpireTime("k") != -2 {
+		t.Errorf("Expected -2 for a missing key")
+	}
+}
+
+func TestActiveExpireCycle(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 100; i++ {
+		s.SetWithOptions(fmt.Sprintf("expired:%d", i), []byte("v"), SetOptions{ExpireAt: Now() - 1})
+	}
+	s.SetWithOptions("alive", []byte("v"), SetOptions{ExpireAt: Now() + 100000})
+	s.Set("persistent", []byte("v"))
+
+	var expired []string
+	s.SetExpireHook(func(key string) {
+		expired = append(expired, key)
+	})
+	s.activeExpireCycle()
+
+	if s.keys.Len() != 2 || len(s.expires) != 1 {
+		t.Errorf("Expected only the 2 live keys to remain, got %d keys and %d expires", s.keys.Len(), len(s.expires))
+	}
+	if len(expired) != 100 {
+		t.Errorf("Expected the 100 expired keys to be reported, got %d", len(expired))
+	}
+	if got := s.Stats().ExpiredKeys; got != 100 {
+		t.Errorf("Expected 100 expired keys, got %d", got)
+	}
+}
+
+func TestSetHzClamps(t *testing.T) {
+	s := NewStore()
+	s.SetHz(0)
+	if s.Hz() != MinHz {
+		t.Errorf("Expected hz %d, got %d", MinHz, s.Hz())
+	}
+	s.SetHz(1000)
+	if s.Hz() != MaxHz {
+		t.Errorf("Expected hz %d, got %d", MaxHz, s.Hz())
+	}
+}
+
+func TestStringIntEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		encoding Encoding
+	}{
+		{"12345", EncodingInt},
+		{"-9223372036854775808", EncodingInt},
+		{"9223372036854775808", EncodingEmbstr},
+		{"012", EncodingEmbstr},
+		{"+1", EncodingEmbstr},
+		{"-0", EncodingEmbstr},
+		{"", EncodingEmbstr},
+	} {
+		v := NewString([]byte(tc.s))
+		if v.Encoding != tc.encoding {
+			t.Errorf("Expected %q to be %s, got %s", tc.s, tc.encoding, v.Encoding)
+		}
+		if string(v.Bytes()) != tc.s || v.StrLen() != len(tc.s) {
+			t.Errorf("Expected %q back, got %q with length %d", tc.s, v.Bytes(), v.StrLen())
+		}
+		if _, ok := v.Int(); ok != (tc.encoding == EncodingInt) {
+			t.Errorf("Expected Int to report %v for %q", tc.encoding == EncodingInt, tc.s)
+		}
+	}
+}
+
+func TestStringUnshare(t *testing.T) {
+	arg := []byte("abc")
+	v := NewString(arg)
+	b := append(v.Unshare(), 'd')
+	v.SetBytes(b)
+	if string(arg) != "abc" || string(v.Bytes()) != "abcd" || v.Encoding != EncodingRaw {
+		t.Fatalf("Expected the argument to be copied, got %q and %q %s", arg, v.Bytes(), v.Encoding)
+	}
+
+	// owned bytes are changed in place until they are handed out
+	v.SetBytes(append(v.Unshare()[:0], "xyz"...))
+	owned := v.Unshare()
+	if &owned[0] != &v.Peek()[0] {
+		t.Errorf("Expected owned bytes not to be copied")
+	}
+	reply := v.Bytes()
+	if b := v.Unshare(); &b[0] == &reply[0] {
+		t.Errorf("Expected bytes handed out by Bytes to be copied")
+	}
+
+	dup := v.Copy()
+	changed := dup.Unshare()
+	changed[0] = 'X'
+	dup.SetBytes(changed)
+	if string(v.Peek()) != "xyz" || string(dup.Peek()) != "Xyz" {
+		t.Errorf("Expected a copy to change independently, got %q and %q", v.Peek(), dup.Peek())
+	}
+
+	n := NewInt(12)
+	n.SetBytes(append(n.Unshare(), '3'))
+	if string(n.Peek()) != "123" || n.Encoding != EncodingRaw {
+		t.Errorf("Expected an int to turn raw, got %q %s", n.Peek(), n.Encoding)
+	}
+}
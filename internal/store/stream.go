@@ -0,0 +1,284 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// StreamID identifies a stream entry by the unix time in milliseconds it
+// was added at and a sequence number among the entries of that
+// millisecond.
+type StreamID struct {
+	Ms, Seq uint64
+}
+
+// MaxStreamID is the largest possible ID.
+var MaxStreamID = StreamID{math.MaxUint64, math.MaxUint64}
+
+var (
+	ErrStreamIDZero     = errors.New("ERR The ID specified in XADD must be greater than 0-0")
+	ErrStreamIDTooSmall = errors.New("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	ErrStreamExhausted  = errors.New("ERR The stream has exhausted the last possible ID, unable to add more items")
+)
+
+// ParseStreamID parses an ID written as ms-seq, or as ms alone in which
+// case the sequence is missingSeq.
+func ParseStreamID(s []byte, missingSeq uint64) (StreamID, bool) {
+	ms, seq := s, []byte(nil)
+	if i := bytes.IndexByte(s, '-'); i >= 0 {
+		ms, seq = s[:i], s[i+1:]
+	}
+	var id StreamID
+	var err error
+	if id.Ms, err = strconv.ParseUint(string(ms), 10, 64); err != nil {
+		return id, false
+	}
+	if seq == nil {
+		id.Seq = missingSeq
+		return id, true
+	}
+	if id.Seq, err = strconv.ParseUint(string(seq), 10, 64); err != nil {
+		return id, false
+	}
+	return id, true
+}
+
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// Less reports whether id sorts before other.
+func (id StreamID) Less(other StreamID) bool {
+	return id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq)
+}
+
+// Incr returns the ID following id. It reports false if id is the largest.
+func (id StreamID) Incr() (StreamID, bool) {
+	switch {
+	case id == MaxStreamID:
+		return id, false
+	case id.Seq == math.MaxUint64:
+		return StreamID{id.Ms + 1, 0}, true
+	}
+	return StreamID{id.Ms, id.Seq + 1}, true
+}
+
+// Decr returns the ID preceding id. It reports false if id is 0-0.
+func (id StreamID) Decr() (StreamID, bool) {
+	switch {
+	case id == StreamID{}:
+		return id, false
+	case id.Seq == 0:
+		return StreamID{id.Ms - 1, math.MaxUint64}, true
+	}
+	return StreamID{id.Ms, id.Seq - 1}, true
+}
+
+// StreamEntry is an entry of a stream, Fields alternating field names and
+// values.
+type StreamEntry struct {
+	ID     StreamID
+	Fields [][]byte
+}
+
+// Stream is an append only log of entries sorted by ID. Entries are kept
+// in a slice: new entries always have the greatest ID so adding appends,
+// lookups are binary searches and trimming drops a prefix.
+type Stream struct {
+	entries []StreamEntry
+	// lastID is the ID of the last entry ever added, which may have been
+	// deleted since
+	lastID StreamID
+	// entriesAdded counts the entries ever added
+	entriesAdded int64
+	// maxDeletedID is the greatest ID deleted with Delete
+	maxDeletedID StreamID
+	groups       map[string]*StreamGroup
+}
+
+// NewStream returns an empty stream value.
+func NewStream() *Value {
+	return &Value{Type: ObjStream, Encoding: EncodingStream, Ptr: &Stream{}}
+}
+
+func (v *Value) Stream() *Stream {
+	return v.Ptr.(*Stream)
+}
+
+// copy returns a stream with the same entries and consumer groups.
+func (s *Stream) copy() *Stream {
+	dup := *s
+	dup.entries = append([]StreamEntry(nil), s.entries...)
+	dup.groups = nil
+	for name, g := range s.groups {
+		if dup.groups == nil {
+			dup.groups = map[string]*StreamGroup{}
+		}
+		dup.groups[name] = g.copy()
+	}
+	return &dup
+}
+
+// Len returns the number of entries.
+func (s *Stream) Len() int {
+	return len(s.entries)
+}
+
+// LastID returns the ID of the last entry added to the stream.
+func (s *Stream) LastID() StreamID {
+	return s.lastID
+}
+
+// AutoID returns the ID to give to a new entry added at time now, in
+// milliseconds. IDs keep increasing when the clock goes backwards.
+func (s *Stream) AutoID(now uint64) (StreamID, error) {
+	if now > s.lastID.Ms {
+		return StreamID{now, 0}, nil
+	}
+	id, ok := s.lastID.Incr()
+	if !ok {
+		return id, ErrStreamExhausted
+	}
+	return id, nil
+}
+
+// AutoSeqID returns the ID to give to a new entry added with the given
+// milliseconds part.
+func (s *Stream) AutoSeqID(ms uint64) (StreamID, error) {
+	switch {
+	case ms > s.lastID.Ms:
+		return StreamID{ms, 0}, nil
+	case ms < s.lastID.Ms || s.lastID.Seq == math.MaxUint64:
+		return StreamID{}, ErrStreamIDTooSmall
+	}
+	return StreamID{ms, s.lastID.Seq + 1}, nil
+}
+
+// Add appends an entry, whose ID must be greater than the last ID.
+func (s *Stream) Add(id StreamID, fields [][]byte) error {
+	switch {
+	case id == StreamID{}:
+		return ErrStreamIDZero
+	case !s.lastID.Less(id):
+		return ErrStreamIDTooSmall
+	}
+	s.entries = append(s.entries, StreamEntry{id, fields})
+	s.lastID = id
+	s.entriesAdded++
+	return nil
+}
+
+// EntriesAdded returns the number of entries ever added to the stream.
+func (s *Stream) EntriesAdded() int64 {
+	return s.entriesAdded
+}
+
+// MaxDeletedID returns the greatest ID deleted from the stream, not
+// counting trimming.
+func (s *Stream) MaxDeletedID() StreamID {
+	return s.maxDeletedID
+}
+
+// First returns the first entry.
+func (s *Stream) First() (StreamEntry, bool) {
+	if len(s.entries) == 0 {
+		return StreamEntry{}, false
+	}
+	return s.entries[0], true
+}
+
+// Last returns the last entry.
+func (s *Stream) Last() (StreamEntry, bool) {
+	if len(s.entries) == 0 {
+		return StreamEntry{}, false
+	}
+	return s.entries[len(s.entries)-1], true
+}
+
+// Entry returns the entry with the given ID.
+func (s *Stream) Entry(id StreamID) (StreamEntry, bool) {
+	i := s.search(id)
+	if i == len(s.entries) || s.entries[i].ID != id {
+		return StreamEntry{}, false
+	}
+	return s.entries[i], true
+}
+
+// search returns the index of the first entry with an ID not less than id.
+func (s *Stream) search(id StreamID) int {
+	return sort.Search(len(s.entries), func(i int) bool { return !s.entries[i].ID.Less(id) })
+}
+
+// Range calls fn for the entries with IDs from start to end, from end to
+// start when reverse is set, until it returns false.
+func (s *Stream) Range(start, end StreamID, reverse bool, fn func(entry StreamEntry) bool) {
+	if end.Less(start) {
+		return
+	}
+	first := s.search(start)
+	last := s.search(end)
+	if last == len(s.entries) || end.Less(s.entries[last].ID) {
+		last--
+	}
+	if reverse {
+		for i := last; i >= first; i-- {
+			if !fn(s.entries[i]) {
+				return
+			}
+		}
+		return
+	}
+	for i := first; i <= last; i++ {
+		if !fn(s.entries[i]) {
+			return
+		}
+	}
+}
+
+// Delete removes the entry with the given ID and reports whether it was
+// there.
+func (s *Stream) Delete(id StreamID) bool {
+	i := s.search(id)
+	if i == len(s.entries) || s.entries[i].ID != id {
+		return false
+	}
+	if s.maxDeletedID.Less(id) {
+		s.maxDeletedID = id
+	}
+	copy(s.entries[i:], s.entries[i+1:])
+	s.entries[len(s.entries)-1] = StreamEntry{}
+	s.entries = s.entries[:len(s.entries)-1]
+	return true
+}
+
+// TrimMaxLen removes the oldest entries until at most maxLen are left,
+// but no more than limit entries unless limit is 0. It returns the number
+// of entries removed.
+func (s *Stream) TrimMaxLen(maxLen, limit int) int {
+	return s.trim(len(s.entries)-maxLen, limit)
+}
+
+// TrimMinID removes the entries with IDs less than minID, but no more than
+// limit entries unless limit is 0. It returns the number of entries
+// removed.
+func (s *Stream) TrimMinID(minID StreamID, limit int) int {
+	return s.trim(s.search(minID), limit)
+}
+
+func (s *Stream) trim(n, limit int) int {
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	if n <= 0 {
+		return 0
+	}
+	// the dropped prefix is released once append reallocates the slice
+	for i := 0; i < n; i++ {
+		s.entries[i] = StreamEntry{}
+	}
+	s.entries = s.entries[n:]
+	return n
+}
@@ -0,0 +1,324 @@
+package store
+
+import (
+	"sort"
+)
+
+// StreamGroup is a consumer group of a stream. It tracks the last entry
+// delivered to its consumers and the entries delivered but not yet
+// acknowledged, in a pending entries list shared by the group and split
+// by consumer.
+type StreamGroup struct {
+	Name   string
+	LastID StreamID
+	// EntriesRead is the number of entries of the stream the group read,
+	// or -1 when it can't be known, e.g. after the last ID was set
+	EntriesRead int64
+	pending     pendingList
+	consumers   map[string]*StreamConsumer
+}
+
+// StreamConsumer is a consumer of a group.
+type StreamConsumer struct {
+	Name string
+	// SeenTime is when the consumer last tried to read or claim entries
+	// and ActiveTime when it last got some, -1 if never
+	SeenTime   int64
+	ActiveTime int64
+	pending    pendingList
+}
+
+// StreamPending is an entry delivered to a consumer and not acknowledged
+// yet.
+type StreamPending struct {
+	ID            StreamID
+	Consumer      *StreamConsumer
+	DeliveryTime  int64
+	DeliveryCount int64
+}
+
+// pendingList is a list of pending entries sorted by ID.
+type pendingList []*StreamPending
+
+func (l pendingList) search(id StreamID) int {
+	return sort.Search(len(l), func(i int) bool { return !l[i].ID.Less(id) })
+}
+
+func (l pendingList) find(id StreamID) *StreamPending {
+	if i := l.search(id); i < len(l) && l[i].ID == id {
+		return l[i]
+	}
+	return nil
+}
+
+func (l *pendingList) insert(p *StreamPending) {
+	i := l.search(p.ID)
+	*l = append(*l, nil)
+	copy((*l)[i+1:], (*l)[i:])
+	(*l)[i] = p
+}
+
+func (l *pendingList) remove(id StreamID) {
+	if i := l.search(id); i < len(*l) && (*l)[i].ID == id {
+		*l = append((*l)[:i], (*l)[i+1:]...)
+	}
+}
+
+// rangeFrom calls fn for the pending entries with IDs from start on until
+// it returns false. fn may remove the entry it is called for.
+func (l *pendingList) rangeFrom(start StreamID, fn func(p *StreamPending) bool) {
+	for i := l.search(start); i < len(*l); {
+		p := (*l)[i]
+		if !fn(p) {
+			return
+		}
+		if i < len(*l) && (*l)[i] == p {
+			i++
+		}
+	}
+}
+
+// copy returns a group with the same consumers and pending entries.
+func (g *StreamGroup) copy() *StreamGroup {
+	dup := &StreamGroup{Name: g.Name, LastID: g.LastID, EntriesRead: g.EntriesRead, consumers: map[string]*StreamConsumer{}}
+	for name, c := range g.consumers {
+		dup.consumers[name] = &StreamConsumer{Name: c.Name, SeenTime: c.SeenTime, ActiveTime: c.ActiveTime}
+	}
+	// the group list is sorted, so appending keeps the consumer lists
+	// sorted too
+	dup.pending = make(pendingList, len(g.pending))
+	for i, p := range g.pending {
+		np := *p
+		np.Consumer = dup.consumers[p.Consumer.Name]
+		np.Consumer.pending = append(np.Consumer.pending, &np)
+		dup.pending[i] = &np
+	}
+	return dup
+}
+
+// CreateGroup adds a group whose last delivered ID is lastID. It returns
+// nil if a group of that name exists.
+func (s *Stream) CreateGroup(name string, lastID StreamID, entriesRead int64) *StreamGroup {
+	if s.groups == nil {
+		s.groups = map[string]*StreamGroup{}
+	}
+	if _, ok := s.groups[name]; ok {
+		return nil
+	}
+	g := &StreamGroup{Name: name, LastID: lastID, EntriesRead: entriesRead, consumers: map[string]*StreamConsumer{}}
+	s.groups[name] = g
+	return g
+}
+
+// Group returns the group of the given name, or nil.
+func (s *Stream) Group(name string) *StreamGroup {
+	return s.groups[name]
+}
+
+// DestroyGroup removes a group and reports whether it existed.
+func (s *Stream) DestroyGroup(name string) bool {
+	if _, ok := s.groups[name]; !ok {
+		return false
+	}
+	delete(s.groups, name)
+	return true
+}
+
+// Groups returns the groups sorted by name.
+func (s *Stream) Groups() []*StreamGroup {
+	groups := make([]*StreamGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// hasTombstones reports whether entries with IDs from start on may have
+// been deleted.
+func (s *Stream) hasTombstones(start StreamID) bool {
+	if len(s.entries) == 0 || s.maxDeletedID == (StreamID{}) || s.maxDeletedID.Less(s.entries[0].ID) {
+		return false
+	}
+	return !s.maxDeletedID.Less(start)
+}
+
+// entriesReadUpTo estimates how many entries were added up to id, or
+// returns -1 when deletions make it impossible to tell.
+func (s *Stream) entriesReadUpTo(id StreamID) int64 {
+	if s.entriesAdded == 0 {
+		return 0
+	}
+	if len(s.entries) == 0 && !s.lastID.Less(id) {
+		return s.entriesAdded
+	}
+	if id == s.lastID {
+		return s.entriesAdded
+	}
+	if s.lastID.Less(id) || len(s.entries) == 0 {
+		return -1
+	}
+	first := s.entries[0].ID
+	if s.maxDeletedID == (StreamID{}) || s.maxDeletedID.Less(first) {
+		// no entry was deleted past the first one
+		switch {
+		case id.Less(first):
+			return s.entriesAdded - int64(len(s.entries))
+		case id == first:
+			return s.entriesAdded - int64(len(s.entries)) + 1
+		}
+	}
+	return -1
+}
+
+// Lag returns the number of entries not read by the group yet. It reports
+// false when it can't be known.
+func (s *Stream) Lag(g *StreamGroup) (int64, bool) {
+	if s.entriesAdded == 0 {
+		return 0, true
+	}
+	entriesRead := g.EntriesRead
+	if entriesRead < 0 || s.hasTombstones(g.LastID) {
+		entriesRead = s.entriesReadUpTo(g.LastID)
+	}
+	if entriesRead < 0 {
+		return 0, false
+	}
+	return s.entriesAdded - entriesRead, true
+}
+
+// ReadGroup delivers to consumer c up to count entries added after the
+// last ID delivered to the group, all of them if count is 0, and returns
+// them. Unless noAck is set they are added to the pending entries lists.
+func (s *Stream) ReadGroup(g *StreamGroup, c *StreamConsumer, count int, noAck bool, now int64) []StreamEntry {
+	start, ok := g.LastID.Incr()
+	if !ok {
+		return nil
+	}
+	var entries []StreamEntry
+	s.Range(start, MaxStreamID, false, func(entry StreamEntry) bool {
+		if g.EntriesRead >= 0 && !s.hasTombstones(entry.ID) {
+			g.EntriesRead++
+		} else {
+			g.EntriesRead = s.entriesReadUpTo(entry.ID)
+		}
+		g.LastID = entry.ID
+		if !noAck {
+			if p := g.pending.find(entry.ID); p != nil {
+				// the entry was delivered before the last ID was set back
+				g.Claim(p, c, now)
+				p.DeliveryCount = 1
+			} else {
+				g.AddPending(entry.ID, c, now)
+			}
+		}
+		entries = append(entries, entry)
+		return len(entries) != count
+	})
+	return entries
+}
+
+// Consumer returns the consumer of the given name, or nil.
+func (g *StreamGroup) Consumer(name string) *StreamConsumer {
+	return g.consumers[name]
+}
+
+// CreateConsumer returns the consumer of the given name, added at time
+// now if needed, and reports whether it was added.
+func (g *StreamGroup) CreateConsumer(name string, now int64) (*StreamConsumer, bool) {
+	if c, ok := g.consumers[name]; ok {
+		return c, false
+	}
+	c := &StreamConsumer{Name: name, SeenTime: now, ActiveTime: -1}
+	g.consumers[name] = c
+	return c, true
+}
+
+// DeleteConsumer removes a consumer along with its pending entries and
+// returns how many it had. It reports false if there is no such consumer.
+func (g *StreamGroup) DeleteConsumer(name string) (int, bool) {
+	c, ok := g.consumers[name]
+	if !ok {
+		return 0, false
+	}
+	for _, p := range c.pending {
+		g.pending.remove(p.ID)
+	}
+	delete(g.consumers, name)
+	return len(c.pending), true
+}
+
+// Consumers returns the consumers sorted by name.
+func (g *StreamGroup) Consumers() []*StreamConsumer {
+	consumers := make([]*StreamConsumer, 0, len(g.consumers))
+	for _, c := range g.consumers {
+		consumers = append(consumers, c)
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].Name < consumers[j].Name })
+	return consumers
+}
+
+// PendingLen returns the number of entries pending for the group.
+func (g *StreamGroup) PendingLen() int {
+	return len(g.pending)
+}
+
+// Pending returns the pending entry with the given ID, or nil.
+func (g *StreamGroup) Pending(id StreamID) *StreamPending {
+	return g.pending.find(id)
+}
+
+// PendingBounds returns the smallest and greatest IDs pending for the
+// group, which must have some.
+func (g *StreamGroup) PendingBounds() (StreamID, StreamID) {
+	return g.pending[0].ID, g.pending[len(g.pending)-1].ID
+}
+
+// PendingRange calls fn for the entries pending for the group with IDs
+// from start on until it returns false. fn may acknowledge the entry it is
+// called for.
+func (g *StreamGroup) PendingRange(start StreamID, fn func(p *StreamPending) bool) {
+	g.pending.rangeFrom(start, fn)
+}
+
+// AddPending records the entry with the given ID as delivered to consumer
+// c at time now.
+func (g *StreamGroup) AddPending(id StreamID, c *StreamConsumer, now int64) *StreamPending {
+	p := &StreamPending{ID: id, Consumer: c, DeliveryTime: now, DeliveryCount: 1}
+	g.pending.insert(p)
+	c.pending.insert(p)
+	return p
+}
+
+// Claim transfers a pending entry to consumer c, delivered at time now.
+func (g *StreamGroup) Claim(p *StreamPending, c *StreamConsumer, now int64) {
+	if p.Consumer != c {
+		p.Consumer.pending.remove(p.ID)
+		c.pending.insert(p)
+		p.Consumer = c
+	}
+	p.DeliveryTime = now
+}
+
+// Ack removes the entry with the given ID from the pending entries lists
+// and reports whether it was there.
+func (g *StreamGroup) Ack(id StreamID) bool {
+	p := g.pending.find(id)
+	if p == nil {
+		return false
+	}
+	g.pending.remove(id)
+	p.Consumer.pending.remove(id)
+	return true
+}
+
+// PendingLen returns the number of entries pending for the consumer.
+func (c *StreamConsumer) PendingLen() int {
+	return len(c.pending)
+}
+
+// PendingRange calls fn for the entries pending for the consumer with IDs
+// from start on until it returns false.
+func (c *StreamConsumer) PendingRange(start StreamID, fn func(p *StreamPending) bool) {
+	c.pending.rangeFrom(start, fn)
+}
@@ -0,0 +1,77 @@
+package store
+
+import "testing"
+
+func TestStreamGroupRead(t *testing.T) {
+	s := NewStream().Stream()
+	for ms := uint64(1); ms <= 5; ms++ {
+		s.Add(StreamID{ms, 0}, nil)
+	}
+	g := s.CreateGroup("g", StreamID{}, 0)
+	if s.CreateGroup("g", StreamID{}, 0) != nil {
+		t.Error("Expected a duplicate group to be refused")
+	}
+	alice, _ := g.CreateConsumer("alice", 100)
+	bob, _ := g.CreateConsumer("bob", 100)
+	if entries := s.ReadGroup(g, alice, 2, false, 100); len(entries) != 2 || entries[1].ID != (StreamID{2, 0}) {
+		t.Fatalf("Expected 1-0 and 2-0, got %v", entries)
+	}
+	if entries := s.ReadGroup(g, bob, 0, false, 100); len(entries) != 3 {
+		t.Fatalf("Expected the 3 remaining entries, got %v", entries)
+	}
+	if g.LastID != (StreamID{5, 0}) || g.EntriesRead != 5 {
+		t.Errorf("Expected the group at 5-0 with 5 entries read, got %s and %d", g.LastID, g.EntriesRead)
+	}
+	if lag, ok := s.Lag(g); !ok || lag != 0 {
+		t.Errorf("Expected no lag, got %d %v", lag, ok)
+	}
+	if g.PendingLen() != 5 || alice.PendingLen() != 2 || bob.PendingLen() != 3 {
+		t.Errorf("Expected 5 pending entries split 2/3, got %d %d/%d", g.PendingLen(), alice.PendingLen(), bob.PendingLen())
+	}
+
+	p := g.Pending(StreamID{4, 0})
+	g.Claim(p, alice, 200)
+	if p.Consumer != alice || p.DeliveryTime != 200 || alice.PendingLen() != 3 || bob.PendingLen() != 2 {
+		t.Errorf("Expected 4-0 to move to alice, got %s with %d/%d", p.Consumer.Name, alice.PendingLen(), bob.PendingLen())
+	}
+	if !g.Ack(StreamID{1, 0}) || g.Ack(StreamID{1, 0}) {
+		t.Error("Expected 1-0 to be acknowledged once")
+	}
+	if first, last := g.PendingBounds(); first != (StreamID{2, 0}) || last != (StreamID{5, 0}) {
+		t.Errorf("Expected pending entries from 2-0 to 5-0, got %s to %s", first, last)
+	}
+	if n, ok := g.DeleteConsumer("bob"); !ok || n != 2 || g.PendingLen() != 2 {
+		t.Errorf("Expected bob to leave 2 entries, got %d with %d left", n, g.PendingLen())
+	}
+
+	// pending entries can be acknowledged while ranging over them
+	var seen []StreamID
+	g.PendingRange(StreamID{}, func(p *StreamPending) bool {
+		seen = append(seen, p.ID)
+		g.Ack(p.ID)
+		return true
+	})
+	if len(seen) != 2 || g.PendingLen() != 0 || alice.PendingLen() != 0 {
+		t.Errorf("Expected to ack 2 entries, got %v with %d left", seen, g.PendingLen())
+	}
+}
+
+func TestStreamGroupLag(t *testing.T) {
+	s := NewStream().Stream()
+	for ms := uint64(1); ms <= 4; ms++ {
+		s.Add(StreamID{ms, 0}, nil)
+	}
+	g := s.CreateGroup("g", StreamID{}, -1)
+	if lag, ok := s.Lag(g); !ok || lag != 4 {
+		t.Errorf("Expected a lag of 4 before the first entry, got %d %v", lag, ok)
+	}
+	s.Delete(StreamID{3, 0})
+	if _, ok := s.Lag(g); ok {
+		t.Error("Expected the lag to be unknown with a deleted entry ahead")
+	}
+	c, _ := g.CreateConsumer("c", 0)
+	s.ReadGroup(g, c, 0, true, 0)
+	if lag, ok := s.Lag(g); !ok || lag != 0 || g.PendingLen() != 0 {
+		t.Errorf("Expected no lag nor pending entries with NOACK, got %d %v %d", lag, ok, g.PendingLen())
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStreamIDs(t *testing.T) {
+	s := NewStream().Stream()
+	if err := s.Add(StreamID{}, nil); err != ErrStreamIDZero {
+		t.Errorf("Expected ErrStreamIDZero, got %v", err)
+	}
+	id, _ := s.AutoSeqID(0)
+	if id != (StreamID{0, 1}) {
+		t.Errorf("Expected 0-1, got %s", id)
+	}
+	s.Add(StreamID{5, 3}, nil)
+	if err := s.Add(StreamID{5, 3}, nil); err != ErrStreamIDTooSmall {
+		t.Errorf("Expected ErrStreamIDTooSmall, got %v", err)
+	}
+	if id, _ := s.AutoID(4); id != (StreamID{5, 4}) {
+		t.Errorf("Expected 5-4 when the clock went back, got %s", id)
+	}
+	if id, _ := s.AutoID(9); id != (StreamID{9, 0}) {
+		t.Errorf("Expected 9-0, got %s", id)
+	}
+	if _, err := s.AutoSeqID(4); err != ErrStreamIDTooSmall {
+		t.Errorf("Expected ErrStreamIDTooSmall, got %v", err)
+	}
+	s.Add(MaxStreamID, nil)
+	if _, err := s.AutoID(0); err != ErrStreamExhausted {
+		t.Errorf("Expected ErrStreamExhausted, got %v", err)
+	}
+	if id, ok := ParseStreamID([]byte("12"), math.MaxUint64); !ok || id != (StreamID{12, math.MaxUint64}) {
+		t.Errorf("Expected 12 with the largest sequence, got %s", id)
+	}
+	for _, bad := range []string{"", "1-", "-1", "a-1", "1-2-3", "+1"} {
+		if _, ok := ParseStreamID([]byte(bad), 0); ok {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestStreamRangeAndTrim(t *testing.T) {
+	s := NewStream().Stream()
+	for i := uint64(1); i <= 10; i++ {
+		s.Add(StreamID{i, 0}, nil)
+	}
+	var got []uint64
+	s.Range(StreamID{3, 1}, StreamID{6, 0}, true, func(entry StreamEntry) bool {
+		got = append(got, entry.ID.Ms)
+		return true
+	})
+	if len(got) != 3 || got[0] != 6 || got[2] != 4 {
+		t.Errorf("Expected 6 5 4, got %v", got)
+	}
+	if !s.Delete(StreamID{5, 0}) || s.Delete(StreamID{5, 0}) {
+		t.Errorf("Expected 5-0 to be deleted once")
+	}
+	if n := s.TrimMaxLen(6, 2); n != 2 || s.Len() != 7 {
+		t.Errorf("Expected 2 entries trimmed to 7, got %d trimmed to %d", n, s.Len())
+	}
+	if n := s.TrimMinID(StreamID{7, 0}, 0); n != 3 || s.Len() != 4 {
+		t.Errorf("Expected 3 entries trimmed to 4, got %d trimmed to %d", n, s.Len())
+	}
+	if s.LastID() != (StreamID{10, 0}) {
+		t.Errorf("Expected the last ID 10-0, got %s", s.LastID())
+	}
+}
@@ -0,0 +1,271 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrWrongType is returned when a key holds a value of another type than
+// the operation expects.
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// ObjectType is the data type of a value, as reported by TYPE.
+type ObjectType int
+
+const (
+	ObjString ObjectType = iota
+	ObjList
+	ObjSet
+	ObjZSet
+	ObjHash
+	ObjStream
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjString:
+		return "string"
+	case ObjList:
+		return "list"
+	case ObjSet:
+		return "set"
+	case ObjZSet:
+		return "zset"
+	case ObjHash:
+		return "hash"
+	case ObjStream:
+		return "stream"
+	}
+	return "unknown"
+}
+
+// Encoding is the internal representation of a value, as reported by
+// OBJECT ENCODING. A type may switch to a bigger encoding as it grows.
+type Encoding int
+
+const (
+	EncodingRaw Encoding = iota
+	EncodingInt
+	EncodingEmbstr
+	EncodingListpack
+	EncodingQuicklist
+	EncodingIntset
+	EncodingHashtable
+	EncodingSkiplist
+	EncodingStream
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingRaw:
+		return "raw"
+	case EncodingInt:
+		return "int"
+	case EncodingEmbstr:
+		return "embstr"
+	case EncodingListpack:
+		return "listpack"
+	case EncodingQuicklist:
+		return "quicklist"
+	case EncodingIntset:
+		return "intset"
+	case EncodingHashtable:
+		return "hashtable"
+	case EncodingSkiplist:
+		return "skiplist"
+	case EncodingStream:
+		return "stream"
+	}
+	return "unknown"
+}
+
+// embstrSizeLimit is the longest string Redis allocates together with its
+// object header.
+const embstrSizeLimit = 44
+
+// Value is an entry of the keyspace. Ptr holds the data in the layout
+// given by Encoding, e.g. a []byte for raw and embstr strings.
+type Value struct {
+	Type     ObjectType
+	Encoding Encoding
+	Ptr      interface{}
+	// ExpireAt is the unix time in milliseconds the value expires at, 0
+	// means it does not expire
+	ExpireAt int64
+	// shared is set once the bytes of a string may be referenced outside
+	// the value, e.g. by a reply or a copy, so Unshare copies them before
+	// they are changed in place
+	shared bool
+}
+
+// NewString returns a string value holding b. Strings that are the
+// canonical form of a 64-bit integer are stored as the integer.
+func NewString(b []byte) *Value {
+	if n, ok := parseInt(b); ok {
+		return NewInt(n)
+	}
+	encoding := EncodingRaw
+	if len(b) <= embstrSizeLimit {
+		encoding = EncodingEmbstr
+	}
+	// b is the caller's, e.g. the argument of a command
+	return &Value{Type: ObjString, Encoding: encoding, Ptr: b, shared: true}
+}
+
+// NewInt returns a string value holding n with the int encoding.
+func NewInt(n int64) *Value {
+	return &Value{Type: ObjString, Encoding: EncodingInt, Ptr: n}
+}
+
+// Bytes returns the contents of a string value. The slice may be shared
+// with the value and must not be modified, but it can be kept: the value
+// no longer changes it in place.
+func (v *Value) Bytes() []byte {
+	if v.Encoding == EncodingInt {
+		return strconv.AppendInt(nil, v.Ptr.(int64), 10)
+	}
+	v.shared = true
+	return v.Ptr.([]byte)
+}
+
+// Peek returns the contents of a string value like Bytes, for reading
+// them while the database is locked. The slice must not be kept past the
+// command, since the value may change it in place later on.
+func (v *Value) Peek() []byte {
+	if v.Encoding == EncodingInt {
+		return strconv.AppendInt(nil, v.Ptr.(int64), 10)
+	}
+	return v.Ptr.([]byte)
+}
+
+// Unshare returns the contents of a string value to be changed in place,
+// copying them first if they may be referenced outside the value. Like
+// dbUnshareStringValue in Redis it makes SETBIT and APPEND O(1) while the
+// string is not read in between. Pass the result to SetBytes once done.
+func (v *Value) Unshare() []byte {
+	if v.Encoding == EncodingInt {
+		return strconv.AppendInt(nil, v.Ptr.(int64), 10)
+	}
+	b := v.Ptr.([]byte)
+	if v.shared {
+		b = append([]byte(nil), b...)
+	}
+	return b
+}
+
+// SetBytes stores b, obtained from Unshare and possibly grown, as the
+// contents of a string value. The value owns b from now on, and is raw
+// encoded like the strings Redis changes in place.
+func (v *Value) SetBytes(b []byte) {
+	v.Encoding, v.Ptr, v.shared = EncodingRaw, b, false
+}
+
+// Int returns the integer a string value holds. It reports false if the
+// string is not the canonical form of a 64-bit integer.
+func (v *Value) Int() (int64, bool) {
+	if v.Encoding == EncodingInt {
+		return v.Ptr.(int64), true
+	}
+	return parseInt(v.Ptr.([]byte))
+}
+
+// StrLen returns the length of a string value.
+func (v *Value) StrLen() int {
+	if v.Encoding == EncodingInt {
+		var buf [20]byte
+		return len(strconv.AppendInt(buf[:0], v.Ptr.(int64), 10))
+	}
+	return len(v.Ptr.([]byte))
+}
+
+// Copy returns a value with the same contents and expiration that can be
+// changed independently. Elements are shared since they are never
+// modified in place.
+func (v *Value) Copy() *Value {
+	dup := &Value{Type: v.Type, Encoding: v.Encoding, ExpireAt: v.ExpireAt}
+	switch ptr := v.Ptr.(type) {
+	case *Quicklist:
+		list := NewQuicklist()
+		ptr.Range(0, ptr.Len()-1, func(_ int, value []byte) bool {
+			list.PushTail(value)
+			return true
+		})
+		dup.Ptr = list
+	case *listpack:
+		lp := append(listpack(nil), *ptr...)
+		dup.Ptr = &lp
+	case *intset:
+		is := append(intset(nil), *ptr...)
+		dup.Ptr = &is
+	case *Dict:
+		dup.Ptr = ptr.copy()
+	case *zset:
+		zs := NewZSet()
+		v.ZSetRangeByRank(0, v.ZSetLen()-1, false, func(member []byte, score float64) bool {
+			zs.ZSetAdd(member, score)
+			return true
+		})
+		dup.Ptr = zs.Ptr
+	case *Stream:
+		dup.Ptr = ptr.copy()
+	default:
+		// strings share their bytes until either one is changed
+		dup.Ptr = v.Ptr
+		v.shared, dup.shared = true, true
+	}
+	return dup
+}
+
+// NewList returns an empty list value.
+func NewList() *Value {
+	return &Value{Type: ObjList, Encoding: EncodingListpack, Ptr: NewQuicklist()}
+}
+
+func (v *Value) List() *Quicklist {
+	return v.Ptr.(*Quicklist)
+}
+
+// release drops the elements of a value nothing references any more, the
+// way lazy freeing takes it apart in the background.
+func (v *Value) release() {
+	switch p := v.Ptr.(type) {
+	case *listpack:
+		*p = nil
+	case *intset:
+		*p = nil
+	case *Quicklist:
+		p.release()
+	case *Dict:
+		p.release(nil)
+	case *zset:
+		p.dict.release(nil)
+	}
+}
+
+// Len returns the number of elements of a collection value.
+func (v *Value) Len() int {
+	switch v.Type {
+	case ObjList:
+		return v.List().Len()
+	case ObjHash:
+		return v.HashLen()
+	case ObjSet:
+		return v.SetLen()
+	case ObjZSet:
+		return v.ZSetLen()
+	}
+	return 1
+}
+
+// updateListEncoding reports lists as listpack while they fit in a single
+// node. Like in Redis they only turn back once they shrink to half of the
+// limits, so a list at the edge does not flip at every write.
+func (v *Value) updateListEncoding() {
+	list := v.List()
+	switch {
+	case v.Encoding == EncodingListpack && (list.count > quicklistNodeMaxEntries || list.bytes > quicklistNodeMaxBytes):
+		v.Encoding = EncodingQuicklist
+	case v.Encoding == EncodingQuicklist && list.count <= quicklistNodeMaxEntries/2 && list.bytes <= quicklistNodeMaxBytes/2:
+		v.Encoding = EncodingListpack
+	}
+}
@@ -0,0 +1,440 @@
+package store
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Sorted sets are kept in a skiplist ordered by score, then member, along
+// with a Dict from member to score. The skiplist records in every link how
+// many nodes it skips, so the rank of a member and the member at a rank
+// are found in O(log n) like the lookup of a score.
+const (
+	zskiplistMaxLevel = 32
+	zskiplistP        = 0.25
+)
+
+type zskiplistNode struct {
+	member   string
+	score    float64
+	backward *zskiplistNode
+	level    []zskiplistLevel
+}
+
+type zskiplistLevel struct {
+	forward *zskiplistNode
+	// span is the number of nodes between this node and forward, counting
+	// forward
+	span int
+}
+
+type zskiplist struct {
+	header, tail *zskiplistNode
+	length       int
+	level        int
+}
+
+type zset struct {
+	dict *Dict
+	zsl  *zskiplist
+}
+
+// NewZSet returns an empty sorted set value.
+func NewZSet() *Value {
+	zsl := &zskiplist{header: &zskiplistNode{level: make([]zskiplistLevel, zskiplistMaxLevel)}, level: 1}
+	return &Value{Type: ObjZSet, Encoding: EncodingSkiplist, Ptr: &zset{dict: NewDict(), zsl: zsl}}
+}
+
+func (v *Value) zset() *zset {
+	return v.Ptr.(*zset)
+}
+
+func zslRandomLevel() int {
+	level := 1
+	for level < zskiplistMaxLevel && rand.Float64() < zskiplistP {
+		level++
+	}
+	return level
+}
+
+// less reports whether the node sorts before score and member.
+func (x *zskiplistNode) less(score float64, member string) bool {
+	return x.score < score || (x.score == score && x.member < member)
+}
+
+func (zsl *zskiplist) insert(score float64, member string) {
+	var update [zskiplistMaxLevel]*zskiplistNode
+	var rank [zskiplistMaxLevel]int
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		if i != zsl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.less(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	level := zslRandomLevel()
+	if level > zsl.level {
+		for i := zsl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = zsl.header
+			update[i].level[i].span = zsl.length
+		}
+		zsl.level = level
+	}
+	x = &zskiplistNode{member: member, score: score, level: make([]zskiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	// the links above the new node now skip one more node
+	for i := level; i < zsl.level; i++ {
+		update[i].level[i].span++
+	}
+	if update[0] != zsl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		zsl.tail = x
+	}
+	zsl.length++
+}
+
+func (zsl *zskiplist) delete(score float64, member string) bool {
+	var update [zskiplistMaxLevel]*zskiplistNode
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.less(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+	for i := 0; i < zsl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		zsl.tail = x.backward
+	}
+	for zsl.level > 1 && zsl.header.level[zsl.level-1].forward == nil {
+		zsl.level--
+	}
+	zsl.length--
+	return true
+}
+
+// rank returns the 1-based rank of the member with score.
+func (zsl *zskiplist) rank(score float64, member string) int {
+	rank := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && (x.level[i].forward.less(score, member) || x.level[i].forward.member == member) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+		if x != zsl.header && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// byRank returns the node at the 1-based rank.
+func (zsl *zskiplist) byRank(rank int) *zskiplistNode {
+	traversed := 0
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+// zrangeSpec is a range of a sorted set, by score or by member.
+type zrangeSpec interface {
+	empty() bool
+	gteMin(x *zskiplistNode) bool
+	lteMax(x *zskiplistNode) bool
+}
+
+// ScoreRange is a range of scores, each end included unless exclusive.
+type ScoreRange struct {
+	Min, Max     float64
+	MinEx, MaxEx bool
+}
+
+func (r ScoreRange) empty() bool {
+	return r.Min > r.Max || (r.Min == r.Max && (r.MinEx || r.MaxEx))
+}
+
+func (r ScoreRange) gteMin(x *zskiplistNode) bool {
+	if r.MinEx {
+		return x.score > r.Min
+	}
+	return x.score >= r.Min
+}
+
+func (r ScoreRange) lteMax(x *zskiplistNode) bool {
+	if r.MaxEx {
+		return x.score < r.Max
+	}
+	return x.score <= r.Max
+}
+
+// LexBound is an end of a LexRange: a member, or with Inf -1 or 1 the
+// smallest or largest of all.
+type LexBound struct {
+	Member    string
+	Exclusive bool
+	Inf       int
+}
+
+// compareLexBounds compares two bounds by their member or infinity.
+func compareLexBounds(a, b LexBound) int {
+	switch {
+	case a.Inf == 0 && b.Inf == 0:
+		return strings.Compare(a.Member, b.Member)
+	case a.Inf == b.Inf:
+		return 0
+	case a.Inf != 0:
+		return a.Inf
+	}
+	return -b.Inf
+}
+
+// LexRange is a range of members, meaningful when all the members of the
+// sorted set have the same score.
+type LexRange struct {
+	Min, Max LexBound
+}
+
+func (r LexRange) empty() bool {
+	c := compareLexBounds(r.Min, r.Max)
+	return c > 0 || (c == 0 && (r.Min.Exclusive || r.Max.Exclusive))
+}
+
+func (r LexRange) gteMin(x *zskiplistNode) bool {
+	c := compareLexBounds(r.Min, LexBound{Member: x.member})
+	return c < 0 || (c == 0 && !r.Min.Exclusive)
+}
+
+func (r LexRange) lteMax(x *zskiplistNode) bool {
+	c := compareLexBounds(r.Max, LexBound{Member: x.member})
+	return c > 0 || (c == 0 && !r.Max.Exclusive)
+}
+
+// inRange reports whether some part of the skiplist is in r.
+func (zsl *zskiplist) inRange(r zrangeSpec) bool {
+	if r.empty() || zsl.tail == nil || !r.gteMin(zsl.tail) {
+		return false
+	}
+	return r.lteMax(zsl.header.level[0].forward)
+}
+
+func (zsl *zskiplist) firstInRange(r zrangeSpec) *zskiplistNode {
+	if !zsl.inRange(r) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.gteMin(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+	x = x.level[0].forward
+	if !r.lteMax(x) {
+		return nil
+	}
+	return x
+}
+
+func (zsl *zskiplist) lastInRange(r zrangeSpec) *zskiplistNode {
+	if !zsl.inRange(r) {
+		return nil
+	}
+	x := zsl.header
+	for i := zsl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && r.lteMax(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+	if !r.gteMin(x) {
+		return nil
+	}
+	return x
+}
+
+// ZSetLen returns the number of members of a sorted set.
+func (v *Value) ZSetLen() int {
+	return v.zset().zsl.length
+}
+
+// ZSetScore returns the score of member.
+func (v *Value) ZSetScore(member []byte) (float64, bool) {
+	score, ok := v.zset().dict.Get(string(member))
+	if !ok {
+		return 0, false
+	}
+	return score.(float64), true
+}
+
+// ZSetAdd sets the score of member and reports whether it was added.
+func (v *Value) ZSetAdd(member []byte, score float64) bool {
+	zs := v.zset()
+	key := string(member)
+	if old, ok := zs.dict.Get(key); ok {
+		if old.(float64) != score {
+			zs.zsl.delete(old.(float64), key)
+			zs.zsl.insert(score, key)
+			zs.dict.Set(key, score)
+		}
+		return false
+	}
+	zs.zsl.insert(score, key)
+	zs.dict.Set(key, score)
+	return true
+}
+
+// ZSetRemove removes member and reports whether it was there.
+func (v *Value) ZSetRemove(member []byte) bool {
+	zs := v.zset()
+	key := string(member)
+	score, ok := zs.dict.Get(key)
+	if !ok {
+		return false
+	}
+	zs.dict.Delete(key)
+	zs.zsl.delete(score.(float64), key)
+	return true
+}
+
+// ZSetRank returns the 0-based rank of member, counted from the highest
+// score when reverse is set.
+func (v *Value) ZSetRank(member []byte, reverse bool) (int, bool) {
+	zs := v.zset()
+	score, ok := zs.dict.Get(string(member))
+	if !ok {
+		return 0, false
+	}
+	rank := zs.zsl.rank(score.(float64), string(member))
+	if reverse {
+		return zs.zsl.length - rank, true
+	}
+	return rank - 1, true
+}
+
+// ZSetRangeByRank calls fn for the members from rank start to stop,
+// counted from the highest score when reverse is set, until it returns
+// false. The ranks must be within the set. fn must not modify the set.
+func (v *Value) ZSetRangeByRank(start, stop int, reverse bool, fn func(member []byte, score float64) bool) {
+	zsl := v.zset().zsl
+	var x *zskiplistNode
+	if reverse {
+		x = zsl.byRank(zsl.length - start)
+	} else {
+		x = zsl.byRank(start + 1)
+	}
+	for i := start; i <= stop && x != nil; i++ {
+		if !fn([]byte(x.member), x.score) {
+			return
+		}
+		if reverse {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+}
+
+// ZSetRangeByScore calls fn for the members with a score in r, in
+// descending order when reverse is set, until it returns false.
+func (v *Value) ZSetRangeByScore(r ScoreRange, reverse bool, fn func(member []byte, score float64) bool) {
+	v.zsetRange(r, reverse, fn)
+}
+
+// ZSetRangeByLex calls fn for the members in r, in descending order when
+// reverse is set, until it returns false.
+func (v *Value) ZSetRangeByLex(r LexRange, reverse bool, fn func(member []byte, score float64) bool) {
+	v.zsetRange(r, reverse, fn)
+}
+
+func (v *Value) zsetRange(r zrangeSpec, reverse bool, fn func(member []byte, score float64) bool) {
+	zsl := v.zset().zsl
+	if reverse {
+		for x := zsl.lastInRange(r); x != nil && r.gteMin(x); x = x.backward {
+			if !fn([]byte(x.member), x.score) {
+				return
+			}
+		}
+		return
+	}
+	for x := zsl.firstInRange(r); x != nil && r.lteMax(x); x = x.level[0].forward {
+		if !fn([]byte(x.member), x.score) {
+			return
+		}
+	}
+}
+
+// ZSetScan calls fn for the members at cursor and returns the next
+// cursor, see Dict.Scan.
+func (v *Value) ZSetScan(cursor uint64, fn func(member []byte, score float64)) uint64 {
+	return v.zset().dict.Scan(cursor, func(key string, value interface{}) {
+		fn([]byte(key), value.(float64))
+	})
+}
+
+// ZSetCount returns the number of members with a score in r.
+func (v *Value) ZSetCount(r ScoreRange) int {
+	return v.zsetCount(r)
+}
+
+// ZSetLexCount returns the number of members in r.
+func (v *Value) ZSetLexCount(r LexRange) int {
+	return v.zsetCount(r)
+}
+
+func (v *Value) zsetCount(r zrangeSpec) int {
+	zsl := v.zset().zsl
+	first := zsl.firstInRange(r)
+	if first == nil {
+		return 0
+	}
+	last := zsl.lastInRange(r)
+	return zsl.rank(last.score, last.member) - zsl.rank(first.score, first.member) + 1
+}
+
+// ZSetPop removes the member with the lowest score, or the highest when
+// highest is set, from a non empty sorted set and returns it.
+func (v *Value) ZSetPop(highest bool) ([]byte, float64) {
+	zs := v.zset()
+	x := zs.zsl.header.level[0].forward
+	if highest {
+		x = zs.zsl.tail
+	}
+	member, score := x.member, x.score
+	zs.zsl.delete(score, member)
+	zs.dict.Delete(member)
+	return []byte(member), score
+}
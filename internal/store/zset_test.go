@@ -0,0 +1,102 @@
+package store
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestZSetRanks(t *testing.T) {
+	z := NewZSet()
+	scores := map[string]float64{}
+	for i := 0; i < 1000; i++ {
+		member := strconv.Itoa(rand.Intn(500))
+		score := float64(rand.Intn(100))
+		z.ZSetAdd([]byte(member), score)
+		scores[member] = score
+		if i%3 == 0 {
+			victim := strconv.Itoa(rand.Intn(500))
+			z.ZSetRemove([]byte(victim))
+			delete(scores, victim)
+		}
+	}
+	members := make([]string, 0, len(scores))
+	for member := range scores {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		a, b := members[i], members[j]
+		return scores[a] < scores[b] || (scores[a] == scores[b] && a < b)
+	})
+	if z.ZSetLen() != len(members) {
+		t.Fatalf("Expected %d members, got %d", len(members), z.ZSetLen())
+	}
+	for i, member := range members {
+		if rank, ok := z.ZSetRank([]byte(member), false); !ok || rank != i {
+			t.Fatalf("Expected %s at rank %d, got %d", member, i, rank)
+		}
+		if rank, _ := z.ZSetRank([]byte(member), true); rank != len(members)-1-i {
+			t.Fatalf("Expected %s at reverse rank %d, got %d", member, len(members)-1-i, rank)
+		}
+	}
+	i := 10
+	z.ZSetRangeByRank(10, 19, false, func(member []byte, score float64) bool {
+		if string(member) != members[i] {
+			t.Errorf("Expected %s at rank %d, got %s", members[i], i, member)
+		}
+		i++
+		return true
+	})
+	if i != 20 {
+		t.Errorf("Expected 10 members in the range, got %d", i-10)
+	}
+}
+
+func TestZSetScoreRange(t *testing.T) {
+	z := NewZSet()
+	for i := 0; i < 10; i++ {
+		z.ZSetAdd([]byte{'a' + byte(i)}, float64(i))
+	}
+	r := ScoreRange{Min: 2, Max: 5, MinEx: true}
+	if n := z.ZSetCount(r); n != 3 {
+		t.Errorf("Expected 3 members in (2 5], got %d", n)
+	}
+	var got string
+	z.ZSetRangeByScore(r, true, func(member []byte, score float64) bool {
+		got += string(member)
+		return true
+	})
+	if got != "fed" {
+		t.Errorf("Expected fed, got %s", got)
+	}
+	if n := z.ZSetCount(ScoreRange{Min: 5, Max: 2}); n != 0 {
+		t.Errorf("Expected an empty range, got %d", n)
+	}
+	member, score := z.ZSetPop(true)
+	if string(member) != "j" || score != 9 || z.ZSetLen() != 9 {
+		t.Errorf("Expected to pop j with 9, got %s with %v", member, score)
+	}
+}
+
+func TestZSetLexRange(t *testing.T) {
+	z := NewZSet()
+	for _, member := range []string{"a", "b", "c", "d", "e"} {
+		z.ZSetAdd([]byte(member), 0)
+	}
+	tests := []struct {
+		r    LexRange
+		want int
+	}{
+		{LexRange{Min: LexBound{Inf: -1}, Max: LexBound{Inf: 1}}, 5},
+		{LexRange{Min: LexBound{Member: "b"}, Max: LexBound{Member: "d", Exclusive: true}}, 2},
+		{LexRange{Min: LexBound{Member: "c", Exclusive: true}, Max: LexBound{Inf: 1}}, 2},
+		{LexRange{Min: LexBound{Inf: 1}, Max: LexBound{Inf: -1}}, 0},
+		{LexRange{Min: LexBound{Member: "c", Exclusive: true}, Max: LexBound{Member: "c"}}, 0},
+	}
+	for _, test := range tests {
+		if n := z.ZSetLexCount(test.r); n != test.want {
+			t.Errorf("Expected %d members in %+v, got %d", test.want, test.r, n)
+		}
+	}
+}
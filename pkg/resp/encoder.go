@@ -0,0 +1,200 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Encoder writes RESP values straight to a buffered writer. With Protocol
+// set to 2, RESP3 values are downgraded to their RESP2 equivalents while
+// being written; with 3 they are written as is and every null is sent as
+// the RESP3 null.
+type Encoder struct {
+	w        *bufio.Writer
+	Protocol int
+	scratch  []byte
+	// number holds formatted doubles while scratch encodes their header
+	number []byte
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	bw, ok := w.(*bufio.Writer)
+	if !ok {
+		bw = bufio.NewWriter(w)
+	}
+	return &Encoder{w: bw, Protocol: 3, scratch: make([]byte, 0, 32), number: make([]byte, 0, 32)}
+}
+
+// Encode writes v to the underlying buffer. Call Flush to send it.
+func (e *Encoder) Encode(v RESP) error {
+	e.encode(&v)
+	// bufio.Writer keeps the first write error and returns it on every call
+	_, err := e.w.Write(nil)
+	return err
+}
+
+func (e *Encoder) Flush() error {
+	return e.w.Flush()
+}
+
+func (e *Encoder) encode(v *RESP) {
+	resp2 := e.Protocol < 3
+	switch v.Type {
+	case SimpleString:
+		e.line('+', v.Str)
+	case Error:
+		e.line('-', v.Str)
+	case Integer:
+		e.integer(':', v.Int)
+	case BulkString:
+		e.blob('$', v.Str)
+	case Array:
+		e.aggregate('*', v.Elems, 1)
+	case RDB:
+		// RDB transfers are framed like a bulk string without trailing CRLF
+		e.integer('$', int64(len(v.Str)))
+		e.w.Write(v.Str)
+	case NullBulkString, NullArray, Null:
+		switch {
+		case !resp2:
+			e.w.WriteString("_\r\n")
+		case v.Type == NullArray:
+			e.w.WriteString("*-1\r\n")
+		default:
+			e.w.WriteString("$-1\r\n")
+		}
+	case Boolean:
+		if resp2 {
+			e.integer(':', v.Int)
+		} else if v.Int != 0 {
+			e.w.WriteString("#t\r\n")
+		} else {
+			e.w.WriteString("#f\r\n")
+		}
+	case Double:
+		e.number = AppendDouble(e.number[:0], v.Float)
+		if resp2 {
+			e.blob('$', e.number)
+		} else {
+			e.line(',', e.number)
+		}
+	case BigNumber:
+		if resp2 {
+			e.blob('$', v.Str)
+		} else {
+			e.line('(', v.Str)
+		}
+	case BulkError:
+		if resp2 {
+			e.line('-', v.Str)
+		} else {
+			e.blob('!', v.Str)
+		}
+	case VerbatimString:
+		if resp2 {
+			// drop the three letter format and its colon, e.g. "txt:"
+			e.blob('$', v.Str[4:])
+		} else {
+			e.blob('=', v.Str)
+		}
+	case Map:
+		if resp2 {
+			e.aggregate('*', v.Elems, 1)
+		} else {
+			e.aggregate('%', v.Elems, 2)
+		}
+	case Set:
+		if resp2 {
+			e.aggregate('*', v.Elems, 1)
+		} else {
+			e.aggregate('~', v.Elems, 1)
+		}
+	case Attribute:
+		if resp2 {
+			e.aggregate('*', v.Elems, 1)
+		} else {
+			e.aggregate('|', v.Elems, 2)
+		}
+	case Push:
+		if resp2 {
+			e.aggregate('*', v.Elems, 1)
+		} else {
+			e.aggregate('>', v.Elems, 1)
+		}
+	default:
+		panic("unknown RESP type")
+	}
+}
+
+func (e *Encoder) line(prefix byte, payload []byte) {
+	e.w.WriteByte(prefix)
+	e.w.Write(payload)
+	e.w.WriteString("\r\n")
+}
+
+func (e *Encoder) integer(prefix byte, n int64) {
+	e.scratch = append(e.scratch[:0], prefix)
+	e.scratch = strconv.AppendInt(e.scratch, n, 10)
+	e.scratch = append(e.scratch, '\r', '\n')
+	e.w.Write(e.scratch)
+}
+
+func (e *Encoder) blob(prefix byte, payload []byte) {
+	e.integer(prefix, int64(len(payload)))
+	e.w.Write(payload)
+	e.w.WriteString("\r\n")
+}
+
+func (e *Encoder) aggregate(prefix byte, elems []RESP, perEntry int) {
+	e.integer(prefix, int64(len(elems)/perEntry))
+	for i := range elems {
+		e.encode(&elems[i])
+	}
+}
+
+// Serialize returns the RESP3 encoding of the value.
+func (r *RESP) Serialize() string {
+	return r.SerializeAs(3)
+}
+
+// SerializeAs returns the encoding of the value for a connection speaking
+// the given protocol version.
+func (r *RESP) SerializeAs(protocol int) string {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Protocol = protocol
+	enc.Encode(*r)
+	enc.Flush()
+	return buf.String()
+}
+
+// AppendDouble appends f formatted the way Redis replies with doubles in
+// human readable form: integers without decimals, other values with as
+// many digits as needed to round trip, switching to an exponent only for
+// magnitudes that would otherwise take dozens of digits.
+func AppendDouble(dst []byte, f float64) []byte {
+	abs := math.Abs(f)
+	switch {
+	case math.IsInf(f, 1):
+		return append(dst, "inf"...)
+	case math.IsInf(f, -1):
+		return append(dst, "-inf"...)
+	case math.IsNaN(f):
+		return append(dst, "nan"...)
+	case f == 0 && math.Signbit(f):
+		return append(dst, "-0"...)
+	case f == math.Trunc(f) && abs < 1<<53:
+		return strconv.AppendInt(dst, int64(f), 10)
+	case abs >= 1e-6 && abs < 1e21:
+		return strconv.AppendFloat(dst, f, 'f', -1, 64)
+	}
+	return strconv.AppendFloat(dst, f, 'g', -1, 64)
+}
+
+// FormatDouble returns f formatted like AppendDouble does.
+func FormatDouble(f float64) string {
+	return string(AppendDouble(nil, f))
+}
@@ -0,0 +1,99 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"math"
+	"testing"
+)
+
+func TestEncoderNulls(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     RESP
+		protocol int
+		expected string
+	}{
+		{"NullBulkString RESP2", NewNullBulkString(), 2, "$-1\r\n"},
+		{"NullArray RESP2", NewNullArray(), 2, "*-1\r\n"},
+		{"Null RESP2", NewNull(), 2, "$-1\r\n"},
+		{"NullBulkString RESP3", NewNullBulkString(), 3, "_\r\n"},
+		{"NullArray RESP3", NewNullArray(), 3, "_\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := NewEncoder(&buf)
+			enc.Protocol = tt.protocol
+			if err := enc.Encode(tt.resp); err != nil {
+				t.Fatalf("Encode returned an error: %v", err)
+			}
+			enc.Flush()
+			if buf.String() != tt.expected {
+				t.Errorf("Encode() wrote %q, expected %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncoderPipeline(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Encode(NewSimpleString("OK"))
+	enc.Encode(NewInteger(-42))
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing written before Flush, got %q", buf.String())
+	}
+	enc.Flush()
+	if buf.String() != "+OK\r\n:-42\r\n" {
+		t.Errorf("Flush wrote %q", buf.String())
+	}
+}
+
+func TestEncoderDoesNotAllocate(t *testing.T) {
+	enc := NewEncoder(bufio.NewWriterSize(ioutil.Discard, 64*1024))
+	reply := NewArray([]RESP{
+		NewBulkString("foo"),
+		NewInteger(123),
+		NewDouble(1.5),
+		NewNullBulkString(),
+	})
+	allocs := testing.AllocsPerRun(100, func() {
+		enc.Encode(reply)
+	})
+	if allocs != 0 {
+		t.Errorf("Encode allocated %v times per run, expected 0", allocs)
+	}
+}
+
+func TestAppendDouble(t *testing.T) {
+	for f, want := range map[float64]string{
+		1234567:             "1234567",
+		1700000000:          "1700000000",
+		-3:                  "-3",
+		3.25:                "3.25",
+		0.30000000000000004: "0.30000000000000004",
+		0.00001:             "0.00001",
+		1e-7:                "1e-07",
+		1e20 + 0.5:          "100000000000000000000",
+		1.5e300:             "1.5e+300",
+		0:                   "0",
+		math.Inf(1):         "inf",
+		math.Inf(-1):        "-inf",
+		13.361389338970184:  "13.361389338970184",
+	} {
+		if got := string(AppendDouble(nil, f)); got != want {
+			t.Errorf("AppendDouble(%v) = %q, expected %q", f, got, want)
+		}
+	}
+	// -0 equals 0 as a map key, so it is checked on its own
+	if got := string(AppendDouble(nil, math.Copysign(0, -1))); got != "-0" {
+		t.Errorf("AppendDouble(-0) = %q, expected \"-0\"", got)
+	}
+	score := NewDouble(1234567)
+	if got := score.SerializeAs(2); got != "$7\r\n1234567\r\n" {
+		t.Errorf("SerializeAs(2) = %q", got)
+	}
+}
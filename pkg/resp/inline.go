@@ -0,0 +1,219 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxInlineSize bounds the length of an inline command line, the same limit
+// Redis applies to telnet-style requests.
+const maxInlineSize = 64 * 1024
+
+// Limits Redis applies to multibulk requests, so a malicious header cannot
+// make the server allocate arbitrary amounts of memory.
+const (
+	maxMultibulkLength = 1024 * 1024
+	maxBulkLength      = 512 * 1024 * 1024
+)
+
+var ErrUnbalancedQuotes = errors.New("unbalanced quotes in request")
+var ErrInlineTooBig = errors.New("too big inline request")
+
+// ReadCommand reads the next client request. Requests starting with '*' are
+// arrays of bulk strings; anything else is an inline command of space
+// separated arguments terminated by a newline, as typed into nc or telnet.
+// Both are returned as an array of bulk strings.
+func (r *Reader) ReadCommand() (RESP, error) {
+	first, err := r.rd.Peek(1)
+	if err != nil {
+		return RESP{}, err
+	}
+	if first[0] == '*' {
+		return r.readMultibulk()
+	}
+	line, err := r.readInlineLine()
+	if err != nil {
+		return RESP{}, err
+	}
+	args, err := SplitArgs(line)
+	if err != nil {
+		return RESP{}, err
+	}
+	elements := make([]RESP, len(args))
+	for i, arg := range args {
+		elements[i] = NewBulk(arg)
+	}
+	return NewArray(elements), nil
+}
+
+// readMultibulk reads a request array, which may only hold bulk strings.
+func (r *Reader) readMultibulk() (RESP, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return RESP{}, err
+	}
+	length, err := parseLength(line)
+	if err != nil || length > maxMultibulkLength {
+		return RESP{}, errors.New("invalid multibulk length")
+	}
+	if length <= 0 {
+		return NewArray(nil), nil
+	}
+	elements := make([]RESP, length)
+	for i := range elements {
+		line, err := r.readLine()
+		if err != nil {
+			return RESP{}, unexpectedEOF(err)
+		}
+		if len(line) == 0 || line[0] != '$' {
+			if len(line) == 0 {
+				return RESP{}, errors.New("expected '$', got empty line")
+			}
+			return RESP{}, fmt.Errorf("expected '$', got '%c'", line[0])
+		}
+		length, err := parseLength(line)
+		if err != nil || length < 0 || length > maxBulkLength {
+			return RESP{}, errors.New("invalid bulk length")
+		}
+		data, err := r.readBulk(length)
+		if err != nil {
+			return RESP{}, err
+		}
+		elements[i] = NewBulk(data)
+	}
+	return NewArray(elements), nil
+}
+
+// readInlineLine reads a line terminated by LF, tolerating a missing CR.
+func (r *Reader) readInlineLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.rd.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxInlineSize {
+			return nil, ErrInlineTooBig
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		break
+	}
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return line, nil
+}
+
+// SplitArgs splits an inline command line into arguments following the
+// rules of redis-cli and the Redis inline protocol: arguments are separated
+// by whitespace, "double quoted" arguments support escapes such as \n, \t
+// and \xff, and 'single quoted' arguments only support \'.
+func SplitArgs(line []byte) ([][]byte, error) {
+	args := [][]byte{}
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+		current := []byte{}
+		inDoubleQuotes, inSingleQuotes, done := false, false, false
+		started := false
+		for !done {
+			if inDoubleQuotes {
+				switch {
+				case i == len(line):
+					return nil, ErrUnbalancedQuotes
+				case line[i] == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHexDigit(line[i+2]) && isHexDigit(line[i+3]):
+					current = append(current, hexValue(line[i+2])<<4|hexValue(line[i+3]))
+					i += 3
+				case line[i] == '\\' && i+1 < len(line):
+					i++
+					switch line[i] {
+					case 'n':
+						current = append(current, '\n')
+					case 'r':
+						current = append(current, '\r')
+					case 't':
+						current = append(current, '\t')
+					case 'b':
+						current = append(current, '\b')
+					case 'a':
+						current = append(current, '\a')
+					default:
+						current = append(current, line[i])
+					}
+				case line[i] == '"':
+					// the closing quote must be followed by a space or the end
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					current = append(current, line[i])
+				}
+			} else if inSingleQuotes {
+				switch {
+				case i == len(line):
+					return nil, ErrUnbalancedQuotes
+				case line[i] == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					current = append(current, '\'')
+				case line[i] == '\'':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					current = append(current, line[i])
+				}
+			} else {
+				switch {
+				case i == len(line) || isSpace(line[i]):
+					done = true
+				case line[i] == '"' && !started:
+					inDoubleQuotes = true
+				case line[i] == '\'' && !started:
+					inSingleQuotes = true
+				default:
+					current = append(current, line[i])
+				}
+				started = true
+			}
+			if i < len(line) {
+				i++
+			}
+		}
+		args = append(args, current)
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\v' || c == '\f'
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,82 @@
+package resp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"PING", []string{"PING"}},
+		{"  SET foo   bar  ", []string{"SET", "foo", "bar"}},
+		{`SET "hello world" 'it\'s'`, []string{"SET", "hello world", "it's"}},
+		{`ECHO "line\nbreak\x41\x00"`, []string{"ECHO", "line\nbreak" + "A\x00"}},
+		{`SET key ""`, []string{"SET", "key", ""}},
+		{`SET a"b c`, []string{"SET", `a"b`, "c"}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		args, err := SplitArgs([]byte(tt.input))
+		if err != nil {
+			t.Errorf("SplitArgs(%q) returned an error: %v", tt.input, err)
+			continue
+		}
+		got := make([]string, len(args))
+		for i, arg := range args {
+			got[i] = string(arg)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SplitArgs(%q) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestSplitArgsUnbalancedQuotes(t *testing.T) {
+	inputs := []string{`SET "foo`, `SET 'foo`, `SET "foo"bar`, `SET 'foo'bar`}
+	for _, input := range inputs {
+		if _, err := SplitArgs([]byte(input)); err != ErrUnbalancedQuotes {
+			t.Errorf("SplitArgs(%q) expected ErrUnbalancedQuotes, got %v", input, err)
+		}
+	}
+}
+
+func TestReadCommandInline(t *testing.T) {
+	reader := NewReader(strings.NewReader("PING\r\nSET foo \"bar baz\"\n*1\r\n$4\r\nPING\r\n"))
+	expected := []RESP{
+		{Type: Array, Elems: []RESP{{Type: BulkString, Str: []byte("PING")}}},
+		{Type: Array, Elems: []RESP{
+			{Type: BulkString, Str: []byte("SET")},
+			{Type: BulkString, Str: []byte("foo")},
+			{Type: BulkString, Str: []byte("bar baz")},
+		}},
+		{Type: Array, Elems: []RESP{{Type: BulkString, Str: []byte("PING")}}},
+	}
+	for _, want := range expected {
+		got, err := reader.ReadCommand()
+		if err != nil {
+			t.Fatalf("ReadCommand returned an error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadCommand returned %+v, expected %+v", got, want)
+		}
+	}
+}
+
+func TestReadCommandRejectsNonBulkArguments(t *testing.T) {
+	reader := NewReader(strings.NewReader("*2\r\n$4\r\nECHO\r\n:1\r\n"))
+	if _, err := reader.ReadCommand(); err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+}
+
+func TestReadCommandInlineTooBig(t *testing.T) {
+	reader := NewReader(strings.NewReader(strings.Repeat("a", maxInlineSize+1) + "\r\n"))
+	if _, err := reader.ReadCommand(); err != ErrInlineTooBig {
+		t.Errorf("Expected ErrInlineTooBig, got %v", err)
+	}
+}
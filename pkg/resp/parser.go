@@ -1,207 +1,243 @@
 package resp
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"strconv"
-	"strings"
 )
 
-type RespType int
+var crlf = []byte("\r\n")
 
-const (
-	SimpleString RespType = iota
-	Error
-	Integer
-	BulkString
-	Array
-	RDB
-	NullBulkString
-)
-
-type RESP struct {
-	Type RespType
-	Data interface{}
-}
-
-func (r *RESP) Serialize() string {
-	switch r.Type {
-	case SimpleString:
-		return fmt.Sprintf("+%s\r\n", r.Data)
-	case Error:
-		return fmt.Sprintf("-%s\r\n", r.Data)
-	case Integer:
-		return fmt.Sprintf(":%d\r\n", r.Data)
-	case BulkString:
-		return fmt.Sprintf("$%d\r\n%s\r\n", len(r.Data.(string)), r.Data)
-	case Array:
-		res := fmt.Sprintf("*%d\r\n", len(r.Data.([]*RESP)))
-		for _, element := range r.Data.([]*RESP) {
-			res += element.Serialize()
-		}
-		return res
-	case RDB:
-		return fmt.Sprintf("$%d\r\n%s", len(r.Data.([]byte)), string(r.Data.([]byte)))
-	case NullBulkString:
-		return "$-1\r\n"
-	}
-	panic("unknown RESP type")
-}
-
-type RESPParser struct {
-	rawRequest   string
-	CurrentIndex int
-}
-
-func NewRESPParser(input string) *RESPParser {
-	return &RESPParser{
-		rawRequest:   input,
-		CurrentIndex: 0,
-	}
-}
-
-func (p *RESPParser) HasNext() bool {
-	hasNext := p.CurrentIndex < len(p.rawRequest)
-	if hasNext {
-		// print left data
-		fmt.Printf("left data: %s\n", p.rawRequest[p.CurrentIndex:])
-	}
-	fmt.Printf("hasNext: %v\n", hasNext)
-	return hasNext
-}
-
-func (p *RESPParser) ParseNext() (*RESP, error) {
-	if p.CurrentIndex >= len(p.rawRequest) {
-		return nil, errors.New("no more data to parse")
-	}
-	resp, nextIndex, err := ParseRESP(p.rawRequest[p.CurrentIndex:])
-	if err != nil {
-		return nil, err
-	}
-	p.CurrentIndex += nextIndex
-	return resp, nil
-}
-
-func ParseRESP(input string) (*RESP, int, error) {
-	if input == "" {
-		return nil, 0, errors.New("empty input")
+// ParseRESP decodes the first value in input and returns it together with
+// the number of bytes consumed. The returned value references input rather
+// than copying it.
+func ParseRESP(input []byte) (RESP, int, error) {
+	if len(input) == 0 {
+		return RESP{}, 0, errors.New("empty input")
 	}
 	switch input[0] {
 	case '+':
-		return parseSimpleString(input)
+		return parseLine(input, SimpleString, "simple string")
 	case '-':
-		return parseError(input)
+		return parseLine(input, Error, "error")
 	case ':':
 		return parseInteger(input)
 	case '$':
 		return parseBulkString(input)
 	case '*':
 		return parseArray(input)
+	case '_':
+		return parseNull(input)
+	case '#':
+		return parseBoolean(input)
+	case ',':
+		return parseDoubleValue(input)
+	case '(':
+		return parseBigNumber(input)
+	case '!':
+		return parseBlob(input, BulkError)
+	case '=':
+		return parseBlob(input, VerbatimString)
+	case '%':
+		return parseAggregate(input, Map, 2)
+	case '~':
+		return parseAggregate(input, Set, 1)
+	case '|':
+		return parseAggregate(input, Attribute, 2)
+	case '>':
+		return parseAggregate(input, Push, 1)
 	default:
-		return nil, 0, fmt.Errorf("unknown RESP type: %s", string(input))
+		return RESP{}, 0, fmt.Errorf("unknown RESP type: %q", input[0])
 	}
 }
 
-func parseSimpleString(input string) (*RESP, int, error) {
-	end := strings.Index(input, "\r\n")
+// lineEnd returns the index of the first CRLF in input.
+func lineEnd(input []byte, what string) (int, error) {
+	end := bytes.Index(input, crlf)
 	if end == -1 {
-		return nil, 0, errors.New("invalid simple string: no CRLF")
+		return 0, fmt.Errorf("invalid %s: no CRLF", what)
 	}
-	return &RESP{
-		Type: SimpleString,
-		Data: input[1:end],
-	}, end + len("\r\n"), nil
+	return end, nil
 }
 
-func parseError(input string) (*RESP, int, error) {
-	end := strings.Index(input, "\r\n")
-	if end == -1 {
-		return nil, 0, errors.New("invalid error: no CRLF")
+func parseLine(input []byte, typ RespType, what string) (RESP, int, error) {
+	end, err := lineEnd(input, what)
+	if err != nil {
+		return RESP{}, 0, err
 	}
-	return &RESP{
-		Type: Error,
-		Data: input[1:end],
-	}, end + len("\r\n"), nil
+	return RESP{Type: typ, Str: input[1:end]}, end + len(crlf), nil
 }
 
-func parseInteger(input string) (*RESP, int, error) {
-	end := strings.Index(input, "\r\n")
-	if end == -1 {
-		return nil, 0, errors.New("invalid integer: no CRLF")
+func parseInteger(input []byte) (RESP, int, error) {
+	end, err := lineEnd(input, "integer")
+	if err != nil {
+		return RESP{}, 0, err
 	}
-	val, err := strconv.ParseInt(input[1:end], 10, 64)
+	val, err := strconv.ParseInt(string(input[1:end]), 10, 64)
 	if err != nil {
-		return nil, 0, err
+		return RESP{}, 0, err
 	}
-	return &RESP{
-		Type: Integer,
-		Data: val,
-	}, end + len("\r\n"), nil
+	return NewInteger(val), end + len(crlf), nil
 }
 
-func parseBulkString(input string) (*RESP, int, error) {
-	end := strings.Index(input, "\r\n")
-	if end == -1 {
-		return nil, 0, errors.New("invalid bulk string: no CRLF")
+func parseBulkString(input []byte) (RESP, int, error) {
+	end, err := lineEnd(input, "bulk string")
+	if err != nil {
+		return RESP{}, 0, err
 	}
-	length, err := strconv.ParseInt(input[1:end], 10, 64)
+	length, err := strconv.ParseInt(string(input[1:end]), 10, 64)
 	if err != nil {
-		return nil, 0, err
+		return RESP{}, 0, err
 	}
 	if length == -1 {
-		return &RESP{Type: BulkString, Data: nil}, 0, nil // Nil bulk string
+		return NewNullBulkString(), end + len(crlf), nil
+	}
+	if length < 0 {
+		return RESP{}, 0, errors.New("invalid bulk string length")
 	}
-	startIndex := end + len("\r\n")
-	if int64(len(input)) < int64(startIndex)+length+int64(len("\r\n")) {
-		return nil, 0, errors.New("invalid bulk string: data too short")
+	startIndex := end + len(crlf)
+	if int64(len(input)) < int64(startIndex)+length+int64(len(crlf)) {
+		return RESP{}, 0, errors.New("invalid bulk string: data too short")
 	}
-	data := input[startIndex : startIndex+int(length)]
-	return &RESP{
-		Type: BulkString,
-		Data: data,
-	}, startIndex + int(length) + len("\r\n"), nil
+	stop := startIndex + int(length)
+	if !bytes.Equal(input[stop:stop+len(crlf)], crlf) {
+		return RESP{}, 0, errors.New("invalid bulk string: missing CRLF")
+	}
+	return NewBulk(input[startIndex:stop]), stop + len(crlf), nil
 }
 
-func parseArray(input string) (*RESP, int, error) {
-	arrHeaderEnd := strings.Index(input, "\r\n")
-	if arrHeaderEnd == -1 {
-		return nil, 0, errors.New("invalid array: no CRLF")
-	}
-	arrayLength, err := strconv.ParseInt(input[1:arrHeaderEnd], 10, 64)
+func parseArray(input []byte) (RESP, int, error) {
+	end, err := lineEnd(input, "array")
 	if err != nil {
-		return nil, 0, errors.New("invalid array length")
+		return RESP{}, 0, err
 	}
-	elements := make([]*RESP, 0, arrayLength)
-	currentIndex := arrHeaderEnd + len("\r\n") // Start right after the init CRLF
+	if string(input[1:end]) == "-1" {
+		return NewNullArray(), end + len(crlf), nil
+	}
+	return parseAggregate(input, Array, 1)
+}
 
-	for i := int64(0); i < arrayLength; i++ {
+// parseAggregate parses the header and elements of an aggregate type.
+// perEntry is 2 for types whose header counts key/value pairs.
+func parseAggregate(input []byte, typ RespType, perEntry int) (RESP, int, error) {
+	headerEnd, err := lineEnd(input, "aggregate")
+	if err != nil {
+		return RESP{}, 0, err
+	}
+	length, err := strconv.ParseInt(string(input[1:headerEnd]), 10, 64)
+	if err != nil || length < 0 {
+		return RESP{}, 0, errors.New("invalid aggregate length")
+	}
+	length *= int64(perEntry)
+	// every element takes at least three bytes, so a header announcing more
+	// elements than the input has bytes cannot be complete
+	if length > int64(len(input)) {
+		return RESP{}, 0, errors.New("incomplete input data")
+	}
+	elements := make([]RESP, length)
+	currentIndex := headerEnd + len(crlf)
+	for i := range elements {
 		if currentIndex >= len(input) {
-			return nil, 0, errors.New("incomplete input data")
+			return RESP{}, 0, errors.New("incomplete input data")
 		}
-		// print the next index
-		nextResp, nextIndex, err := parseNextElement(input, currentIndex)
+		element, n, err := ParseRESP(input[currentIndex:])
 		if err != nil {
-			return nil, 0, err
+			return RESP{}, 0, err
 		}
-		elements = append(elements, nextResp)
-		currentIndex += nextIndex // Update currentIndex to the end of the last parsed element
+		elements[i] = element
+		currentIndex += n
+	}
+	return RESP{Type: typ, Elems: elements}, currentIndex, nil
+}
+
+func parseNull(input []byte) (RESP, int, error) {
+	if !bytes.HasPrefix(input, []byte("_\r\n")) {
+		return RESP{}, 0, errors.New("invalid null")
+	}
+	return NewNull(), len("_\r\n"), nil
+}
+
+func parseBoolean(input []byte) (RESP, int, error) {
+	end, err := lineEnd(input, "boolean")
+	if err != nil {
+		return RESP{}, 0, err
+	}
+	switch string(input[1:end]) {
+	case "t":
+		return NewBoolean(true), end + len(crlf), nil
+	case "f":
+		return NewBoolean(false), end + len(crlf), nil
 	}
+	return RESP{}, 0, fmt.Errorf("invalid boolean: %q", input[1:end])
+}
 
-	return &RESP{
-		Type: Array,
-		Data: elements,
-	}, currentIndex, nil
+func parseDoubleValue(input []byte) (RESP, int, error) {
+	end, err := lineEnd(input, "double")
+	if err != nil {
+		return RESP{}, 0, err
+	}
+	val, err := parseDouble(input[1:end])
+	if err != nil {
+		return RESP{}, 0, err
+	}
+	return NewDouble(val), end + len(crlf), nil
 }
 
-// parseNextElement finds and parses the next RESP element in the input string
-func parseNextElement(input string, startIndex int) (*RESP, int, error) {
-	if startIndex >= len(input) {
-		return nil, 0, errors.New("out of bounds when parsing next element")
+func parseBigNumber(input []byte) (RESP, int, error) {
+	end, err := lineEnd(input, "big number")
+	if err != nil {
+		return RESP{}, 0, err
 	}
-	nextResp, nextIndex, err := ParseRESP(input[startIndex:])
+	if err := checkBigNumber(input[1:end]); err != nil {
+		return RESP{}, 0, err
+	}
+	return RESP{Type: BigNumber, Str: input[1:end]}, end + len(crlf), nil
+}
+
+// parseBlob parses the length-prefixed RESP3 types that share the bulk
+// string framing.
+func parseBlob(input []byte, typ RespType) (RESP, int, error) {
+	res, n, err := parseBulkString(input)
 	if err != nil {
-		return nil, startIndex, err
+		return RESP{}, 0, err
+	}
+	if res.Type == NullBulkString {
+		return RESP{}, 0, errors.New("invalid blob: negative length")
+	}
+	if typ == VerbatimString {
+		if err := checkVerbatim(res.Str); err != nil {
+			return RESP{}, 0, err
+		}
 	}
+	res.Type = typ
+	return res, n, nil
+}
+
+func parseDouble(b []byte) (float64, error) {
+	switch string(b) {
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(string(b), 64)
+}
 
-	return nextResp, nextIndex, nil
+func checkBigNumber(b []byte) error {
+	if _, ok := new(big.Int).SetString(string(b), 10); !ok {
+		return fmt.Errorf("invalid big number: %q", b)
+	}
+	return nil
+}
+
+func checkVerbatim(b []byte) error {
+	if len(b) < 4 || b[3] != ':' {
+		return errors.New("invalid verbatim string: missing format")
+	}
+	return nil
 }
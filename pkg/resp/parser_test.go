@@ -1,85 +1,88 @@
 package resp
 
 import (
+	"bytes"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestParseSimpleString(t *testing.T) {
 	input := "+OK\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: SimpleString,
-		Data: "OK",
+		Str:  []byte("OK"),
 	}
-	actual, _, err := ParseRESP(input)
+	actual, _, err := ParseRESP([]byte(input))
 	if err != nil {
 		t.Errorf("Error parsing simple string: %v", err)
 	}
 	if actual.Type != expected.Type {
 		t.Errorf("Expected type %v, got %v", expected.Type, actual.Type)
 	}
-	if actual.Data != expected.Data {
-		t.Errorf("Expected data %v, got %v", expected.Data, actual.Data)
+	if !bytes.Equal(actual.Str, expected.Str) {
+		t.Errorf("Expected data %q, got %q", expected.Str, actual.Str)
 	}
 }
 
 func TestParseError(t *testing.T) {
 	input := "-Error\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: Error,
-		Data: "Error",
+		Str:  []byte("Error"),
 	}
-	actual, _, err := ParseRESP(input)
+	actual, _, err := ParseRESP([]byte(input))
 	if err != nil {
 		t.Errorf("Error parsing error: %v", err)
 	}
 	if actual.Type != expected.Type {
 		t.Errorf("Expected type %v, got %v", expected.Type, actual.Type)
 	}
-	if actual.Data != expected.Data {
-		t.Errorf("Expected data %v, got %v", expected.Data, actual.Data)
+	if !bytes.Equal(actual.Str, expected.Str) {
+		t.Errorf("Expected data %q, got %q", expected.Str, actual.Str)
 	}
 }
 
 func TestParseInteger(t *testing.T) {
 	input := ":123\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: Integer,
-		Data: int64(123),
+		Int:  123,
 	}
-	actual, _, err := ParseRESP(input)
+	actual, _, err := ParseRESP([]byte(input))
 	if err != nil {
 		t.Errorf("Error parsing integer: %v", err)
 	}
 	if actual.Type != expected.Type {
 		t.Errorf("Expected type %v, got %v", expected.Type, actual.Type)
 	}
-	if actual.Data != expected.Data {
-		t.Errorf("Expected data %s, got %s", expected.Data, actual.Data)
+	if actual.Int != expected.Int {
+		t.Errorf("Expected data %d, got %d", expected.Int, actual.Int)
 	}
 }
 
 func TestParseBulkString(t *testing.T) {
 	input := "$6\r\nfoobar\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: BulkString,
-		Data: "foobar",
+		Str:  []byte("foobar"),
 	}
-	actual, _, err := ParseRESP(input)
+	actual, _, err := ParseRESP([]byte(input))
 	if err != nil {
 		t.Errorf("Error parsing bulk string: %v", err)
 	}
 	if actual.Type != expected.Type {
 		t.Errorf("Expected type %v, got %v", expected.Type, actual.Type)
 	}
-	if actual.Data != expected.Data {
-		t.Errorf("Expected data %v, got %v", expected.Data, actual.Data)
+	if !bytes.Equal(actual.Str, expected.Str) {
+		t.Errorf("Expected data %q, got %q", expected.Str, actual.Str)
 	}
 }
 
 func TestParseInvalid(t *testing.T) {
 	input := "foobar\r\n"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -87,7 +90,7 @@ func TestParseInvalid(t *testing.T) {
 
 func TestParseInvalidBulkString(t *testing.T) {
 	input := "$6\r\nfoobar"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -95,7 +98,7 @@ func TestParseInvalidBulkString(t *testing.T) {
 
 func TestParseInvalidInteger(t *testing.T) {
 	input := ":123"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -103,7 +106,7 @@ func TestParseInvalidInteger(t *testing.T) {
 
 func TestParseInvalidSimpleString(t *testing.T) {
 	input := "+OK"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -111,7 +114,7 @@ func TestParseInvalidSimpleString(t *testing.T) {
 
 func TestParseInvalidError(t *testing.T) {
 	input := "-Error"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -119,7 +122,7 @@ func TestParseInvalidError(t *testing.T) {
 
 func TestParseInvalidType(t *testing.T) {
 	input := "foobar"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -127,18 +130,18 @@ func TestParseInvalidType(t *testing.T) {
 
 func TestParseArrayOfBulkStrings(t *testing.T) {
 	input := "*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: Array,
-		Data: []*RESP{
-			{Type: BulkString, Data: "foo"},
-			{Type: BulkString, Data: "bar"},
+		Elems: []RESP{
+			{Type: BulkString, Str: []byte("foo")},
+			{Type: BulkString, Str: []byte("bar")},
 		},
 	}
-	result, _, err := parseArray(input)
+	result, _, err := parseArray([]byte(input))
 	if err != nil {
 		t.Errorf("parseArray returned an error: %v", err)
 	} else {
-		for _, element := range result.Data.([]*RESP) {
+		for _, element := range result.Elems {
 			t.Logf("element: %+v", element) // print each element of result
 		}
 	}
@@ -150,16 +153,16 @@ func TestParseArrayOfBulkStrings(t *testing.T) {
 
 func TestParseArrayOfSimpleString(t *testing.T) {
 	input := "*2\r\n+foo\r\n+bar\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: Array,
-		Data: []*RESP{
-			{Type: SimpleString, Data: "foo"},
-			{Type: SimpleString, Data: "bar"},
+		Elems: []RESP{
+			{Type: SimpleString, Str: []byte("foo")},
+			{Type: SimpleString, Str: []byte("bar")},
 		},
 	}
-	result, _, err := parseArray(input)
+	result, _, err := parseArray([]byte(input))
 	// print each element of result
-	for _, element := range result.Data.([]*RESP) {
+	for _, element := range result.Elems {
 		t.Logf("element: %+v", element) // print each element of result
 	}
 	if err != nil {
@@ -173,16 +176,16 @@ func TestParseArrayOfSimpleString(t *testing.T) {
 func TestParseArrayWithIntegers(t *testing.T) {
 	// Input representing an array of two integers
 	input := "*2\r\n:123\r\n:456\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: Array,
-		Data: []*RESP{
-			{Type: Integer, Data: int64(123)},
-			{Type: Integer, Data: int64(456)},
+		Elems: []RESP{
+			{Type: Integer, Int: 123},
+			{Type: Integer, Int: 456},
 		},
 	}
 
 	// Call the ParseRESP function or the specific array parsing function
-	got, _, err := ParseRESP(input)
+	got, _, err := ParseRESP([]byte(input))
 	if err != nil {
 		t.Fatalf("ParseRESP failed with error: %v", err)
 	}
@@ -195,7 +198,7 @@ func TestParseArrayWithIntegers(t *testing.T) {
 
 func TestParseInvalidArray(t *testing.T) {
 	input := "*3\r\n+OK\r\n+OK\r\n"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -203,7 +206,7 @@ func TestParseInvalidArray(t *testing.T) {
 
 func TestParseInvalidTypeArray(t *testing.T) {
 	input := "*3"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -211,7 +214,7 @@ func TestParseInvalidTypeArray(t *testing.T) {
 
 func TestParseInvalidTypeBulkString(t *testing.T) {
 	input := "$6"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -219,15 +222,15 @@ func TestParseInvalidTypeBulkString(t *testing.T) {
 
 func TestParseArrayWithIntegerAndString(t *testing.T) {
 	input := "*2\r\n:100\r\n+OK\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: Array,
-		Data: []*RESP{
-			{Type: Integer, Data: int64(100)},
-			{Type: SimpleString, Data: "OK"},
+		Elems: []RESP{
+			{Type: Integer, Int: 100},
+			{Type: SimpleString, Str: []byte("OK")},
 		},
 	}
 
-	got, _, err := ParseRESP(input)
+	got, _, err := ParseRESP([]byte(input))
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -237,27 +240,24 @@ func TestParseArrayWithIntegerAndString(t *testing.T) {
 	}
 
 	// Assert and compare each element in the data slice
-	if len(got.Data.([]*RESP)) != len(expected.Data.([]*RESP)) {
-		t.Fatalf("Expected data length %d, got %d", len(expected.Data.([]*RESP)), len(got.Data.([]*RESP)))
-	}
-
-	for i, expResp := range expected.Data.([]*RESP) {
-		switch expResp.Data.(type) {
-		case int64:
-			if gotData, ok := got.Data.([]*RESP)[i].Data.(int64); ok {
-				if gotData != expResp.Data.(int64) {
-					t.Errorf("Expected integer data %d, got %d", expResp.Data.(int64), gotData)
-				}
-			} else {
-				t.Errorf("Expected data type int64, got %T", got.Data.([]*RESP)[i].Data)
+	if len(got.Elems) != len(expected.Elems) {
+		t.Fatalf("Expected data length %d, got %d", len(expected.Elems), len(got.Elems))
+	}
+
+	for i, expResp := range expected.Elems {
+		gotResp := got.Elems[i]
+		if gotResp.Type != expResp.Type {
+			t.Errorf("Expected element type %v, got %v", expResp.Type, gotResp.Type)
+			continue
+		}
+		switch expResp.Type {
+		case Integer:
+			if gotResp.Integer() != expResp.Integer() {
+				t.Errorf("Expected integer data %d, got %d", expResp.Integer(), gotResp.Integer())
 			}
-		case string:
-			if gotData, ok := got.Data.([]*RESP)[i].Data.(string); ok {
-				if gotData != expResp.Data.(string) {
-					t.Errorf("Expected string data %s, got %s", expResp.Data.(string), gotData)
-				}
-			} else {
-				t.Errorf("Expected data type string, got %T", got.Data.([]*RESP)[i].Data)
+		case SimpleString:
+			if gotResp.String() != expResp.String() {
+				t.Errorf("Expected string data %s, got %s", expResp.String(), gotResp.String())
 			}
 		default:
 			t.Errorf("Unhandled type in expected data")
@@ -267,39 +267,39 @@ func TestParseArrayWithIntegerAndString(t *testing.T) {
 
 func TestParseArrayWithBulkStringAndError(t *testing.T) {
 	input := "*2\r\n$10\r\nfooooooooo\r\n-Error\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: Array,
-		Data: []*RESP{
-			{Type: BulkString, Data: "fooooooooo"},
-			{Type: Error, Data: "Error"},
+		Elems: []RESP{
+			{Type: BulkString, Str: []byte("fooooooooo")},
+			{Type: Error, Str: []byte("Error")},
 		},
 	}
 
-	got, _, err := ParseRESP(input)
+	got, _, err := ParseRESP([]byte(input))
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 	if !reflect.DeepEqual(got, expected) {
-		t.Errorf("Got = %+s, want %+s", got.Data, expected.Data)
+		t.Errorf("Got = %+v, want %+v", got, expected)
 	}
 }
 
 func TestParseNestedArray(t *testing.T) {
 	input := "*2\r\n*1\r\n+OK\r\n:123\r\n"
-	expected := &RESP{
+	expected := RESP{
 		Type: Array,
-		Data: []*RESP{
+		Elems: []RESP{
 			{
 				Type: Array,
-				Data: []*RESP{
-					{Type: SimpleString, Data: "OK"},
+				Elems: []RESP{
+					{Type: SimpleString, Str: []byte("OK")},
 				},
 			},
-			{Type: Integer, Data: int64(123)},
+			{Type: Integer, Int: 123},
 		},
 	}
 
-	got, _, err := ParseRESP(input)
+	got, _, err := ParseRESP([]byte(input))
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -310,7 +310,7 @@ func TestParseNestedArray(t *testing.T) {
 
 func TestIncompleteArrayElement(t *testing.T) {
 	input := "*1\r\n$3\r\nfo"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Error("Expected error, got none")
 	}
@@ -318,7 +318,7 @@ func TestIncompleteArrayElement(t *testing.T) {
 
 func TestIncorrectTypeInArray(t *testing.T) {
 	input := "*1\r\n?3\r\nfoo\r\n"
-	_, _, err := ParseRESP(input)
+	_, _, err := ParseRESP([]byte(input))
 	if err == nil {
 		t.Error("Expected error for incorrect type, got none")
 	}
@@ -327,65 +327,65 @@ func TestIncorrectTypeInArray(t *testing.T) {
 func TestSerialize(t *testing.T) {
 	tests := []struct {
 		name     string
-		resp     *RESP
+		resp     RESP
 		expected string
 	}{
 		{
 			name: "SimpleString",
-			resp: &RESP{
+			resp: RESP{
 				Type: SimpleString,
-				Data: "OK",
+				Str:  []byte("OK"),
 			},
 			expected: "+OK\r\n",
 		},
 		{
 			name: "Error",
-			resp: &RESP{
+			resp: RESP{
 				Type: Error,
-				Data: "Error",
+				Str:  []byte("Error"),
 			},
 			expected: "-Error\r\n",
 		},
 		{
 			name: "Integer",
-			resp: &RESP{
+			resp: RESP{
 				Type: Integer,
-				Data: int64(123),
+				Int:  123,
 			},
 			expected: ":123\r\n",
 		},
 		{
 			name: "BulkString",
-			resp: &RESP{
+			resp: RESP{
 				Type: BulkString,
-				Data: "foo",
+				Str:  []byte("foo"),
 			},
 			expected: "$3\r\nfoo\r\n",
 		},
 		{
 			name: "Array",
-			resp: &RESP{
+			resp: RESP{
 				Type: Array,
-				Data: []*RESP{
-					{Type: BulkString, Data: "SET"},
-					{Type: BulkString, Data: "foo"},
-					{Type: BulkString, Data: "123"},
+				Elems: []RESP{
+					{Type: BulkString, Str: []byte("SET")},
+					{Type: BulkString, Str: []byte("foo")},
+					{Type: BulkString, Str: []byte("123")},
 				},
 			},
 			expected: "*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\n123\r\n",
 		},
 		{
 			name: "Nested Array",
-			resp: &RESP{
+			resp: RESP{
 				Type: Array,
-				Data: []*RESP{
+				Elems: []RESP{
 					{
 						Type: Array,
-						Data: []*RESP{
-							{Type: SimpleString, Data: "OK"},
+						Elems: []RESP{
+							{Type: SimpleString, Str: []byte("OK")},
 						},
 					},
-					{Type: Integer, Data: int64(123)},
+					{Type: Integer, Int: 123},
 				},
 			},
 			expected: "*2\r\n*1\r\n+OK\r\n:123\r\n",
@@ -401,3 +401,141 @@ func TestSerialize(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRESP3(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected RESP
+	}{
+		{"Null", "_\r\n", RESP{Type: Null}},
+		{"True", "#t\r\n", RESP{Type: Boolean, Int: 1}},
+		{"False", "#f\r\n", RESP{Type: Boolean, Int: 0}},
+		{"Double", ",1.5\r\n", RESP{Type: Double, Float: 1.5}},
+		{"Infinity", ",-inf\r\n", RESP{Type: Double, Float: math.Inf(-1)}},
+		{"BigNumber", "(3492890328409238509324850943850943825024385\r\n", RESP{Type: BigNumber, Str: []byte("3492890328409238509324850943850943825024385")}},
+		{"BulkError", "!21\r\nSYNTAX invalid syntax\r\n", RESP{Type: BulkError, Str: []byte("SYNTAX invalid syntax")}},
+		{"VerbatimString", "=15\r\ntxt:Some string\r\n", RESP{Type: VerbatimString, Str: []byte("txt:Some string")}},
+		{"Map", "%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n", RESP{Type: Map, Elems: []RESP{
+			{Type: SimpleString, Str: []byte("first")}, {Type: Integer, Int: 1},
+			{Type: SimpleString, Str: []byte("second")}, {Type: Integer, Int: 2},
+		}}},
+		{"Set", "~2\r\n+a\r\n+b\r\n", RESP{Type: Set, Elems: []RESP{
+			{Type: SimpleString, Str: []byte("a")}, {Type: SimpleString, Str: []byte("b")},
+		}}},
+		{"Attribute", "|1\r\n+ttl\r\n:3600\r\n", RESP{Type: Attribute, Elems: []RESP{
+			{Type: SimpleString, Str: []byte("ttl")}, {Type: Integer, Int: 3600},
+		}}},
+		{"Push", ">2\r\n+message\r\n+hello\r\n", RESP{Type: Push, Elems: []RESP{
+			{Type: SimpleString, Str: []byte("message")}, {Type: SimpleString, Str: []byte("hello")},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n, err := ParseRESP([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ParseRESP returned an error: %v", err)
+			}
+			if n != len(tt.input) {
+				t.Errorf("ParseRESP consumed %d bytes, expected %d", n, len(tt.input))
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseRESP returned %+v, expected %+v", got, tt.expected)
+			}
+			if serialized := got.Serialize(); serialized != tt.input {
+				t.Errorf("Serialize() = %q, expected %q", serialized, tt.input)
+			}
+			read, err := NewReader(strings.NewReader(tt.input)).ReadValue()
+			if err != nil {
+				t.Fatalf("ReadValue returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(read, tt.expected) {
+				t.Errorf("ReadValue returned %+v, expected %+v", read, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseInvalidRESP3(t *testing.T) {
+	inputs := []string{"#x\r\n", ",abc\r\n", "(12a\r\n", "=3\r\nabc\r\n", "%1\r\n+key\r\n"}
+	for _, input := range inputs {
+		if _, _, err := ParseRESP([]byte(input)); err == nil {
+			t.Errorf("Expected error for %q, got nil", input)
+		}
+	}
+}
+
+func TestSerializeAsRESP2(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     RESP
+		expected string
+	}{
+		{"Null", RESP{Type: Null}, "$-1\r\n"},
+		{"Boolean", RESP{Type: Boolean, Int: 1}, ":1\r\n"},
+		{"Double", RESP{Type: Double, Float: 3.25}, "$4\r\n3.25\r\n"},
+		{"VerbatimString", RESP{Type: VerbatimString, Str: []byte("txt:hello")}, "$5\r\nhello\r\n"},
+		{"Map", RESP{Type: Map, Elems: []RESP{
+			{Type: BulkString, Str: []byte("proto")}, {Type: Integer, Int: 2},
+		}}, "*2\r\n$5\r\nproto\r\n:2\r\n"},
+		{"Nested Set", RESP{Type: Set, Elems: []RESP{
+			{Type: Boolean, Int: 0},
+		}}, "*1\r\n:0\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := tt.resp.SerializeAs(2); actual != tt.expected {
+				t.Errorf("SerializeAs(2) = %q, expected %q", actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBinarySafeRoundTrip(t *testing.T) {
+	payloads := [][]byte{
+		[]byte("foo\x00bar"),
+		{0xff, 0xfe, 0x00, 0xc3, 0x28},
+		[]byte("\r\n$3\r\nfoo\r\n"),
+		{},
+	}
+
+	for _, payload := range payloads {
+		value := NewArray([]RESP{NewBulkString("SET"), NewBulkString("key"), NewBulk(payload)})
+		serialized := value.Serialize()
+
+		parsed, n, err := ParseRESP([]byte(serialized))
+		if err != nil {
+			t.Fatalf("ParseRESP(%q) returned an error: %v", serialized, err)
+		}
+		if n != len(serialized) {
+			t.Errorf("ParseRESP consumed %d bytes, expected %d", n, len(serialized))
+		}
+		if got := parsed.Array()[2].Bytes(); !bytes.Equal(got, payload) {
+			t.Errorf("ParseRESP returned payload %q, expected %q", got, payload)
+		}
+
+		read, err := NewReader(strings.NewReader(serialized)).ReadCommand()
+		if err != nil {
+			t.Fatalf("ReadCommand(%q) returned an error: %v", serialized, err)
+		}
+		if got := read.Array()[2].Bytes(); !bytes.Equal(got, payload) {
+			t.Errorf("ReadCommand returned payload %q, expected %q", got, payload)
+		}
+		if reserialized := read.Serialize(); reserialized != serialized {
+			t.Errorf("Serialize() = %q, expected %q", reserialized, serialized)
+		}
+	}
+}
+
+func TestBinarySafeInline(t *testing.T) {
+	read, err := NewReader(strings.NewReader("ECHO \"a\\x00b\\xff\"\r\n")).ReadCommand()
+	if err != nil {
+		t.Fatalf("ReadCommand returned an error: %v", err)
+	}
+	expected := []byte{'a', 0x00, 'b', 0xff}
+	if got := read.Array()[1].Bytes(); !bytes.Equal(got, expected) {
+		t.Errorf("ReadCommand returned payload %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,273 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Reader incrementally decodes RESP values from a stream. Values that are
+// split across several reads are buffered until they are complete.
+type Reader struct {
+	rd *bufio.Reader
+}
+
+func NewReader(rd io.Reader) *Reader {
+	return &Reader{rd: bufio.NewReader(rd)}
+}
+
+// Buffered returns the number of bytes already received but not decoded
+// yet. Servers use it to flush replies only once a pipeline is drained.
+func (r *Reader) Buffered() int {
+	return r.rd.Buffered()
+}
+
+// Peek blocks until more data is received and returns the error that
+// ended the stream, if any, without consuming anything.
+func (r *Reader) Peek() error {
+	_, err := r.rd.Peek(1)
+	return err
+}
+
+// ReadValue blocks until a complete RESP value is available and returns it.
+// io.EOF is returned only when the stream ends on a value boundary.
+func (r *Reader) ReadValue() (RESP, error) {
+	var v RESP
+	err := r.readInto(&v)
+	return v, err
+}
+
+func (r *Reader) readInto(v *RESP) error {
+	line, err := r.readLine()
+	if err != nil {
+		return err
+	}
+	if len(line) == 0 {
+		return errors.New("invalid RESP: empty line")
+	}
+	switch line[0] {
+	case '+', '-', '(':
+		// line aliases the bufio buffer, so keep a copy
+		payload := append([]byte(nil), line[1:]...)
+		switch line[0] {
+		case '+':
+			*v = RESP{Type: SimpleString, Str: payload}
+		case '-':
+			*v = RESP{Type: Error, Str: payload}
+		default:
+			if err := checkBigNumber(payload); err != nil {
+				return err
+			}
+			*v = RESP{Type: BigNumber, Str: payload}
+		}
+	case ':':
+		val, ok := parseDecimal(line[1:])
+		if !ok {
+			// fall back to strconv for the edges of the int64 range
+			parsed, err := strconv.ParseInt(string(line[1:]), 10, 64)
+			if err != nil {
+				return err
+			}
+			val = parsed
+		}
+		*v = NewInteger(val)
+	case '_':
+		if len(line) != 1 {
+			return errors.New("invalid null")
+		}
+		*v = NewNull()
+	case '#':
+		switch string(line[1:]) {
+		case "t":
+			*v = NewBoolean(true)
+		case "f":
+			*v = NewBoolean(false)
+		default:
+			return fmt.Errorf("invalid boolean: %q", line[1:])
+		}
+	case ',':
+		val, err := parseDouble(line[1:])
+		if err != nil {
+			return err
+		}
+		*v = NewDouble(val)
+	case '$', '!', '=':
+		prefix := line[0]
+		length, err := parseLength(line)
+		if err != nil {
+			return err
+		}
+		if length == -1 && prefix == '$' {
+			*v = NewNullBulkString()
+			return nil
+		}
+		if length < 0 {
+			return errors.New("invalid blob: negative length")
+		}
+		if length > maxBulkLength {
+			return errors.New("invalid bulk length")
+		}
+		data, err := r.readBulk(length)
+		if err != nil {
+			return err
+		}
+		switch prefix {
+		case '$':
+			*v = NewBulk(data)
+		case '!':
+			*v = RESP{Type: BulkError, Str: data}
+		default:
+			if err := checkVerbatim(data); err != nil {
+				return err
+			}
+			*v = RESP{Type: VerbatimString, Str: data}
+		}
+	case '*', '%', '~', '|', '>':
+		prefix := line[0]
+		length, err := parseLength(line)
+		if err != nil {
+			return err
+		}
+		if length == -1 && prefix == '*' {
+			*v = NewNullArray()
+			return nil
+		}
+		if length < 0 {
+			return errors.New("invalid aggregate: negative length")
+		}
+		if length > maxMultibulkLength {
+			return errors.New("invalid multibulk length")
+		}
+		switch prefix {
+		case '*':
+			return r.readAggregate(v, Array, length)
+		case '%':
+			return r.readAggregate(v, Map, length*2)
+		case '~':
+			return r.readAggregate(v, Set, length)
+		case '|':
+			return r.readAggregate(v, Attribute, length*2)
+		default:
+			return r.readAggregate(v, Push, length)
+		}
+	default:
+		return fmt.Errorf("unknown RESP type: %q", line[0])
+	}
+	return nil
+}
+
+// readAggregate decodes the elements of an aggregate in place into a single
+// slice allocation.
+func (r *Reader) readAggregate(v *RESP, typ RespType, length int) error {
+	elements := make([]RESP, length)
+	for i := range elements {
+		if err := r.readInto(&elements[i]); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+	*v = RESP{Type: typ, Elems: elements}
+	return nil
+}
+
+// ReadRDB reads an RDB payload as sent by a master after FULLRESYNC. It is
+// framed like a bulk string but has no trailing CRLF.
+func (r *Reader) ReadRDB() ([]byte, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return nil, fmt.Errorf("invalid RDB header: %q", line)
+	}
+	length, err := parseLength(line)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 {
+		return nil, errors.New("invalid RDB length")
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.rd, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return data, nil
+}
+
+// readLine returns the next CRLF terminated line without its terminator.
+// The returned slice is only valid until the next read.
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.rd.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// header lines are short, only oversized simple strings get here
+		rest, err := r.rd.ReadBytes('\n')
+		line = append(append([]byte(nil), line...), rest...)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	} else if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errors.New("invalid RESP: line not terminated by CRLF")
+	}
+	return line[:len(line)-2], nil
+}
+
+func parseLength(line []byte) (int, error) {
+	length, ok := parseDecimal(line[1:])
+	if !ok || length < -1 || int64(int(length)) != length {
+		return 0, fmt.Errorf("invalid length: %q", line[1:])
+	}
+	return int(length), nil
+}
+
+// parseDecimal parses a signed decimal without the string conversion
+// strconv needs.
+func parseDecimal(b []byte) (int64, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	negative := b[0] == '-'
+	if negative {
+		b = b[1:]
+		if len(b) == 0 {
+			return 0, false
+		}
+	}
+	var n int64
+	for _, c := range b {
+		if c < '0' || c > '9' || n > (1<<63-1-9)/10 {
+			return 0, false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	if negative {
+		return -n, true
+	}
+	return n, true
+}
+
+func (r *Reader) readBulk(length int) ([]byte, error) {
+	data := make([]byte, length+2)
+	if _, err := io.ReadFull(r.rd, data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if data[length] != '\r' || data[length+1] != '\n' {
+		return nil, errors.New("invalid bulk string: missing CRLF")
+	}
+	return data[:length:length], nil
+}
+
+// unexpectedEOF turns a clean EOF in the middle of a value into
+// io.ErrUnexpectedEOF so callers can tell truncated frames apart.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
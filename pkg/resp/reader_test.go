@@ -0,0 +1,111 @@
+package resp
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReaderSplitAcrossReads(t *testing.T) {
+	input := "*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n+OK\r\n"
+	reader := NewReader(iotest.OneByteReader(strings.NewReader(input)))
+	expected := []RESP{
+		{
+			Type: Array,
+			Elems: []RESP{
+				{Type: BulkString, Str: []byte("foo")},
+				{Type: BulkString, Str: []byte("bar")},
+			},
+		},
+		{Type: SimpleString, Str: []byte("OK")},
+	}
+	for _, want := range expected {
+		got, err := reader.ReadValue()
+		if err != nil {
+			t.Fatalf("ReadValue returned an error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadValue returned %+v, expected %+v", got, want)
+		}
+	}
+	if _, err := reader.ReadValue(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestReaderLargeBulkString(t *testing.T) {
+	value := strings.Repeat("x", 64*1024)
+	input := "*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$65536\r\n" + value + "\r\n"
+	reader := NewReader(iotest.HalfReader(strings.NewReader(input)))
+	got, err := reader.ReadValue()
+	if err != nil {
+		t.Fatalf("ReadValue returned an error: %v", err)
+	}
+	elements := got.Array()
+	if len(elements) != 3 {
+		t.Fatalf("Expected 3 elements, got %d", len(elements))
+	}
+	if elements[2].String() != value {
+		t.Errorf("Expected bulk string of length %d, got %d", len(value), len(elements[2].Bytes()))
+	}
+}
+
+func TestReaderNullBulkString(t *testing.T) {
+	reader := NewReader(strings.NewReader("$-1\r\n"))
+	got, err := reader.ReadValue()
+	if err != nil {
+		t.Fatalf("ReadValue returned an error: %v", err)
+	}
+	if got.Type != NullBulkString {
+		t.Errorf("Expected type %v, got %v", NullBulkString, got.Type)
+	}
+}
+
+func TestReaderTruncatedValue(t *testing.T) {
+	reader := NewReader(strings.NewReader("*2\r\n$3\r\nfoo\r\n$3\r\nba"))
+	if _, err := reader.ReadValue(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestReaderRejectsHugeBulkLength(t *testing.T) {
+	for _, input := range []string{"$9000000000000000\r\n", "$536870913\r\n", "=9000000000000000\r\n"} {
+		reader := NewReader(strings.NewReader(input))
+		if _, err := reader.ReadValue(); err == nil || err.Error() != "invalid bulk length" {
+			t.Errorf("Expected %q to be rejected, got %v", input, err)
+		}
+	}
+}
+
+func TestReaderRejectsHugeAggregateLength(t *testing.T) {
+	for _, input := range []string{"*1000000000000\r\n", "*1048577\r\n", "%1000000000000\r\n"} {
+		reader := NewReader(strings.NewReader(input))
+		if _, err := reader.ReadValue(); err == nil || err.Error() != "invalid multibulk length" {
+			t.Errorf("Expected %q to be rejected, got %v", input, err)
+		}
+	}
+}
+
+func TestReaderRDB(t *testing.T) {
+	input := "+FULLRESYNC abc 0\r\n$5\r\nREDIS*1\r\n$4\r\nPING\r\n"
+	reader := NewReader(strings.NewReader(input))
+	if _, err := reader.ReadValue(); err != nil {
+		t.Fatalf("ReadValue returned an error: %v", err)
+	}
+	rdb, err := reader.ReadRDB()
+	if err != nil {
+		t.Fatalf("ReadRDB returned an error: %v", err)
+	}
+	if string(rdb) != "REDIS" {
+		t.Errorf("Expected RDB payload %q, got %q", "REDIS", rdb)
+	}
+	got, err := reader.ReadValue()
+	if err != nil {
+		t.Fatalf("ReadValue returned an error: %v", err)
+	}
+	if got.Type != Array || got.Elems[0].String() != "PING" {
+		t.Errorf("Expected PING command after RDB payload, got %+v", got)
+	}
+}
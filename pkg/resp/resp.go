@@ -0,0 +1,172 @@
+package resp
+
+type RespType int
+
+const (
+	SimpleString RespType = iota
+	Error
+	Integer
+	BulkString
+	Array
+	RDB
+	NullBulkString
+	NullArray
+	// RESP3 types
+	Null
+	Boolean
+	Double
+	BigNumber
+	BulkError
+	VerbatimString
+	Map
+	Set
+	Attribute
+	Push
+)
+
+// RESP is a single protocol value. Which field carries the payload depends
+// on Type:
+//   - Str for SimpleString, Error, BulkString, BulkError, VerbatimString
+//     (including its "txt:" format prefix), BigNumber digits and RDB payloads
+//   - Int for Integer and Boolean (1 or 0)
+//   - Float for Double
+//   - Elems for Array, Map, Set, Attribute and Push. Map and Attribute store
+//     their entries as alternating keys and values.
+//
+// Values decoded by ParseRESP share memory with the input buffer.
+type RESP struct {
+	Type  RespType
+	Str   []byte
+	Int   int64
+	Float float64
+	Elems []RESP
+}
+
+func NewSimpleString(s string) RESP {
+	return RESP{Type: SimpleString, Str: []byte(s)}
+}
+
+func NewError(msg string) RESP {
+	return RESP{Type: Error, Str: []byte(msg)}
+}
+
+func NewInteger(n int64) RESP {
+	return RESP{Type: Integer, Int: n}
+}
+
+// NewBulk returns a bulk string that references b without copying it.
+func NewBulk(b []byte) RESP {
+	return RESP{Type: BulkString, Str: b}
+}
+
+func NewBulkString(s string) RESP {
+	return RESP{Type: BulkString, Str: []byte(s)}
+}
+
+func NewNullBulkString() RESP {
+	return RESP{Type: NullBulkString}
+}
+
+func NewNullArray() RESP {
+	return RESP{Type: NullArray}
+}
+
+func NewArray(elems []RESP) RESP {
+	return RESP{Type: Array, Elems: elems}
+}
+
+func NewRDB(payload []byte) RESP {
+	return RESP{Type: RDB, Str: payload}
+}
+
+func NewNull() RESP {
+	return RESP{Type: Null}
+}
+
+func NewBoolean(b bool) RESP {
+	if b {
+		return RESP{Type: Boolean, Int: 1}
+	}
+	return RESP{Type: Boolean, Int: 0}
+}
+
+func NewDouble(f float64) RESP {
+	return RESP{Type: Double, Float: f}
+}
+
+func NewBigNumber(digits string) RESP {
+	return RESP{Type: BigNumber, Str: []byte(digits)}
+}
+
+func NewBulkError(msg string) RESP {
+	return RESP{Type: BulkError, Str: []byte(msg)}
+}
+
+// NewVerbatimString returns a verbatim string with a three letter format
+// such as "txt" or "mkd".
+func NewVerbatimString(format string, s string) RESP {
+	return RESP{Type: VerbatimString, Str: []byte(format + ":" + s)}
+}
+
+// NewMap returns a map from alternating keys and values.
+func NewMap(kv []RESP) RESP {
+	return RESP{Type: Map, Elems: kv}
+}
+
+func NewSet(elems []RESP) RESP {
+	return RESP{Type: Set, Elems: elems}
+}
+
+func NewPush(elems []RESP) RESP {
+	return RESP{Type: Push, Elems: elems}
+}
+
+// Bytes returns the payload of string-like values.
+func (r *RESP) Bytes() []byte {
+	return r.Str
+}
+
+// String returns the payload of string-like values as a Go string.
+func (r *RESP) String() string {
+	return string(r.Str)
+}
+
+func (r *RESP) Integer() int64 {
+	return r.Int
+}
+
+func (r *RESP) Bool() bool {
+	return r.Int != 0
+}
+
+func (r *RESP) Double() float64 {
+	return r.Float
+}
+
+// Array returns the elements of aggregate values.
+func (r *RESP) Array() []RESP {
+	return r.Elems
+}
+
+// Len returns the number of elements of an aggregate value, counting
+// key/value pairs of maps and attributes once.
+func (r *RESP) Len() int {
+	if r.Type == Map || r.Type == Attribute {
+		return len(r.Elems) / 2
+	}
+	return len(r.Elems)
+}
+
+// IsNull reports whether the value is a RESP2 or RESP3 null.
+func (r *RESP) IsNull() bool {
+	switch r.Type {
+	case Null, NullBulkString, NullArray:
+		return true
+	}
+	return false
+}
+
+// IsError reports whether the value is an error reply.
+func (r *RESP) IsError() bool {
+	return r.Type == Error || r.Type == BulkError
+}